@@ -0,0 +1,615 @@
+package max_RESTfulAPI
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/sirupsen/logrus"
+)
+
+// AlgoExchange is what the execution algorithms trade on, MaxClient, PaperClient and MemoryExchange implement it.
+type AlgoExchange interface {
+	PlaceTaggedOrder(market, side, ordType string, price, volume float64, clientOid string) (WsOrder, error)
+	CancelOrder(market string, id, clientId interface{}) (WsOrder, error)
+	// the returned func unsubscribes fn.
+	OnTradeReport(fn func(Trade)) (unsubscribe func())
+	ReadMarkets() []Market
+}
+
+var (
+	_ AlgoExchange = (*MaxClient)(nil)
+	_ AlgoExchange = (*PaperClient)(nil)
+	_ AlgoExchange = (*MemoryExchange)(nil)
+)
+
+type AlgoKind string
+
+const (
+	// slices the volume evenly over a duration.
+	AlgoTWAP AlgoKind = "twap"
+	// follows a participation rate of the public volume.
+	AlgoVWAP AlgoKind = "vwap"
+	// shows a part of the volume at a time, refilled when filled.
+	AlgoIceberg AlgoKind = "iceberg"
+)
+
+type AlgoState string
+
+const (
+	AlgoRunning  AlgoState = "running"
+	AlgoPaused   AlgoState = "paused"
+	AlgoCanceled AlgoState = "canceled"
+	AlgoDone     AlgoState = "done"
+)
+
+type AlgoConfig struct {
+	Market string
+	Side   string
+	// total base volume to execute.
+	Volume decimal.Decimal
+	// worst price, buys never pay more and sells never take less.
+	// Zero for no limit, TWAP and VWAP children are market orders then. Required by the iceberg.
+	LimitPrice decimal.Decimal
+	// children below it wait to grow, but the last one.
+	MinChild decimal.Decimal
+
+	// TWAP, executes Volume over Duration in Slices children, Slices default to 10.
+	Duration time.Duration
+	Slices   int
+
+	// VWAP, executes Participation of the public volume traded within LimitPrice, e.g. 0.1.
+	Participation decimal.Decimal
+	Trades        *TradeStreamBranch
+
+	// iceberg, base volume shown at LimitPrice.
+	ShowSize decimal.Decimal
+
+	// how often the schedule is checked, default to Duration/Slices for TWAP and 1 second otherwise.
+	Interval time.Duration
+
+	// called with the progress after every fill.
+	OnProgress func(AlgoProgress)
+}
+
+type AlgoProgress struct {
+	Id    string
+	Kind  AlgoKind
+	State AlgoState
+	// base volumes.
+	Volume    decimal.Decimal
+	Filled    decimal.Decimal
+	Remaining decimal.Decimal
+	// volume of the live children.
+	Working decimal.Decimal
+	// volume weighted, zero before the first fill.
+	AvgPrice  decimal.Decimal
+	Children  int
+	StartedAt time.Time
+}
+
+// AlgoOrder works a parent order through child orders tagged "<Id>-<n>" by client oid.
+// Fills are attributed to the parent by the order id of the children.
+type AlgoOrder struct {
+	Id     string
+	Kind   AlgoKind
+	Config AlgoConfig
+
+	exchange           AlgoExchange
+	logger             *logrus.Logger
+	pricePrec, volPrec int32
+
+	wake     chan struct{}
+	done     chan struct{}
+	doneOnce sync.Once
+	// listeners registered on the exchange and the trade stream.
+	unsubscribes []func()
+	// serializes the exchange calls of step, Pause and Cancel.
+	actionMutex sync.Mutex
+
+	stateBranch struct {
+		state     AlgoState
+		startedAt time.Time
+		// running time before the last resume, the TWAP schedule stops while paused.
+		activeBefore time.Duration
+		resumedAt    time.Time
+		filled       decimal.Decimal
+		notional     decimal.Decimal
+		// public volume of others within the limit while running, for VWAP.
+		marketVolume decimal.Decimal
+		// own fills not yet seen on the trade stream, and public trades not yet matched to an own fill.
+		unprinted []algoPrint
+		printed   []algoPrint
+		children  int
+		// every child, fills of canceled children still count.
+		childIds map[int64]bool
+		working  map[int64]*algoChild
+		// while placing, fills of unknown orders are kept until the order id is known.
+		inflight int
+		orphans  []Trade
+		sync.Mutex
+	}
+}
+
+type algoChild struct {
+	remaining decimal.Decimal
+	// market children can not be canceled, they are settled by their fills or after marketSettle.
+	market   bool
+	placedAt time.Time
+}
+
+// a market child not fully filled by then is assumed done.
+const marketSettle = 10 * time.Second
+
+// an own fill and its print on the public trade stream arrive within ownPrintWindow of each other.
+const ownPrintWindow = 5 * time.Second
+
+type algoPrint struct {
+	price  decimal.Decimal
+	volume decimal.Decimal
+	at     time.Time
+}
+
+// NewAlgoOrder works the parent order on the client.
+func (Mc *MaxClient) NewAlgoOrder(kind AlgoKind, cfg AlgoConfig) (*AlgoOrder, error) {
+	return NewAlgoOrder(Mc, kind, cfg, Mc.logger)
+}
+
+func NewAlgoOrder(exchange AlgoExchange, kind AlgoKind, cfg AlgoConfig, logger *logrus.Logger) (*AlgoOrder, error) {
+	cfg.Market = NormalizeMarket(cfg.Market)
+	cfg.Side = strings.ToLower(cfg.Side)
+	if cfg.Side != "buy" && cfg.Side != "sell" {
+		return nil, fmt.Errorf("unknown side %q", cfg.Side)
+	}
+	if !cfg.Volume.IsPositive() {
+		return nil, errors.New("volume should be positive")
+	}
+	switch kind {
+	case AlgoTWAP:
+		if cfg.Duration <= 0 {
+			return nil, errors.New("twap needs a duration")
+		}
+		if cfg.Slices <= 0 {
+			cfg.Slices = 10
+		}
+		if cfg.Interval <= 0 {
+			cfg.Interval = cfg.Duration / time.Duration(cfg.Slices)
+		}
+	case AlgoVWAP:
+		if cfg.Trades == nil || !cfg.Participation.IsPositive() || cfg.Participation.GreaterThan(decimal.NewFromInt(1)) {
+			return nil, errors.New("vwap needs a trade stream and a participation in (0, 1]")
+		}
+	case AlgoIceberg:
+		if !cfg.ShowSize.IsPositive() || !cfg.LimitPrice.IsPositive() {
+			return nil, errors.New("iceberg needs a show size and a limit price")
+		}
+	default:
+		return nil, fmt.Errorf("unknown algo %q", kind)
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = time.Second
+	}
+
+	a := &AlgoOrder{
+		Id:       string(kind) + "-" + strconv.FormatInt(time.Now().UnixNano(), 36),
+		Kind:     kind,
+		Config:   cfg,
+		exchange: exchange,
+		logger:   logger,
+		wake:     make(chan struct{}, 1),
+		done:     make(chan struct{}),
+	}
+	known := false
+	a.pricePrec, a.volPrec = 8, 8
+	for _, m := range exchange.ReadMarkets() {
+		if m.Id == cfg.Market {
+			known = true
+			a.pricePrec, a.volPrec = int32(m.QuoteUnitPrecision), int32(m.BaseUnitPrecision)
+		}
+	}
+	if !known {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownMarket, cfg.Market)
+	}
+	a.Config.LimitPrice = a.limitPrice()
+	a.stateBranch.state = AlgoPaused
+	a.stateBranch.childIds = make(map[int64]bool)
+	a.stateBranch.working = make(map[int64]*algoChild)
+
+	a.unsubscribes = append(a.unsubscribes, exchange.OnTradeReport(a.tradeReportArrived))
+	if kind == AlgoVWAP {
+		a.unsubscribes = append(a.unsubscribes, cfg.Trades.OnTrade(a.publicTradeArrived))
+	}
+	return a, nil
+}
+
+// finish closes done, the listeners stay until the fills of the last children had time to arrive.
+func (a *AlgoOrder) finish() {
+	a.doneOnce.Do(func() {
+		close(a.done)
+		time.AfterFunc(marketSettle, func() {
+			for _, unsubscribe := range a.unsubscribes {
+				unsubscribe()
+			}
+		})
+	})
+}
+
+// buys round the limit down and sells up, so the limit is never passed.
+func (a *AlgoOrder) limitPrice() decimal.Decimal {
+	if a.Config.Side == "buy" {
+		return a.Config.LimitPrice.RoundFloor(a.pricePrec)
+	}
+	return a.Config.LimitPrice.RoundCeil(a.pricePrec)
+}
+
+// Run works the order until it is done, canceled or ctx is done, which cancels it.
+func (a *AlgoOrder) Run(ctx context.Context) {
+	if !a.start() {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(a.Config.Interval)
+		defer ticker.Stop()
+		for {
+			a.step()
+			select {
+			case <-ctx.Done():
+				a.Cancel()
+				return
+			case <-a.done:
+				a.cancelWorking()
+				return
+			case <-ticker.C:
+			case <-a.wake:
+			}
+		}
+	}()
+}
+
+// start moves a new order to running, false if it already ran.
+func (a *AlgoOrder) start() bool {
+	a.stateBranch.Lock()
+	defer a.stateBranch.Unlock()
+	if !a.stateBranch.startedAt.IsZero() || a.stateBranch.state != AlgoPaused {
+		return false
+	}
+	a.stateBranch.state = AlgoRunning
+	a.stateBranch.startedAt = time.Now()
+	a.stateBranch.resumedAt = a.stateBranch.startedAt
+	return true
+}
+
+// Done is closed once the order is filled or canceled.
+func (a *AlgoOrder) Done() <-chan struct{} {
+	return a.done
+}
+
+// Pause cancels the live children and stops placing new ones.
+func (a *AlgoOrder) Pause() {
+	a.stateBranch.Lock()
+	if a.stateBranch.state != AlgoRunning {
+		a.stateBranch.Unlock()
+		return
+	}
+	a.stateBranch.state = AlgoPaused
+	a.stateBranch.activeBefore += time.Since(a.stateBranch.resumedAt)
+	a.stateBranch.Unlock()
+	a.cancelWorking()
+	a.logger.Infof("algo %s paused", a.Id)
+}
+
+// Resume picks up a paused order where it stopped.
+func (a *AlgoOrder) Resume() {
+	a.stateBranch.Lock()
+	if a.stateBranch.state != AlgoPaused || a.stateBranch.startedAt.IsZero() {
+		a.stateBranch.Unlock()
+		return
+	}
+	a.stateBranch.state = AlgoRunning
+	a.stateBranch.resumedAt = time.Now()
+	a.stateBranch.Unlock()
+	a.notify()
+	a.logger.Infof("algo %s resumed", a.Id)
+}
+
+// Cancel cancels the live children and ends the order, what was filled stays filled.
+func (a *AlgoOrder) Cancel() {
+	a.stateBranch.Lock()
+	if a.stateBranch.state == AlgoCanceled || a.stateBranch.state == AlgoDone {
+		a.stateBranch.Unlock()
+		return
+	}
+	a.stateBranch.state = AlgoCanceled
+	a.stateBranch.Unlock()
+	a.finish()
+	a.cancelWorking()
+	a.logger.Infof("algo %s canceled", a.Id)
+}
+
+func (a *AlgoOrder) Progress() AlgoProgress {
+	a.stateBranch.Lock()
+	defer a.stateBranch.Unlock()
+	return a.progress()
+}
+
+func (a *AlgoOrder) progress() AlgoProgress {
+	p := AlgoProgress{
+		Id:        a.Id,
+		Kind:      a.Kind,
+		State:     a.stateBranch.state,
+		Volume:    a.Config.Volume,
+		Filled:    a.stateBranch.filled,
+		Remaining: decimal.Max(a.Config.Volume.Sub(a.stateBranch.filled), decimal.Zero),
+		Working:   a.workingVolume(false),
+		AvgPrice:  decimal.Zero,
+		Children:  a.stateBranch.children,
+		StartedAt: a.stateBranch.startedAt,
+	}
+	if a.stateBranch.filled.IsPositive() {
+		p.AvgPrice = a.stateBranch.notional.Div(a.stateBranch.filled).Round(a.pricePrec)
+	}
+	return p
+}
+
+func (a *AlgoOrder) workingVolume(onlyMarket bool) decimal.Decimal {
+	sum := decimal.Zero
+	for _, child := range a.stateBranch.working {
+		if child.market || !onlyMarket {
+			sum = sum.Add(child.remaining)
+		}
+	}
+	return sum
+}
+
+// due is the volume which should be filled or working by now.
+func (a *AlgoOrder) due() decimal.Decimal {
+	var due decimal.Decimal
+	switch a.Kind {
+	case AlgoTWAP:
+		elapsed := a.stateBranch.activeBefore + time.Since(a.stateBranch.resumedAt)
+		slice := int(elapsed/(a.Config.Duration/time.Duration(a.Config.Slices))) + 1
+		if slice >= a.Config.Slices {
+			return a.Config.Volume
+		}
+		due = a.Config.Volume.Mul(decimal.NewFromInt(int64(slice))).Div(decimal.NewFromInt(int64(a.Config.Slices)))
+	case AlgoVWAP:
+		due = a.stateBranch.marketVolume.Mul(a.Config.Participation)
+	case AlgoIceberg:
+		due = a.stateBranch.filled.Add(a.Config.ShowSize)
+	}
+	return decimal.Min(due.RoundFloor(a.volPrec), a.Config.Volume)
+}
+
+func (a *AlgoOrder) step() {
+	a.reportProgress(a.advance())
+}
+
+// advance replaces the limit children when the volume due changed, and refills the iceberg once its child is filled.
+// It returns the progress of the fills which came with the new child.
+func (a *AlgoOrder) advance() []AlgoProgress {
+	a.actionMutex.Lock()
+	defer a.actionMutex.Unlock()
+
+	a.stateBranch.Lock()
+	if a.stateBranch.state != AlgoRunning {
+		a.stateBranch.Unlock()
+		return nil
+	}
+	for id, child := range a.stateBranch.working {
+		if child.market && time.Since(child.placedAt) > marketSettle {
+			delete(a.stateBranch.working, id)
+		}
+	}
+	if a.Kind == AlgoIceberg && len(a.stateBranch.working) != 0 {
+		a.stateBranch.Unlock()
+		return nil
+	}
+	want := a.due().Sub(a.stateBranch.filled).Sub(a.workingVolume(true))
+	limitWorking := a.workingVolume(false).Sub(a.workingVolume(true))
+	if want.Equal(limitWorking) || (!want.IsPositive() && !limitWorking.IsPositive()) {
+		a.stateBranch.Unlock()
+		return nil
+	}
+	a.stateBranch.Unlock()
+
+	a.cancelLimitChildren()
+
+	a.stateBranch.Lock()
+	if a.stateBranch.state != AlgoRunning {
+		a.stateBranch.Unlock()
+		return nil
+	}
+	// fills of the canceled children may have come in meanwhile.
+	volume := a.due().Sub(a.stateBranch.filled).Sub(a.workingVolume(true)).RoundFloor(a.volPrec)
+	remaining := a.Config.Volume.Sub(a.stateBranch.filled)
+	if !volume.IsPositive() || (volume.LessThan(a.Config.MinChild) && volume.LessThan(remaining)) {
+		a.stateBranch.Unlock()
+		return nil
+	}
+	a.stateBranch.children++
+	clientOid := a.Id + "-" + strconv.Itoa(a.stateBranch.children)
+	a.stateBranch.inflight++
+	a.stateBranch.Unlock()
+
+	ordType, price := "limit", floatOf(a.Config.LimitPrice)
+	if a.Config.LimitPrice.IsZero() {
+		ordType, price = "market", 0
+	}
+	order, err := a.exchange.PlaceTaggedOrder(a.Config.Market, a.Config.Side, ordType, price, floatOf(volume), clientOid)
+
+	a.stateBranch.Lock()
+	a.stateBranch.inflight--
+	orphans := a.stateBranch.orphans
+	if a.stateBranch.inflight == 0 {
+		a.stateBranch.orphans = nil
+	}
+	if err != nil {
+		a.stateBranch.Unlock()
+		a.logger.Warnf("algo %s fail to place child %s: %v", a.Id, clientOid, err)
+		return nil
+	}
+	a.stateBranch.childIds[order.Id] = true
+	a.stateBranch.working[order.Id] = &algoChild{remaining: volume, market: ordType == "market", placedAt: time.Now()}
+	var progress []AlgoProgress
+	for _, trade := range orphans {
+		if trade.Oid == order.Id {
+			progress = append(progress, a.filled(trade))
+		}
+	}
+	a.stateBranch.Unlock()
+	a.logger.Infof("algo %s placed child %s %s %s@%s", a.Id, clientOid, a.Config.Side, volume, a.Config.LimitPrice)
+	return progress
+}
+
+func (a *AlgoOrder) cancelLimitChildren() {
+	a.cancelChildren(false)
+}
+
+// cancelWorking cancels the live children, the market ones are left to settle.
+func (a *AlgoOrder) cancelWorking() {
+	a.actionMutex.Lock()
+	defer a.actionMutex.Unlock()
+	a.cancelChildren(true)
+}
+
+func (a *AlgoOrder) cancelChildren(dropMarket bool) {
+	a.stateBranch.Lock()
+	var ids []int64
+	for id, child := range a.stateBranch.working {
+		if !child.market {
+			ids = append(ids, id)
+		}
+	}
+	a.stateBranch.Unlock()
+
+	for _, id := range ids {
+		// fails when the child was filled meanwhile, its fills still count.
+		if _, err := a.exchange.CancelOrder(a.Config.Market, id, nil); err != nil {
+			a.logger.Debugf("algo %s fail to cancel child %d: %v", a.Id, id, err)
+		}
+	}
+
+	a.stateBranch.Lock()
+	for _, id := range ids {
+		delete(a.stateBranch.working, id)
+	}
+	if dropMarket {
+		for id, child := range a.stateBranch.working {
+			if child.market {
+				delete(a.stateBranch.working, id)
+			}
+		}
+	}
+	a.stateBranch.Unlock()
+}
+
+func (a *AlgoOrder) tradeReportArrived(trade Trade) {
+	a.stateBranch.Lock()
+	if !a.stateBranch.childIds[trade.Oid] {
+		if a.stateBranch.inflight > 0 {
+			a.stateBranch.orphans = append(a.stateBranch.orphans, trade)
+		}
+		a.stateBranch.Unlock()
+		return
+	}
+	progress := a.filled(trade)
+	a.stateBranch.Unlock()
+	a.reportProgress([]AlgoProgress{progress})
+	a.notify()
+}
+
+// filled books a fill of a child, with stateBranch locked.
+func (a *AlgoOrder) filled(trade Trade) AlgoProgress {
+	volume, price := decimalOrZero(trade.Volume), decimalOrZero(trade.Price)
+	a.stateBranch.filled = a.stateBranch.filled.Add(volume)
+	a.stateBranch.notional = a.stateBranch.notional.Add(volume.Mul(price))
+	if a.Kind == AlgoVWAP {
+		a.ownFilled(algoPrint{price: price, volume: volume, at: time.Now()})
+	}
+	if child, ok := a.stateBranch.working[trade.Oid]; ok {
+		child.remaining = child.remaining.Sub(volume)
+		if !child.remaining.IsPositive() {
+			delete(a.stateBranch.working, trade.Oid)
+		}
+	}
+	if a.stateBranch.filled.GreaterThanOrEqual(a.Config.Volume) && a.stateBranch.state != AlgoCanceled && a.stateBranch.state != AlgoDone {
+		a.stateBranch.state = AlgoDone
+		a.finish()
+		a.logger.Infof("algo %s done, %s %s at %s", a.Id, a.Config.Side, a.stateBranch.filled, a.stateBranch.notional.Div(a.stateBranch.filled).Round(a.pricePrec))
+	}
+	return a.progress()
+}
+
+func (a *AlgoOrder) reportProgress(progress []AlgoProgress) {
+	if a.Config.OnProgress == nil {
+		return
+	}
+	for _, p := range progress {
+		a.Config.OnProgress(p)
+	}
+}
+
+func (a *AlgoOrder) publicTradeArrived(trade PublicTrade) {
+	if NormalizeMarket(trade.Market) != a.Config.Market {
+		return
+	}
+	limit := a.Config.LimitPrice
+	if !limit.IsZero() && ((a.Config.Side == "buy" && trade.Price.GreaterThan(limit)) || (a.Config.Side == "sell" && trade.Price.LessThan(limit))) {
+		return
+	}
+	a.stateBranch.Lock()
+	defer a.stateBranch.Unlock()
+	if a.stateBranch.state != AlgoRunning {
+		return
+	}
+	p := algoPrint{price: trade.Price, volume: trade.Volume, at: time.Now()}
+	// the print of an own fill is not volume of others.
+	if i := matchPrint(a.stateBranch.unprinted, p); i >= 0 {
+		a.stateBranch.unprinted = append(a.stateBranch.unprinted[:i], a.stateBranch.unprinted[i+1:]...)
+		return
+	}
+	a.stateBranch.marketVolume = a.stateBranch.marketVolume.Add(trade.Volume)
+	a.stateBranch.printed = append(prunePrints(a.stateBranch.printed, p.at), p)
+}
+
+// ownFilled takes an own fill printed already off the market volume, or waits for its print, with stateBranch locked.
+func (a *AlgoOrder) ownFilled(fill algoPrint) {
+	if i := matchPrint(a.stateBranch.printed, fill); i >= 0 {
+		a.stateBranch.printed = append(a.stateBranch.printed[:i], a.stateBranch.printed[i+1:]...)
+		a.stateBranch.marketVolume = a.stateBranch.marketVolume.Sub(fill.volume)
+		return
+	}
+	a.stateBranch.unprinted = append(prunePrints(a.stateBranch.unprinted, fill.at), fill)
+}
+
+// matchPrint finds the print of the same price and volume within ownPrintWindow, -1 if none.
+func matchPrint(prints []algoPrint, p algoPrint) int {
+	for i, q := range prints {
+		if q.price.Equal(p.price) && q.volume.Equal(p.volume) && p.at.Sub(q.at) <= ownPrintWindow {
+			return i
+		}
+	}
+	return -1
+}
+
+func prunePrints(prints []algoPrint, now time.Time) []algoPrint {
+	kept := prints[:0]
+	for _, p := range prints {
+		if now.Sub(p.at) <= ownPrintWindow {
+			kept = append(kept, p)
+		}
+	}
+	return kept
+}
+
+func (a *AlgoOrder) notify() {
+	select {
+	case a.wake <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,132 @@
+package max_RESTfulAPI
+
+import (
+	"io"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/sirupsen/logrus"
+)
+
+func TestAlgoOrders(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	markets := []Market{{Id: "btcusdt", BaseUnit: "btc", QuoteUnit: "usdt", BaseUnitPrecision: 4, QuoteUnitPrecision: 2}}
+	d := decimal.RequireFromString
+
+	// the iceberg shows 0.3 and refills once the shown part is filled.
+	ex := NewMemoryExchange(markets)
+	var progress []AlgoProgress
+	iceberg, err := NewAlgoOrder(ex, AlgoIceberg, AlgoConfig{
+		Market: "BTC/USDT", Side: "buy", Volume: d("1"), LimitPrice: d("100.009"), ShowSize: d("0.3"),
+		OnProgress: func(p AlgoProgress) { progress = append(progress, p) },
+	}, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	iceberg.start()
+	iceberg.step()
+	rows, _ := ex.OpenOrderRows()
+	if len(rows) != 1 || !rows[0].Price.Equal(d("100")) || !rows[0].Qty.Equal(d("0.3")) {
+		t.Fatalf("first child %+v", rows)
+	}
+	ex.Fill(mustOid(t, rows[0].Oid), d("100"), d("0.1"), decimal.Zero, true)
+	iceberg.step()
+	if len(ex.Placed()) != 1 {
+		t.Fatalf("refilled a partly filled child: %d orders", len(ex.Placed()))
+	}
+	ex.Fill(mustOid(t, rows[0].Oid), d("99"), d("0.2"), decimal.Zero, true)
+	iceberg.step()
+	if len(ex.Placed()) != 2 {
+		t.Fatalf("not refilled: %d orders", len(ex.Placed()))
+	}
+	p := iceberg.Progress()
+	if !p.Filled.Equal(d("0.3")) || !p.Working.Equal(d("0.3")) || !p.AvgPrice.Equal(d("99.33")) || p.Children != 2 || len(progress) != 2 {
+		t.Errorf("progress %+v, %d reports", p, len(progress))
+	}
+
+	// pausing pulls the child, resuming shows it again.
+	iceberg.Pause()
+	iceberg.step()
+	if rows, _ := ex.OpenOrderRows(); len(rows) != 0 || iceberg.Progress().State != AlgoPaused {
+		t.Fatalf("paused with %+v", rows)
+	}
+	iceberg.Resume()
+	iceberg.step()
+	rows, _ = ex.OpenOrderRows()
+	if len(rows) != 1 || !rows[0].Qty.Equal(d("0.3")) {
+		t.Fatalf("resumed child %+v", rows)
+	}
+
+	// the vwap follows 10% of the public volume with market children.
+	trades := newTradeStreamBranch([]string{"btcusdt"}, TradeStreamConfig{}, logger)
+	vwap, err := NewAlgoOrder(ex, AlgoVWAP, AlgoConfig{
+		Market: "btcusdt", Side: "sell", Volume: d("1"), Participation: d("0.1"), Trades: trades,
+	}, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	vwap.start()
+	trades.tradesArrived([]PublicTrade{{Market: "btcusdt", Price: d("100"), Volume: d("2")}, {Market: "ethusdt", Price: d("10"), Volume: d("50")}})
+	vwap.step()
+	vwap.step()
+	placed := ex.Placed()
+	if len(placed) != 4 || placed[3].OrdType != "market" || placed[3].Volume != "0.2" {
+		t.Fatalf("vwap children %+v", placed[2:])
+	}
+	ex.Fill(placed[3].Id, d("100"), d("0.2"), decimal.Zero, false)
+	trades.tradesArrived([]PublicTrade{{Market: "btcusdt", Price: d("100"), Volume: d("1")}})
+	vwap.step()
+	placed = ex.Placed()
+	if len(placed) != 5 || placed[4].Volume != "0.1" || !vwap.Progress().Filled.Equal(d("0.2")) {
+		t.Fatalf("vwap children %+v", placed[3:])
+	}
+	// the iceberg ignores the vwap fills.
+	if !iceberg.Progress().Filled.Equal(d("0.3")) {
+		t.Errorf("iceberg took a vwap fill: %+v", iceberg.Progress())
+	}
+	// the print of its own fill is not volume to follow.
+	ex.Fill(placed[4].Id, d("100"), d("0.1"), decimal.Zero, false)
+	trades.tradesArrived([]PublicTrade{{Market: "btcusdt", Price: d("100"), Volume: d("0.1")}})
+	vwap.step()
+	if placed = ex.Placed(); len(placed) != 5 {
+		t.Fatalf("vwap followed its own fill %+v", placed[4:])
+	}
+	// nor is it once unsubscribed.
+	vwap.Cancel()
+	for _, unsubscribe := range vwap.unsubscribes {
+		unsubscribe()
+	}
+	ex.Fill(placed[3].Id, d("100"), d("0.1"), decimal.Zero, false)
+	if !vwap.Progress().Filled.Equal(d("0.3")) {
+		t.Errorf("unsubscribed vwap took a fill: %+v", vwap.Progress())
+	}
+
+	// the first twap slice is a quarter, canceling pulls it.
+	twap, err := NewAlgoOrder(ex, AlgoTWAP, AlgoConfig{
+		Market: "btcusdt", Side: "sell", Volume: d("1"), LimitPrice: d("100"), Duration: time.Hour, Slices: 4,
+	}, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	twap.start()
+	twap.step()
+	placed = ex.Placed()
+	if len(placed) != 6 || placed[5].Volume != "0.25" || placed[5].OrdType != "limit" {
+		t.Fatalf("twap slice %+v", placed[5:])
+	}
+	twap.Cancel()
+	select {
+	case <-twap.Done():
+	default:
+		t.Error("canceled twap not done")
+	}
+	if rows, _ := ex.OpenOrderRows(); len(rows) != 1 {
+		t.Errorf("open orders after cancel %+v", rows)
+	}
+
+	if _, err := NewAlgoOrder(ex, AlgoIceberg, AlgoConfig{Market: "btcusdt", Side: "buy", Volume: d("1")}, logger); err == nil {
+		t.Error("iceberg without show size")
+	}
+}
@@ -0,0 +1,228 @@
+package max_RESTfulAPI
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// APIError is a non-2xx response of the MAX api.
+type APIError struct {
+	StatusCode int
+	// MAX error code, e.g. 2005 for a signature error, zero if the body has none.
+	Code    int
+	Message string
+	Body    []byte
+}
+
+func (e *APIError) Error() string {
+	if e.Code != 0 {
+		return fmt.Sprintf("max api error %d (status %d): %s", e.Code, e.StatusCode, e.Message)
+	}
+	return fmt.Sprintf("max api status %d: %s", e.StatusCode, e.Body)
+}
+
+func decodeAPIError(resp *http.Response, body []byte) error {
+	apiErr := &APIError{StatusCode: resp.StatusCode, Body: body}
+	var wrapped struct {
+		Error struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if json.Unmarshal(body, &wrapped) == nil {
+		apiErr.Code = wrapped.Error.Code
+		apiErr.Message = wrapped.Error.Message
+	}
+	return apiErr
+}
+
+var pathParamPattern = regexp.MustCompile(`\{(\w+)\}`)
+
+// DoPublic calls a public endpoint, e.g. DoPublic(ctx, "GET", "/api/v2/tickers/{market}", map[string]interface{}{"market": "btctwd"}, &ticker).
+// Parameters named in the path are embedded into it, the rest go to the query of GET and DELETE or to the json body otherwise.
+// The response is decoded into out if it is not nil.
+func (c *APIClient) DoPublic(ctx context.Context, method, path string, params map[string]interface{}, out interface{}) (*http.Response, error) {
+	return c.do(ctx, nil, method, path, params, out)
+}
+
+// DoPrivate calls a signed endpoint like DoPublic, adding the nonce and signing the payload with signer.
+func (c *APIClient) DoPrivate(ctx context.Context, signer Signer, method, path string, params map[string]interface{}, out interface{}) (*http.Response, error) {
+	if signer == nil {
+		return nil, fmt.Errorf("no signer for %s", path)
+	}
+	return c.do(ctx, signer, method, path, params, out)
+}
+
+func (c *APIClient) do(ctx context.Context, signer Signer, method, path string, params map[string]interface{}, out interface{}) (*http.Response, error) {
+	method = strings.ToUpper(method)
+	path, body, err := embedPathParams(path, params)
+	if err != nil {
+		return nil, err
+	}
+
+	// wait before taking the nonce, a nonce taken early may be older than one already sent when the request leaves.
+	if err := c.limiter.wait(ctx); err != nil {
+		return nil, err
+	}
+
+	headerParams := map[string]string{"Accept": "application/json"}
+	if signer != nil {
+		body["nonce"] = c.Nonce.Next()
+		body["path"] = path
+		payload, signature, err := makePayloadAndSignature(body, signer)
+		if err != nil {
+			return nil, err
+		}
+		headerParams["X-MAX-ACCESSKEY"] = signer.AccessKey()
+		headerParams["X-MAX-PAYLOAD"] = payload
+		headerParams["X-MAX-SIGNATURE"] = signature
+	}
+
+	queryParams := url.Values{}
+	var postBody interface{}
+	switch method {
+	case http.MethodGet, http.MethodDelete:
+		for key, value := range body {
+			if key == "path" {
+				continue
+			}
+			addQueryParam(queryParams, key, value)
+		}
+	default:
+		headerParams["Content-Type"] = "application/json"
+		postBody = body
+	}
+
+	r, err := c.prepareRequest(ctx, c.cfg.BasePath+path, method, postBody, headerParams, queryParams, url.Values{}, "", nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.callAPI(r)
+	if err != nil || resp == nil {
+		return resp, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return resp, err
+	}
+	if resp.StatusCode >= 300 {
+		return resp, decodeAPIError(resp, respBody)
+	}
+	if out != nil {
+		if err := json.Unmarshal(respBody, out); err != nil {
+			return resp, fmt.Errorf("fail to decode %s: %w", path, err)
+		}
+	}
+	return resp, nil
+}
+
+// embedPathParams fills {name} in the path from params and returns the remaining params as a new map.
+func embedPathParams(path string, params map[string]interface{}) (string, map[string]interface{}, error) {
+	rest := make(map[string]interface{}, len(params)+2)
+	for key, value := range params {
+		rest[key] = value
+	}
+
+	var missing []string
+	path = pathParamPattern.ReplaceAllStringFunc(path, func(match string) string {
+		name := match[1 : len(match)-1]
+		value, ok := rest[name]
+		if !ok {
+			missing = append(missing, name)
+			return match
+		}
+		delete(rest, name)
+		return url.PathEscape(parameterToString(value, ""))
+	})
+	if len(missing) > 0 {
+		return "", nil, fmt.Errorf("missing path parameters %v of %s", missing, path)
+	}
+	return path, rest, nil
+}
+
+// addQueryParam encodes slices as key[]=a&key[]=b as the MAX api expects.
+func addQueryParam(query url.Values, key string, value interface{}) {
+	if value == nil {
+		return
+	}
+	v := reflect.ValueOf(value)
+	if v.Kind() == reflect.Slice {
+		for i := 0; i < v.Len(); i++ {
+			query.Add(key+"[]", parameterToString(v.Index(i).Interface(), ""))
+		}
+		return
+	}
+	query.Add(key, parameterToString(value, ""))
+}
+
+// copyOptionals adds the given keys of the optional parameters of the generated methods to params.
+func copyOptionals(params, optionals map[string]interface{}, keys ...string) {
+	for _, key := range keys {
+		if value, ok := optionals[key]; ok && value != nil {
+			params[key] = value
+		}
+	}
+}
+
+// rateLimiter is a token bucket shared by every request of an APIClient.
+type rateLimiter struct {
+	rate  float64
+	burst float64
+
+	mux    sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// newRateLimiter allows rate requests per second with bursts of burst, nil if rate is not positive.
+func newRateLimiter(rate float64, burst int) *rateLimiter {
+	if rate <= 0 {
+		return nil
+	}
+	if burst < 1 {
+		burst = 1
+	}
+	return &rateLimiter{rate: rate, burst: float64(burst), tokens: float64(burst), last: time.Now()}
+}
+
+func (l *rateLimiter) wait(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+	for {
+		l.mux.Lock()
+		now := time.Now()
+		l.tokens += now.Sub(l.last).Seconds() * l.rate
+		if l.tokens > l.burst {
+			l.tokens = l.burst
+		}
+		l.last = now
+		if l.tokens >= 1 {
+			l.tokens--
+			l.mux.Unlock()
+			return nil
+		}
+		wait := time.Duration((1 - l.tokens) / l.rate * float64(time.Second))
+		l.mux.Unlock()
+
+		if ctx == nil {
+			time.Sleep(wait)
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
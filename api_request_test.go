@@ -0,0 +1,145 @@
+package max_RESTfulAPI
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// test signed GET requests embed the path, sign the payload and decode errors
+func TestDoPrivate(t *testing.T) {
+	signer := NewHMACSigner("access", "secret")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		payload := r.Header.Get("X-MAX-PAYLOAD")
+		want, _ := signer.Sign([]byte(payload))
+		if r.Header.Get("X-MAX-SIGNATURE") != want || r.Header.Get("X-MAX-ACCESSKEY") != "access" {
+			t.Errorf("bad signature headers %v", r.Header)
+		}
+		raw, _ := base64.StdEncoding.DecodeString(payload)
+		var body map[string]interface{}
+		json.Unmarshal(raw, &body)
+		if body["path"] != r.URL.Path || body["nonce"] == nil {
+			t.Errorf("bad payload %s", raw)
+		}
+
+		switch r.URL.Path {
+		case "/api/v3/wallet/spot/orders":
+			if r.URL.Query().Get("market") != "btctwd" || len(r.URL.Query()["states[]"]) != 2 {
+				t.Errorf("bad query %s", r.URL.RawQuery)
+			}
+			w.Write([]byte(`[{"id":1,"market":"btctwd"}]`))
+		default:
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error":{"code":2005,"message":"Signature is incorrect."}}`))
+		}
+	}))
+	defer server.Close()
+
+	cfg := NewConfiguration()
+	cfg.BasePath = server.URL
+	api := NewAPIClient(cfg)
+
+	var orders []Order
+	params := map[string]interface{}{"wallet_type": "spot", "market": "btctwd", "states": []string{"wait", "convert"}}
+	if _, err := api.DoPrivate(context.Background(), signer, "GET", "/api/v3/wallet/{wallet_type}/orders", params, &orders); err != nil {
+		t.Fatal(err)
+	}
+	if len(orders) != 1 || orders[0].Id != 1 {
+		t.Fatalf("orders %+v", orders)
+	}
+
+	_, err := api.DoPrivate(context.Background(), signer, "GET", "/api/v2/members/me", nil, nil)
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Code != 2005 || apiErr.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("error %v", err)
+	}
+}
+
+// test canceling by client oid sends the client_oid MAX reads
+func TestCancelOrderByClientOid(t *testing.T) {
+	signer := NewHMACSigner("access", "secret")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		raw, _ := base64.StdEncoding.DecodeString(r.Header.Get("X-MAX-PAYLOAD"))
+		var body map[string]interface{}
+		json.Unmarshal(raw, &body)
+		if r.URL.Path != "/api/v2/order/delete" || body["client_oid"] != "algo-1" || body["client_id"] != nil {
+			t.Errorf("bad cancel %s %s", r.URL.Path, raw)
+		}
+		w.Write([]byte(`{"id":7,"market":"btcusdt","state":"cancel"}`))
+	}))
+	defer server.Close()
+
+	cfg := NewConfiguration()
+	cfg.BasePath = server.URL
+	api := NewAPIClient(cfg)
+	order, _, err := api.PrivateApi.PostApiV2OrderDeleteClientId(context.Background(), signer, "algo-1")
+	if err != nil || order.Id != 7 {
+		t.Fatalf("order %+v, err %v", order, err)
+	}
+}
+
+// test the client is not rate limited unless configured
+func TestRateLimitOptIn(t *testing.T) {
+	if NewAPIClient(NewConfiguration()).limiter != nil {
+		t.Fatal("default client is rate limited")
+	}
+	cfg := NewConfiguration()
+	cfg.RateLimit, cfg.RateBurst = 20, 1
+	limiter := NewAPIClient(cfg).limiter
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if err := limiter.wait(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+		t.Errorf("3 requests at 20/s took %s", elapsed)
+	}
+}
+
+// test rate limited requests take their nonce once let through, so they reach MAX in nonce order
+func TestRateLimitNonceOrder(t *testing.T) {
+	var mux sync.Mutex
+	var nonces []float64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		raw, _ := base64.StdEncoding.DecodeString(r.Header.Get("X-MAX-PAYLOAD"))
+		var body map[string]interface{}
+		json.Unmarshal(raw, &body)
+		mux.Lock()
+		nonces = append(nonces, body["nonce"].(float64))
+		mux.Unlock()
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	cfg := NewConfiguration()
+	cfg.BasePath = server.URL
+	cfg.RateLimit, cfg.RateBurst = 50, 1
+	api := NewAPIClient(cfg)
+	signer := NewHMACSigner("access", "secret")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := api.DoPrivate(context.Background(), signer, "GET", "/api/v2/members/me", nil, nil); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if len(nonces) != 6 {
+		t.Fatalf("%d requests", len(nonces))
+	}
+	for i := 1; i < len(nonces); i++ {
+		if nonces[i] <= nonces[i-1] {
+			t.Fatalf("nonces out of order %v", nonces)
+		}
+	}
+}
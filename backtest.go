@@ -0,0 +1,538 @@
+package max_RESTfulAPI
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	// the order is below min_base_amount or min_quote_amount of the market.
+	ErrBelowMinSize = errors.New("order below the market min size")
+	ErrNoLiquidity  = errors.New("no liquidity")
+)
+
+type BacktestConfig struct {
+	Market string
+	// files of raw websocket frames written by FrameRecorder.
+	Files []string
+	From  time.Time
+	To    time.Time
+
+	// precisions and min sizes of the market, e.g. from GetMaxMarketInfo. Not enforced if Info.ID is empty.
+	Info MaxMarketInfo
+	// maker/taker rates, default to the VIP 0 schedule. Fees are charged in the received currency.
+	Fees *FeeModel
+	// between the strategy sending an order or a cancel and the exchange applying it.
+	Latency time.Duration
+
+	// starting base and quote balances, not enforced, inventory may go negative.
+	Base  decimal.Decimal
+	Quote decimal.Decimal
+
+	// virtual time between two samples of the series, default to 1 minute. Every fill is sampled too.
+	SampleInterval time.Duration
+}
+
+// BacktestEvent is what the strategy reacts to.
+type BacktestEvent struct {
+	At time.Time
+	// "book", "trade" or "fill".
+	Kind  string
+	Trade PublicTrade
+	Fill  Trade
+}
+
+// BacktestStrategy is called on every event, on the replay goroutine.
+type BacktestStrategy func(bt *Backtester, event BacktestEvent)
+
+// BacktestPoint is one sample of the result series, in the quote currency.
+type BacktestPoint struct {
+	At        time.Time
+	Mid       decimal.Decimal
+	Inventory decimal.Decimal
+	Cash      decimal.Decimal
+	Equity    decimal.Decimal
+	Pnl       decimal.Decimal
+	// from the highest equity so far, positive.
+	Drawdown decimal.Decimal
+}
+
+type BacktestResult struct {
+	Fills  []Trade
+	Series []BacktestPoint
+	// fees in the quote currency.
+	Fees        decimal.Decimal
+	Pnl         decimal.Decimal
+	MaxDrawdown decimal.Decimal
+	// orders rejected by the exchange rules when they arrived, e.g. post only crossing.
+	Rejected int
+}
+
+// Backtester replays recorded book and trade frames of one market through the local orderbook and
+// trade stream handlers, and simulates the orders of a strategy against them. Limit orders join the
+// back of the queue of their price level and only fill once the volume ahead of them has traded.
+type Backtester struct {
+	Config   BacktestConfig
+	Strategy BacktestStrategy
+
+	logger *logrus.Logger
+	replay *Replay
+	book   *OrderbookBranch
+
+	base, quote string
+	inventory   decimal.Decimal
+	cash        decimal.Decimal
+	orders      map[int64]*btOrder
+	pending     []btAction
+	// volume the orders took from the book levels, until the level changes.
+	consumed map[string]*paperLevel
+	orderId  int64
+	tradeId  int64
+
+	result      BacktestResult
+	startEquity decimal.Decimal
+	peakEquity  decimal.Decimal
+	started     bool
+	lastSample  time.Time
+}
+
+type btOrder struct {
+	id        int64
+	side      string
+	ordType   string
+	price     decimal.Decimal
+	remaining decimal.Decimal
+	// resting volume of the price level in front of the order.
+	queueAhead decimal.Decimal
+}
+
+type btAction struct {
+	due    time.Time
+	order  *btOrder
+	cancel int64
+}
+
+func NewBacktester(cfg BacktestConfig, strategy BacktestStrategy, logger *logrus.Logger) *Backtester {
+	cfg.Market = NormalizeMarket(cfg.Market)
+	if cfg.Fees == nil {
+		cfg.Fees = NewFeeModel("usdt", nil)
+	}
+	if cfg.SampleInterval <= 0 {
+		cfg.SampleInterval = time.Minute
+	}
+	bt := &Backtester{Config: cfg, Strategy: strategy, logger: logger, orders: make(map[int64]*btOrder), consumed: make(map[string]*paperLevel)}
+	bt.base, bt.quote = NormalizeCurrency(cfg.Info.BaseUnit), NormalizeCurrency(cfg.Info.QuoteUnit)
+	if bt.base == "" || bt.quote == "" {
+		bt.base, bt.quote, _ = splitMarket(nil, cfg.Market)
+	}
+	bt.inventory, bt.cash = cfg.Base, cfg.Quote
+	bt.result.Fees = decimal.Zero
+	return bt
+}
+
+// Run replays the files and returns the fills and the series.
+func (bt *Backtester) Run(ctx context.Context) (BacktestResult, error) {
+	bt.replay = NewReplay(bt.Config.Files, ReplayConfig{From: bt.Config.From, To: bt.Config.To}, bt.logger)
+	bt.book = bt.replay.Orderbook(bt.Config.Market)
+	trades := bt.replay.TradeStream([]string{bt.Config.Market}, TradeStreamConfig{BufferSize: -1})
+	trades.OnTrade(bt.tradeArrived)
+	// registered after the book, so the book is up to date when the strategy runs.
+	bt.replay.addHandler(replayHandler{
+		match:  func(_, channel, market string) bool { return channel == "book" && market == bt.Config.Market },
+		handle: func([]byte) error { bt.bookUpdated(); return nil },
+	})
+
+	if err := bt.replay.Run(ctx); err != nil {
+		return bt.result, err
+	}
+	bt.sample(bt.Now())
+	return bt.result, nil
+}
+
+// Now is the virtual time of the event being replayed.
+func (bt *Backtester) Now() time.Time {
+	return bt.replay.Now()
+}
+
+// Book is the local orderbook rebuilt from the frames.
+func (bt *Backtester) Book() *OrderbookBranch {
+	return bt.book
+}
+
+// Inventory returns the simulated base and quote balances.
+func (bt *Backtester) Inventory() (base, quote decimal.Decimal) {
+	return bt.inventory, bt.cash
+}
+
+// OpenOrders returns the resting orders as WsOrder, by id.
+func (bt *Backtester) OpenOrders() []WsOrder {
+	orders := make([]WsOrder, 0, len(bt.orders))
+	for _, o := range bt.orders {
+		orders = append(orders, WsOrder{
+			Id:              o.id,
+			Side:            o.side,
+			OrdType:         o.ordType,
+			Price:           o.price.String(),
+			Market:          bt.Config.Market,
+			RemainingVolume: o.remaining.String(),
+			State:           "wait",
+		})
+	}
+	sort.Slice(orders, func(i, j int) bool { return orders[i].Id < orders[j].Id })
+	return orders
+}
+
+func (bt *Backtester) PlaceLimitOrder(side string, price, volume float64) (int64, error) {
+	return bt.submit(side, "limit", decimal.NewFromFloat(price), decimal.NewFromFloat(volume))
+}
+
+func (bt *Backtester) PlacePostOnlyOrder(side string, price, volume float64) (int64, error) {
+	return bt.submit(side, "post_only", decimal.NewFromFloat(price), decimal.NewFromFloat(volume))
+}
+
+func (bt *Backtester) PlaceMarketOrder(side string, volume float64) (int64, error) {
+	return bt.submit(side, "market", decimal.Zero, decimal.NewFromFloat(volume))
+}
+
+// CancelOrder cancels an order once the latency has passed, it may fill meanwhile.
+func (bt *Backtester) CancelOrder(id int64) {
+	bt.pending = append(bt.pending, btAction{due: bt.Now().Add(bt.Config.Latency), cancel: id})
+}
+
+// submit checks the market rules like the api does, then sends the order with the latency.
+func (bt *Backtester) submit(side, ordType string, price, volume decimal.Decimal) (int64, error) {
+	side = strings.ToLower(side)
+	if side != "buy" && side != "sell" {
+		return 0, fmt.Errorf("unknown side %q", side)
+	}
+	info := bt.Config.Info
+	if info.ID != "" {
+		price = price.Truncate(int32(info.QuoteUnitPrecision))
+		volume = volume.Truncate(int32(info.BaseUnitPrecision))
+		if volume.LessThan(info.MinBaseAmount) || (ordType != "market" && price.Mul(volume).LessThan(info.MinQuoteAmount)) {
+			return 0, fmt.Errorf("%w: %s@%s", ErrBelowMinSize, volume, price)
+		}
+	}
+	if !volume.IsPositive() || (ordType != "market" && !price.IsPositive()) {
+		return 0, fmt.Errorf("invalid price %s or volume %s", price, volume)
+	}
+
+	bt.orderId++
+	o := &btOrder{id: bt.orderId, side: side, ordType: ordType, price: price, remaining: volume}
+	bt.pending = append(bt.pending, btAction{due: bt.Now().Add(bt.Config.Latency), order: o})
+	return o.id, nil
+}
+
+func (bt *Backtester) bookUpdated() {
+	now := bt.Now()
+	bt.begin()
+	bt.pruneConsumed()
+	bt.processDue(now)
+
+	for _, o := range bt.restingOrders() {
+		// cancels ahead of the order shrink its queue, the level can't hold more than is left.
+		level := bt.levelVolume(o.side, o.price)
+		if level.LessThan(o.queueAhead) {
+			o.queueAhead = level
+		}
+		// the opposite side moved through the price, the order would have traded with what is new there.
+		for _, level := range bt.depleted(o.side, bt.crossable(o.side, o.price)) {
+			if !o.remaining.IsPositive() {
+				break
+			}
+			volume := decimal.Min(level[1], o.remaining)
+			bt.consume(o.side, level[0], volume)
+			bt.fill(o, o.price, volume, true)
+		}
+	}
+
+	bt.callStrategy(BacktestEvent{At: now, Kind: "book"})
+	bt.maybeSample(now)
+}
+
+func (bt *Backtester) tradeArrived(trade PublicTrade) {
+	now := bt.Now()
+	bt.begin()
+	bt.processDue(now)
+
+	left := trade.Volume
+	for _, o := range bt.restingOrders() {
+		if !left.IsPositive() {
+			break
+		}
+		var through bool
+		switch {
+		case o.side == "buy" && trade.Side != TakerSideBuy && trade.Price.LessThanOrEqual(o.price):
+			through = trade.Price.LessThan(o.price)
+		case o.side == "sell" && trade.Side != TakerSideSell && trade.Price.GreaterThanOrEqual(o.price):
+			through = trade.Price.GreaterThan(o.price)
+		default:
+			continue
+		}
+		// a trade at the price eats the queue first, one through the price means the level is gone.
+		if !through {
+			eaten := decimal.Min(left, o.queueAhead)
+			o.queueAhead = o.queueAhead.Sub(eaten)
+			left = left.Sub(eaten)
+		} else {
+			o.queueAhead = decimal.Zero
+		}
+		if volume := decimal.Min(left, o.remaining); volume.IsPositive() {
+			left = left.Sub(volume)
+			bt.fill(o, o.price, volume, true)
+		}
+	}
+
+	bt.callStrategy(BacktestEvent{At: now, Kind: "trade", Trade: trade})
+	bt.maybeSample(now)
+}
+
+func (bt *Backtester) callStrategy(event BacktestEvent) {
+	if bt.Strategy != nil {
+		bt.Strategy(bt, event)
+	}
+	// without latency the orders of the strategy reach the book right away.
+	bt.processDue(event.At)
+}
+
+// processDue applies the orders and cancels whose latency has passed, in the order sent.
+func (bt *Backtester) processDue(now time.Time) {
+	for len(bt.pending) > 0 && !bt.pending[0].due.After(now) {
+		action := bt.pending[0]
+		bt.pending = bt.pending[1:]
+		if action.order != nil {
+			bt.arrive(action.order)
+		} else {
+			delete(bt.orders, action.cancel)
+		}
+	}
+}
+
+// arrive matches an order reaching the exchange, the rest joins the back of its price level.
+func (bt *Backtester) arrive(o *btOrder) {
+	crossed := bt.crossable(o.side, o.price)
+	if o.ordType == "market" {
+		crossed = bt.levels(o.side)
+	}
+	crossed = bt.depleted(o.side, crossed)
+	if o.ordType == "post_only" && len(crossed) > 0 {
+		bt.result.Rejected++
+		return
+	}
+	for _, level := range crossed {
+		if !o.remaining.IsPositive() {
+			break
+		}
+		volume := decimal.Min(level[1], o.remaining)
+		bt.consume(o.side, level[0], volume)
+		bt.fill(o, level[0], volume, false)
+	}
+	if o.ordType == "market" || !o.remaining.IsPositive() {
+		return
+	}
+	o.queueAhead = bt.levelVolume(o.side, o.price)
+	bt.orders[o.id] = o
+}
+
+func (bt *Backtester) fill(o *btOrder, price, volume decimal.Decimal, maker bool) {
+	if !volume.IsPositive() {
+		return
+	}
+	rate := bt.Config.Fees.EffectiveRate(maker)
+	notional := price.Mul(volume)
+
+	var fee decimal.Decimal
+	var feeCurrency string
+	if o.side == "buy" {
+		fee, feeCurrency = volume.Mul(rate), bt.base
+		bt.inventory = bt.inventory.Add(volume).Sub(fee)
+		bt.cash = bt.cash.Sub(notional)
+		bt.result.Fees = bt.result.Fees.Add(fee.Mul(price))
+	} else {
+		fee, feeCurrency = notional.Mul(rate), bt.quote
+		bt.inventory = bt.inventory.Sub(volume)
+		bt.cash = bt.cash.Add(notional).Sub(fee)
+		bt.result.Fees = bt.result.Fees.Add(fee)
+	}
+	o.remaining = o.remaining.Sub(volume)
+	if !o.remaining.IsPositive() {
+		delete(bt.orders, o.id)
+	}
+
+	bt.tradeId++
+	trade := Trade{
+		Id:          bt.tradeId,
+		Oid:         o.id,
+		Price:       price.String(),
+		Volume:      volume.String(),
+		Market:      bt.Config.Market,
+		Timestamp:   bt.Now().UnixMilli(),
+		Side:        o.side,
+		Fee:         fee.String(),
+		FeeCurrency: feeCurrency,
+		Maker:       maker,
+	}
+	bt.result.Fills = append(bt.result.Fills, trade)
+	bt.sample(bt.Now())
+	if bt.Strategy != nil {
+		bt.Strategy(bt, BacktestEvent{At: bt.Now(), Kind: "fill", Fill: trade})
+	}
+}
+
+// levels of the opposite side of an order, best first.
+func (bt *Backtester) levels(side string) [][]decimal.Decimal {
+	var levels [][]decimal.Decimal
+	if side == "buy" {
+		levels, _ = bt.book.GetAsks()
+	} else {
+		levels, _ = bt.book.GetBids()
+	}
+	return levels
+}
+
+// crossable returns the opposite side levels at or through price.
+func (bt *Backtester) crossable(side string, price decimal.Decimal) [][]decimal.Decimal {
+	var crossed [][]decimal.Decimal
+	for _, level := range bt.levels(side) {
+		if (side == "buy" && level[0].GreaterThan(price)) || (side == "sell" && level[0].LessThan(price)) {
+			break
+		}
+		crossed = append(crossed, []decimal.Decimal{level[0], level[1]})
+	}
+	return crossed
+}
+
+// depleted takes off the levels the volume the orders already took, a resized level is taken as new.
+func (bt *Backtester) depleted(side string, levels [][]decimal.Decimal) [][]decimal.Decimal {
+	var left [][]decimal.Decimal
+	for _, level := range levels {
+		consumed, ok := bt.consumed[levelKey(bt.Config.Market, side, level[0])]
+		if ok && consumed.size.Equal(level[1]) {
+			level = []decimal.Decimal{level[0], level[1].Sub(consumed.used)}
+		}
+		if level[1].IsPositive() {
+			left = append(left, level)
+		}
+	}
+	return left
+}
+
+// consume records volume taken from the opposite level at price by an order on side.
+func (bt *Backtester) consume(side string, price, volume decimal.Decimal) {
+	key := levelKey(bt.Config.Market, side, price)
+	size := bt.oppositeVolume(side, price)
+	consumed, ok := bt.consumed[key]
+	if !ok || !consumed.size.Equal(size) {
+		consumed = &paperLevel{size: size, used: decimal.Zero}
+		bt.consumed[key] = consumed
+	}
+	consumed.used = consumed.used.Add(volume)
+}
+
+// pruneConsumed forgets the levels the last book change resized or removed.
+func (bt *Backtester) pruneConsumed() {
+	for key, consumed := range bt.consumed {
+		_, side, price := splitLevelKey(key)
+		if !bt.oppositeVolume(side, price).Equal(consumed.size) {
+			delete(bt.consumed, key)
+		}
+	}
+}
+
+// oppositeVolume is the volume at price on the opposite side of an order.
+func (bt *Backtester) oppositeVolume(side string, price decimal.Decimal) decimal.Decimal {
+	for _, level := range bt.levels(side) {
+		if level[0].Equal(price) {
+			return level[1]
+		}
+	}
+	return decimal.Zero
+}
+
+// levelVolume is the resting volume at price on the side of an order.
+func (bt *Backtester) levelVolume(side string, price decimal.Decimal) decimal.Decimal {
+	var levels [][]decimal.Decimal
+	if side == "buy" {
+		levels, _ = bt.book.GetBids()
+	} else {
+		levels, _ = bt.book.GetAsks()
+	}
+	for _, level := range levels {
+		if level[0].Equal(price) {
+			return level[1]
+		}
+	}
+	return decimal.Zero
+}
+
+// restingOrders by price priority then time.
+func (bt *Backtester) restingOrders() []*btOrder {
+	orders := make([]*btOrder, 0, len(bt.orders))
+	for _, o := range bt.orders {
+		orders = append(orders, o)
+	}
+	sort.Slice(orders, func(i, j int) bool {
+		a, b := orders[i], orders[j]
+		if a.side == b.side && !a.price.Equal(b.price) {
+			if a.side == "buy" {
+				return a.price.GreaterThan(b.price)
+			}
+			return a.price.LessThan(b.price)
+		}
+		return a.id < b.id
+	})
+	return orders
+}
+
+// begin sets the starting equity at the first mid price.
+func (bt *Backtester) begin() {
+	if bt.started {
+		return
+	}
+	mid, ok := bt.book.MidPrice()
+	if !ok {
+		return
+	}
+	bt.started = true
+	bt.startEquity = bt.cash.Add(bt.inventory.Mul(mid))
+	bt.peakEquity = bt.startEquity
+}
+
+func (bt *Backtester) maybeSample(now time.Time) {
+	if now.Sub(bt.lastSample) >= bt.Config.SampleInterval {
+		bt.sample(now)
+	}
+}
+
+func (bt *Backtester) sample(now time.Time) {
+	mid, ok := bt.book.MidPrice()
+	if !ok || !bt.started {
+		return
+	}
+	bt.lastSample = now
+	equity := bt.cash.Add(bt.inventory.Mul(mid))
+	if equity.GreaterThan(bt.peakEquity) {
+		bt.peakEquity = equity
+	}
+	point := BacktestPoint{
+		At:        now,
+		Mid:       mid,
+		Inventory: bt.inventory,
+		Cash:      bt.cash,
+		Equity:    equity,
+		Pnl:       equity.Sub(bt.startEquity),
+		Drawdown:  bt.peakEquity.Sub(equity),
+	}
+	bt.result.Series = append(bt.result.Series, point)
+	bt.result.Pnl = point.Pnl
+	if point.Drawdown.GreaterThan(bt.result.MaxDrawdown) {
+		bt.result.MaxDrawdown = point.Drawdown
+	}
+}
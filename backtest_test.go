@@ -0,0 +1,136 @@
+package max_RESTfulAPI
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/sirupsen/logrus"
+)
+
+// test the queue position of a resting bid and the latency of its placement
+func TestBacktesterQueuePosition(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	dir := t.TempDir()
+	r, err := NewFrameRecorder(dir, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	at := func(ms int) (time.Time, int64) {
+		ts := start.Add(time.Duration(ms) * time.Millisecond)
+		return ts, ts.UnixMilli()
+	}
+	record := func(ms int, format string) {
+		ts, T := at(ms)
+		r.Record("replay", ts, []byte(fmt.Sprintf(format, T)))
+	}
+	record(0, `{"c":"book","e":"snapshot","M":"btcusdt","a":[["101","1"]],"b":[["99","2"]],"T":%d}`)
+	// before the order arrives, must not fill it.
+	record(10, `{"c":"trade","e":"update","M":"btcusdt","t":[{"i":1,"p":"99","v":"0.1","tr":"down"}],"T":%d}`)
+	// eats 1.5 of the 2 ahead.
+	record(100, `{"c":"trade","e":"update","M":"btcusdt","t":[{"i":2,"p":"99","v":"1.5","tr":"down"}],"T":%d}`)
+	// eats the last 0.5 ahead and fills 0.5 of the order.
+	record(200, `{"c":"trade","e":"update","M":"btcusdt","t":[{"i":3,"p":"99","v":"1","tr":"down"}],"T":%d}`)
+	record(300, `{"c":"book","e":"update","M":"btcusdt","a":[["101","0"],["98.5","1"]],"b":[["99","0"],["98","1"]],"T":%d}`)
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+	files, _ := filepath.Glob(filepath.Join(dir, "*.jsonl.gz"))
+
+	fees := NewFeeModel("usdt", nil)
+	fees.Schedule = []FeeTier{{Level: 0, Maker: decimal.Zero, Taker: decimal.RequireFromString("0.001")}}
+	cfg := BacktestConfig{
+		Market:  "btcusdt",
+		Files:   files,
+		Info:    MaxMarketInfo{ID: "btcusdt", BaseUnit: "btc", QuoteUnit: "usdt", BaseUnitPrecision: 4, QuoteUnitPrecision: 2, MinBaseAmount: decimal.RequireFromString("0.001"), MinQuoteAmount: decimal.NewFromInt(8)},
+		Fees:    fees,
+		Latency: 50 * time.Millisecond,
+		Quote:   decimal.NewFromInt(1000),
+	}
+	placed := false
+	bt := NewBacktester(cfg, func(bt *Backtester, event BacktestEvent) {
+		if event.Kind != "book" || placed {
+			return
+		}
+		placed = true
+		if _, err := bt.PlaceLimitOrder("buy", 99, 0.01); err == nil {
+			t.Error("order below min size accepted")
+		}
+		if _, err := bt.PlacePostOnlyOrder("buy", 99, 1); err != nil {
+			t.Error(err)
+		}
+	}, logger)
+
+	result, err := bt.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Fills) != 2 {
+		t.Fatalf("fills %+v", result.Fills)
+	}
+	// the rest fills when the asks move through the order.
+	if result.Fills[0].Volume != "0.5" || result.Fills[1].Volume != "0.5" || !result.Fills[1].Maker {
+		t.Errorf("fills %+v", result.Fills)
+	}
+	base, quote := bt.Inventory()
+	if !base.Equal(decimal.NewFromInt(1)) || !quote.Equal(decimal.NewFromInt(901)) {
+		t.Errorf("inventory %s %s", base, quote)
+	}
+	// bought 1 btc at 99 with the mid from 100 to 98.25, the peak was 1000.5 after the first fill.
+	if !result.Pnl.Equal(decimal.RequireFromString("-0.75")) || !result.MaxDrawdown.Equal(decimal.RequireFromString("1.25")) {
+		t.Errorf("pnl %s, drawdown %s", result.Pnl, result.MaxDrawdown)
+	}
+}
+
+// test book updates that leave a crossed level alone don't fill the resting order again
+func TestBacktesterStaticCrossedBook(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	dir := t.TempDir()
+	r, err := NewFrameRecorder(dir, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	frames := []string{
+		`{"c":"book","e":"snapshot","M":"btcusdt","a":[["101","1"]],"b":[["99","2"]],"T":%d}`,
+		// the asks move through the bid at 100.
+		`{"c":"book","e":"update","M":"btcusdt","a":[["100","0.5"]],"b":[],"T":%d}`,
+		`{"c":"book","e":"update","M":"btcusdt","a":[],"b":[["98","1"]],"T":%d}`,
+		`{"c":"book","e":"update","M":"btcusdt","a":[["102","1"]],"b":[["99","3"]],"T":%d}`,
+		// new volume at the crossed level.
+		`{"c":"book","e":"update","M":"btcusdt","a":[["100","0.8"]],"b":[],"T":%d}`,
+	}
+	for i, frame := range frames {
+		ts := start.Add(time.Duration(i) * 100 * time.Millisecond)
+		r.Record("replay", ts, []byte(fmt.Sprintf(frame, ts.UnixMilli())))
+	}
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+	files, _ := filepath.Glob(filepath.Join(dir, "*.jsonl.gz"))
+
+	placed := false
+	bt := NewBacktester(BacktestConfig{Market: "btcusdt", Files: files, Quote: decimal.NewFromInt(1000)}, func(bt *Backtester, event BacktestEvent) {
+		if event.Kind != "book" || placed {
+			return
+		}
+		placed = true
+		if _, err := bt.PlaceLimitOrder("buy", 100, 3); err != nil {
+			t.Error(err)
+		}
+	}, logger)
+	result, err := bt.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Fills) != 2 || result.Fills[0].Volume != "0.5" || result.Fills[1].Volume != "0.8" {
+		t.Fatalf("fills %+v", result.Fills)
+	}
+}
@@ -0,0 +1,457 @@
+package max_RESTfulAPI
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/sirupsen/logrus"
+)
+
+// Candle is an OHLCV bar, Closed is false while the interval is still in progress.
+type Candle struct {
+	Market   string
+	Interval time.Duration
+	Start    time.Time
+	Open     decimal.Decimal
+	High     decimal.Decimal
+	Low      decimal.Decimal
+	Close    decimal.Decimal
+	Volume   decimal.Decimal
+	Closed   bool
+}
+
+// End is the exclusive end of the candle interval.
+func (c Candle) End() time.Time {
+	return c.Start.Add(c.Interval)
+}
+
+// the periods /api/v2/k accepts, in minutes.
+var klinePeriods = []int64{1, 5, 15, 30, 60, 120, 240, 360, 720, 1440, 4320, 10080}
+
+// GetKlines fetches history candles of a market, interval must be one of the /api/v2/k periods.
+func (Mc *MaxClient) GetKlines(market string, interval time.Duration, limit int64, since time.Time) ([]Candle, error) {
+	return getKlines(context.Background(), Mc.ApiClient, market, interval, limit, since)
+}
+
+func getKlines(ctx context.Context, api *APIClient, market string, interval time.Duration, limit int64, since time.Time) ([]Candle, error) {
+	period, ok := klinePeriod(interval)
+	if !ok {
+		return nil, fmt.Errorf("interval %s is not supported by /api/v2/k", interval)
+	}
+	params := map[string]interface{}{"period": period}
+	if limit > 0 {
+		params["limit"] = limit
+	}
+	if !since.IsZero() {
+		params["timestamp"] = since.Unix()
+	}
+
+	rows, _, err := api.PublicApi.GetApiV2K(ctx, strings.ToLower(market), params)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	candles := make([]Candle, 0, len(rows))
+	for _, row := range rows {
+		if len(row) < 6 {
+			return nil, errors.New("fail to parse k line")
+		}
+		c := Candle{
+			Market:   strings.ToLower(market),
+			Interval: interval,
+			Start:    time.Unix(row[0].IntPart(), 0),
+			Open:     row[1],
+			High:     row[2],
+			Low:      row[3],
+			Close:    row[4],
+			Volume:   row[5],
+		}
+		c.Closed = !c.End().After(now)
+		candles = append(candles, c)
+	}
+	return candles, nil
+}
+
+// the resolutions of the kline channel, which time.ParseDuration can't read past hours.
+var klineResolutions = map[string]time.Duration{
+	"1m":  time.Minute,
+	"5m":  5 * time.Minute,
+	"15m": 15 * time.Minute,
+	"30m": 30 * time.Minute,
+	"1h":  time.Hour,
+	"2h":  2 * time.Hour,
+	"4h":  4 * time.Hour,
+	"6h":  6 * time.Hour,
+	"12h": 12 * time.Hour,
+	"1d":  24 * time.Hour,
+	"3d":  3 * 24 * time.Hour,
+	"1w":  7 * 24 * time.Hour,
+}
+
+func klinePeriod(interval time.Duration) (int64, bool) {
+	if interval%time.Minute != 0 {
+		return 0, false
+	}
+	minutes := int64(interval / time.Minute)
+	for _, p := range klinePeriods {
+		if p == minutes {
+			return p, true
+		}
+	}
+	return 0, false
+}
+
+// CandleBuilder aggregates public trades into candles of several intervals.
+type CandleBuilder struct {
+	Market    string
+	Intervals []time.Duration
+	// called with every finalized candle, and with the in-progress candle on each trade if EmitInProgress.
+	OnCandle       func(Candle)
+	EmitInProgress bool
+	// finalized candles kept per interval.
+	HistorySize int
+
+	seriesBranch struct {
+		series map[time.Duration]*candleSeries
+		sync.Mutex
+	}
+}
+
+type candleSeries struct {
+	history []Candle
+	current *Candle
+	// the end of the in-progress bar backfilled from REST, its volume already counts the trades before it.
+	seededUntil time.Time
+}
+
+func NewCandleBuilder(market string, intervals []time.Duration, onCandle func(Candle)) *CandleBuilder {
+	b := &CandleBuilder{
+		Market:      strings.ToLower(market),
+		Intervals:   intervals,
+		OnCandle:    onCandle,
+		HistorySize: 1000,
+	}
+	b.seriesBranch.series = make(map[time.Duration]*candleSeries)
+	for _, interval := range intervals {
+		b.seriesBranch.series[interval] = &candleSeries{}
+	}
+	return b
+}
+
+// Attach feeds the builder from a trade stream, and closes idle intervals until ctx is done.
+func (b *CandleBuilder) Attach(ctx context.Context, stream *TradeStreamBranch) {
+	stream.OnTrade(b.AddTrade)
+	go func() {
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				b.Flush(now)
+			}
+		}
+	}()
+}
+
+// Backfill loads history from REST for every interval /api/v2/k supports, so the series starts without gaps.
+// Call it before Attach, trades of the in-progress REST bar only move its prices, not its volume.
+func (b *CandleBuilder) Backfill(ctx context.Context, api *APIClient, limit int64) error {
+	for _, interval := range b.Intervals {
+		if _, ok := klinePeriod(interval); !ok {
+			continue
+		}
+		candles, err := getKlines(ctx, api, b.Market, interval, limit, time.Time{})
+		if err != nil {
+			return err
+		}
+		var emit []Candle
+		b.seriesBranch.Lock()
+		s := b.seriesBranch.series[interval]
+		for _, c := range candles {
+			if s.current != nil && !c.Start.Before(s.current.Start) {
+				continue
+			}
+			if !c.Closed {
+				current := c
+				s.current = &current
+				s.seededUntil = c.End()
+				continue
+			}
+			s.history = append(s.history, c)
+			emit = append(emit, c)
+		}
+		sort.Slice(s.history, func(i, j int) bool { return s.history[i].Start.Before(s.history[j].Start) })
+		b.trimHistory(s)
+		b.seriesBranch.Unlock()
+
+		b.emit(emit)
+	}
+	return nil
+}
+
+// AddTrade puts a trade into the candle of every interval.
+func (b *CandleBuilder) AddTrade(trade PublicTrade) {
+	if trade.Market != b.Market {
+		return
+	}
+
+	var emit []Candle
+	b.seriesBranch.Lock()
+	for interval, s := range b.seriesBranch.series {
+		start := trade.Time.Truncate(interval)
+		emit = append(emit, b.advance(s, interval, start)...)
+
+		if s.current == nil {
+			if n := len(s.history); n > 0 && start.Before(s.history[n-1].End()) {
+				// late trade of a finalized candle.
+				continue
+			}
+			s.current = &Candle{
+				Market:   b.Market,
+				Interval: interval,
+				Start:    start,
+				Open:     trade.Price,
+				High:     trade.Price,
+				Low:      trade.Price,
+				Close:    trade.Price,
+				Volume:   decimal.Zero,
+			}
+		}
+		if start.Before(s.current.Start) {
+			// late trade of a finalized candle.
+			continue
+		}
+		c := s.current
+		if trade.Price.GreaterThan(c.High) {
+			c.High = trade.Price
+		}
+		if trade.Price.LessThan(c.Low) {
+			c.Low = trade.Price
+		}
+		c.Close = trade.Price
+		if !trade.Time.Before(s.seededUntil) {
+			c.Volume = c.Volume.Add(trade.Volume)
+		}
+		if b.EmitInProgress {
+			emit = append(emit, *c)
+		}
+	}
+	b.seriesBranch.Unlock()
+
+	b.emit(emit)
+}
+
+// Flush finalizes candles whose interval ended before now, filling idle intervals with flat bars.
+func (b *CandleBuilder) Flush(now time.Time) {
+	var emit []Candle
+	b.seriesBranch.Lock()
+	for interval, s := range b.seriesBranch.series {
+		emit = append(emit, b.advance(s, interval, now.Truncate(interval))...)
+	}
+	b.seriesBranch.Unlock()
+	b.emit(emit)
+}
+
+// advance finalizes the current candle and the empty ones before the candle starting at $start.
+func (b *CandleBuilder) advance(s *candleSeries, interval time.Duration, start time.Time) []Candle {
+	var finalized []Candle
+	for {
+		if s.current == nil {
+			if len(s.history) == 0 {
+				break
+			}
+			last := s.history[len(s.history)-1]
+			if !last.End().Before(start) {
+				break
+			}
+			s.current = &Candle{
+				Market:   b.Market,
+				Interval: interval,
+				Start:    last.End(),
+				Open:     last.Close,
+				High:     last.Close,
+				Low:      last.Close,
+				Close:    last.Close,
+				Volume:   decimal.Zero,
+			}
+		}
+		if !s.current.Start.Before(start) {
+			break
+		}
+		done := *s.current
+		done.Closed = true
+		s.history = append(s.history, done)
+		finalized = append(finalized, done)
+		s.current = nil
+	}
+	b.trimHistory(s)
+	return finalized
+}
+
+func (b *CandleBuilder) trimHistory(s *candleSeries) {
+	if b.HistorySize > 0 && len(s.history) > b.HistorySize {
+		s.history = s.history[len(s.history)-b.HistorySize:]
+	}
+}
+
+func (b *CandleBuilder) emit(candles []Candle) {
+	if b.OnCandle == nil {
+		return
+	}
+	for _, c := range candles {
+		b.OnCandle(c)
+	}
+}
+
+// Candles returns the finalized candles of an interval, oldest first.
+func (b *CandleBuilder) Candles(interval time.Duration) []Candle {
+	b.seriesBranch.Lock()
+	defer b.seriesBranch.Unlock()
+	s, ok := b.seriesBranch.series[interval]
+	if !ok {
+		return nil
+	}
+	candles := make([]Candle, len(s.history))
+	copy(candles, s.history)
+	return candles
+}
+
+// Current returns the in-progress candle of an interval.
+func (b *CandleBuilder) Current(interval time.Duration) (Candle, bool) {
+	b.seriesBranch.Lock()
+	defer b.seriesBranch.Unlock()
+	s, ok := b.seriesBranch.series[interval]
+	if !ok || s.current == nil {
+		return Candle{}, false
+	}
+	return *s.current, true
+}
+
+// KlineStreamBranch keeps the kline channel of the MAX websocket.
+type KlineStreamBranch struct {
+	cancel   context.CancelFunc
+	engine   *wsEngine
+	Market   string
+	Interval time.Duration
+	OnCandle func(Candle)
+
+	lastBranch struct {
+		candle Candle
+		sync.RWMutex
+	}
+
+	logger *logrus.Logger
+	health *healthTracker
+}
+
+type klineMsg struct {
+	Channel string `json:"c"`
+	Event   string `json:"e"`
+	Market  string `json:"M"`
+	Kline   struct {
+		StartTime int64           `json:"ST"`
+		EndTime   int64           `json:"ET"`
+		Open      decimal.Decimal `json:"O"`
+		High      decimal.Decimal `json:"H"`
+		Low       decimal.Decimal `json:"L"`
+		Close     decimal.Decimal `json:"C"`
+		Volume    decimal.Decimal `json:"v"`
+		Closed    bool            `json:"x"`
+	} `json:"k"`
+}
+
+// SpotKlineStream subscribes the kline channel, resolution is like "1m", "1h", "1d" or "1w".
+func SpotKlineStream(ctx context.Context, symbol, resolution string, onCandle func(Candle), logger *logrus.Logger) (*KlineStreamBranch, error) {
+	interval, ok := klineResolutions[resolution]
+	if !ok {
+		return nil, fmt.Errorf("resolution %s is not supported by the kline channel", resolution)
+	}
+
+	var o KlineStreamBranch
+	o.Market = strings.ToLower(symbol)
+	o.Interval = interval
+	o.OnCandle = onCandle
+	o.logger = logger
+	o.health = newHealthTracker("kline " + o.Market + " " + resolution)
+
+	ctx, o.cancel = context.WithCancel(ctx)
+	o.engine = newWsEngine("kline "+o.Market, wsConfig{}, logger, o.health)
+	o.engine.subscribe = func() ([][]byte, error) {
+		param := map[string]interface{}{
+			"action": "sub",
+			"subscriptions": []map[string]interface{}{
+				{"channel": "kline", "market": o.Market, "resolution": resolution},
+			},
+		}
+		subMsg, err := json.Marshal(param)
+		if err != nil {
+			return nil, err
+		}
+		return [][]byte{subMsg}, nil
+	}
+	o.engine.handle = o.handleKlineSocketMsg
+
+	go o.engine.run(ctx)
+	return &o, nil
+}
+
+func (o *KlineStreamBranch) handleKlineSocketMsg(msg []byte) error {
+	var k klineMsg
+	if err := json.Unmarshal(msg, &k); err != nil {
+		return errors.New("fail to unmarshal message")
+	}
+
+	switch k.Event {
+	case "subscribed":
+		o.health.connected()
+	case "snapshot", "update":
+		if k.Channel != "kline" || k.Market != o.Market {
+			return errors.New("wrong kline message")
+		}
+		c := Candle{
+			Market:   o.Market,
+			Interval: o.Interval,
+			Start:    time.UnixMilli(k.Kline.StartTime),
+			Open:     k.Kline.Open,
+			High:     k.Kline.High,
+			Low:      k.Kline.Low,
+			Close:    k.Kline.Close,
+			Volume:   k.Kline.Volume,
+			Closed:   k.Kline.Closed,
+		}
+		o.lastBranch.Lock()
+		o.lastBranch.candle = c
+		o.lastBranch.Unlock()
+		if o.OnCandle != nil {
+			o.OnCandle(c)
+		}
+	case "error":
+		return fmt.Errorf("kline subscription error: %s", string(msg))
+	}
+	return nil
+}
+
+// Last returns the latest candle received.
+func (o *KlineStreamBranch) Last() (Candle, bool) {
+	o.lastBranch.RLock()
+	defer o.lastBranch.RUnlock()
+	return o.lastBranch.candle, !o.lastBranch.candle.Start.IsZero()
+}
+
+func (o *KlineStreamBranch) Close() {
+	o.cancel()
+}
+
+// Health of the kline websocket.
+func (o *KlineStreamBranch) Health() Health {
+	return o.health.snapshot()
+}
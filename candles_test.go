@@ -0,0 +1,120 @@
+package max_RESTfulAPI
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// test candle aggregation with an idle interval in between
+func TestCandleBuilderGaps(t *testing.T) {
+	var closed []Candle
+	b := NewCandleBuilder("btctwd", []time.Duration{time.Minute}, func(c Candle) {
+		if c.Closed {
+			closed = append(closed, c)
+		}
+	})
+
+	base := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	trade := func(offset time.Duration, price, volume string) {
+		b.AddTrade(PublicTrade{
+			Market: "btctwd",
+			Price:  decimal.RequireFromString(price),
+			Volume: decimal.RequireFromString(volume),
+			Time:   base.Add(offset),
+		})
+	}
+	trade(5*time.Second, "100", "1")
+	trade(20*time.Second, "110", "2")
+	trade(50*time.Second, "95", "1")
+	// nothing traded in the second minute
+	trade(2*time.Minute+time.Second, "105", "3")
+
+	if len(closed) != 2 {
+		t.Fatalf("got %d closed candles, want 2", len(closed))
+	}
+	first, idle := closed[0], closed[1]
+	if !first.Open.Equal(decimal.NewFromInt(100)) || !first.High.Equal(decimal.NewFromInt(110)) ||
+		!first.Low.Equal(decimal.NewFromInt(95)) || !first.Close.Equal(decimal.NewFromInt(95)) ||
+		!first.Volume.Equal(decimal.NewFromInt(4)) {
+		t.Errorf("unexpected first candle %+v", first)
+	}
+	if !idle.Start.Equal(base.Add(time.Minute)) || !idle.Open.Equal(decimal.NewFromInt(95)) || !idle.Volume.IsZero() {
+		t.Errorf("unexpected idle candle %+v", idle)
+	}
+
+	current, ok := b.Current(time.Minute)
+	if !ok || !current.Start.Equal(base.Add(2*time.Minute)) || !current.Volume.Equal(decimal.NewFromInt(3)) {
+		t.Errorf("unexpected current candle %+v", current)
+	}
+
+	b.Flush(base.Add(4 * time.Minute))
+	if got := len(b.Candles(time.Minute)); got != 4 {
+		t.Errorf("got %d candles after flush, want 4", got)
+	}
+}
+
+// test the kline resolutions past hours are read, and match the REST periods
+func TestKlineResolutions(t *testing.T) {
+	if klineResolutions["1d"] != 24*time.Hour || klineResolutions["1w"] != 7*24*time.Hour {
+		t.Fatalf("resolutions %v", klineResolutions)
+	}
+	if len(klineResolutions) != len(klinePeriods) {
+		t.Fatalf("%d resolutions, %d periods", len(klineResolutions), len(klinePeriods))
+	}
+	for resolution, interval := range klineResolutions {
+		if _, ok := klinePeriod(interval); !ok {
+			t.Errorf("%s is not a REST period", resolution)
+		}
+	}
+	if _, err := SpotKlineStream(context.Background(), "btcusdt", "2d", nil, nil); err == nil {
+		t.Fatal("unsupported resolution accepted")
+	}
+}
+
+// test the trades of the in-progress backfilled bar move its prices, and count once the stream takes over
+func TestCandleBuilderBackfill(t *testing.T) {
+	start := time.Now().Truncate(time.Hour)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `[[%d,90,100,80,95,7],[%d,95,96,94,95,2]]`, start.Add(-time.Hour).Unix(), start.Unix())
+	}))
+	defer server.Close()
+	cfg := NewConfiguration()
+	cfg.BasePath = server.URL
+
+	var closed []Candle
+	b := NewCandleBuilder("btcusdt", []time.Duration{time.Hour}, func(c Candle) {
+		closed = append(closed, c)
+	})
+	if err := b.Backfill(context.Background(), NewAPIClient(cfg), 2); err != nil {
+		t.Fatal(err)
+	}
+	if len(closed) != 1 || !closed[0].Volume.Equal(decimal.NewFromInt(7)) {
+		t.Fatalf("backfilled %+v", closed)
+	}
+
+	trade := func(at time.Time, price, volume int64) {
+		b.AddTrade(PublicTrade{Market: "btcusdt", Price: decimal.NewFromInt(price), Volume: decimal.NewFromInt(volume), Time: at})
+	}
+	// a late trade of the closed bar, and trades of the in-progress one, already counted by MAX.
+	trade(start.Add(-time.Minute), 50, 1)
+	trade(start.Add(time.Second), 98, 1)
+	trade(start.Add(59*time.Minute), 97, 1)
+	current, ok := b.Current(time.Hour)
+	if !ok || !current.Volume.Equal(decimal.NewFromInt(2)) || !current.High.Equal(decimal.NewFromInt(98)) || !current.Close.Equal(decimal.NewFromInt(97)) {
+		t.Fatalf("current %+v", current)
+	}
+
+	trade(start.Add(time.Hour), 99, 3)
+	if len(closed) != 2 || !closed[1].Start.Equal(start) || !closed[1].Volume.Equal(decimal.NewFromInt(2)) {
+		t.Fatalf("closed %+v", closed)
+	}
+	if current, _ := b.Current(time.Hour); !current.Volume.Equal(decimal.NewFromInt(3)) {
+		t.Fatalf("current %+v", current)
+	}
+}
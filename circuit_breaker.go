@@ -0,0 +1,349 @@
+package max_RESTfulAPI
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/sirupsen/logrus"
+)
+
+// ErrCircuitOpen is returned by the order placement functions while the circuit breaker is tripped.
+var ErrCircuitOpen = errors.New("circuit breaker tripped, new orders are blocked")
+
+// BreakerConfig holds the trip conditions, a zero value disables the corresponding condition.
+type BreakerConfig struct {
+	MaxRejectsPerMinute int
+
+	// max loss of the day's fills in the reporting currency of Fees, as a positive number.
+	MaxDailyLoss decimal.Decimal
+	Fees         *FeeModel
+	// day boundary of the loss counter, default to time.Local.
+	Location *time.Location
+
+	// trip if the websocket of any watched orderbook is down with no message for this long.
+	// A quiet book on a live connection is not stale, the engine drops a connection without pongs.
+	// Books which never got a snapshot are not checked.
+	StaleBookAfter time.Duration
+	Books          BookPrices
+
+	// trip if the private websocket is down for this long.
+	PrivateWsDownAfter time.Duration
+
+	// cancel resting orders when tripped.
+	CancelOnTrip bool
+
+	CheckInterval time.Duration
+}
+
+// BreakerState is the trip state visible to callers.
+type BreakerState struct {
+	Tripped   bool
+	Reason    string
+	TrippedAt time.Time
+	// net PnL of the day's fills in the reporting currency.
+	DailyPnl decimal.Decimal
+}
+
+// CircuitBreaker blocks new orders of a MaxClient once tripped, until Reset is called.
+type CircuitBreaker struct {
+	Config BreakerConfig
+	OnTrip func(BreakerState)
+
+	logger *logrus.Logger
+
+	stateBranch struct {
+		state   BreakerState
+		rejects []time.Time
+		sync.RWMutex
+	}
+
+	// per market net base and quote flows of the day's fills.
+	dayBranch struct {
+		day   string
+		base  map[string]decimal.Decimal
+		quote map[string]decimal.Decimal
+		fees  decimal.Decimal
+		sync.Mutex
+	}
+}
+
+func NewCircuitBreaker(config BreakerConfig, logger *logrus.Logger) *CircuitBreaker {
+	if config.Location == nil {
+		config.Location = time.Local
+	}
+	if config.CheckInterval <= 0 {
+		config.CheckInterval = time.Second
+	}
+	cb := &CircuitBreaker{Config: config, logger: logger}
+	cb.resetDay(cb.today())
+	return cb
+}
+
+// SetCircuitBreaker attaches the breaker to the client, nil removes it.
+func (Mc *MaxClient) SetCircuitBreaker(cb *CircuitBreaker) {
+	Mc.BreakerBranch.Lock()
+	defer Mc.BreakerBranch.Unlock()
+	Mc.BreakerBranch.Breaker = cb
+}
+
+func (Mc *MaxClient) ReadCircuitBreaker() *CircuitBreaker {
+	Mc.BreakerBranch.RLock()
+	defer Mc.BreakerBranch.RUnlock()
+	return Mc.BreakerBranch.Breaker
+}
+
+// BreakerState returns the state of the attached breaker, zero value if there is none.
+func (Mc *MaxClient) BreakerState() BreakerState {
+	cb := Mc.ReadCircuitBreaker()
+	if cb == nil {
+		return BreakerState{}
+	}
+	return cb.State()
+}
+
+// RunCircuitBreaker checks the trip conditions periodically until ctx is done.
+func (Mc *MaxClient) RunCircuitBreaker(ctx context.Context) {
+	cb := Mc.ReadCircuitBreaker()
+	if cb == nil {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(cb.Config.CheckInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if cb.IsTripped() {
+					continue
+				}
+				if reason, trip := cb.evaluate(Mc.wsDownFor()); trip {
+					Mc.tripBreaker(cb, reason)
+				}
+			}
+		}
+	}()
+}
+
+// TripBreaker trips the attached breaker manually.
+func (Mc *MaxClient) TripBreaker(reason string) {
+	if cb := Mc.ReadCircuitBreaker(); cb != nil {
+		Mc.tripBreaker(cb, reason)
+	}
+}
+
+func (Mc *MaxClient) tripBreaker(cb *CircuitBreaker, reason string) {
+	if !cb.trip(reason) {
+		return
+	}
+	if cb.Config.CancelOnTrip {
+		if _, err := Mc.CancelAllOrders(); err != nil {
+			Mc.logger.Error("circuit breaker fail to cancel orders: ", err)
+		}
+	}
+}
+
+func (cb *CircuitBreaker) State() BreakerState {
+	cb.stateBranch.RLock()
+	state := cb.stateBranch.state
+	cb.stateBranch.RUnlock()
+	state.DailyPnl, _ = cb.dailyPnl()
+	return state
+}
+
+func (cb *CircuitBreaker) IsTripped() bool {
+	cb.stateBranch.RLock()
+	defer cb.stateBranch.RUnlock()
+	return cb.stateBranch.state.Tripped
+}
+
+// Reset re-opens order placement, it is the only way out of the tripped state.
+func (cb *CircuitBreaker) Reset() {
+	cb.stateBranch.Lock()
+	cb.stateBranch.state = BreakerState{}
+	cb.stateBranch.rejects = nil
+	cb.stateBranch.Unlock()
+	if cb.logger != nil {
+		cb.logger.Warn("circuit breaker reset")
+	}
+}
+
+// trip returns false if the breaker has already tripped.
+func (cb *CircuitBreaker) trip(reason string) bool {
+	cb.stateBranch.Lock()
+	if cb.stateBranch.state.Tripped {
+		cb.stateBranch.Unlock()
+		return false
+	}
+	cb.stateBranch.state = BreakerState{Tripped: true, Reason: reason, TrippedAt: time.Now()}
+	cb.stateBranch.Unlock()
+
+	if cb.logger != nil {
+		cb.logger.Error("circuit breaker tripped: ", reason)
+	}
+	if cb.OnTrip != nil {
+		cb.OnTrip(cb.State())
+	}
+	return true
+}
+
+// recordReject counts a rejected order, returns true if it is over the limit per minute.
+func (cb *CircuitBreaker) recordReject() bool {
+	if cb.Config.MaxRejectsPerMinute <= 0 {
+		return false
+	}
+	cb.stateBranch.Lock()
+	defer cb.stateBranch.Unlock()
+
+	now := time.Now()
+	rejects := cb.stateBranch.rejects[:0]
+	for _, t := range cb.stateBranch.rejects {
+		if now.Sub(t) < time.Minute {
+			rejects = append(rejects, t)
+		}
+	}
+	cb.stateBranch.rejects = append(rejects, now)
+	return len(cb.stateBranch.rejects) >= cb.Config.MaxRejectsPerMinute
+}
+
+func (cb *CircuitBreaker) evaluate(wsDown time.Duration) (reason string, trip bool) {
+	cfg := cb.Config
+
+	if cfg.PrivateWsDownAfter > 0 && wsDown > cfg.PrivateWsDownAfter {
+		return fmt.Sprintf("private websocket down for %s", wsDown.Round(time.Second)), true
+	}
+
+	if cfg.StaleBookAfter > 0 {
+		for market, ob := range cfg.Books {
+			if age, synced := bookAge(ob); synced && age > cfg.StaleBookAfter {
+				return fmt.Sprintf("%s orderbook stale for %s", market, age.Round(time.Second)), true
+			}
+		}
+	}
+
+	if cfg.MaxDailyLoss.IsPositive() {
+		pnl, err := cb.dailyPnl()
+		if err != nil && cb.logger != nil {
+			cb.logger.Warn("circuit breaker fail to value daily pnl: ", err)
+		}
+		if err == nil && pnl.Neg().GreaterThan(cfg.MaxDailyLoss) {
+			return fmt.Sprintf("daily loss %s above %s", pnl.Neg(), cfg.MaxDailyLoss), true
+		}
+	}
+
+	return "", false
+}
+
+// bookAge is zero while the websocket of the book is connected, else the time since its last message,
+// or since the last update when the book has no websocket. synced is false until the first snapshot.
+func bookAge(ob *OrderbookBranch) (age time.Duration, synced bool) {
+	if ob == nil || ob.LastUpdated().UnixMilli() == 0 {
+		return 0, false
+	}
+	if ob.health == nil {
+		return time.Since(ob.LastUpdated()), true
+	}
+	h := ob.health.snapshot()
+	if h.State == ConnStateConnected {
+		return 0, true
+	}
+	return time.Since(h.LastMessage), true
+}
+
+func (cb *CircuitBreaker) today() string {
+	return time.Now().In(cb.Config.Location).Format("2006-01-02")
+}
+
+func (cb *CircuitBreaker) resetDay(day string) {
+	cb.dayBranch.day = day
+	cb.dayBranch.base = make(map[string]decimal.Decimal)
+	cb.dayBranch.quote = make(map[string]decimal.Decimal)
+	cb.dayBranch.fees = decimal.Zero
+}
+
+func (cb *CircuitBreaker) tradesArrived(trades []Trade) {
+	cb.dayBranch.Lock()
+	defer cb.dayBranch.Unlock()
+
+	if today := cb.today(); today != cb.dayBranch.day {
+		cb.resetDay(today)
+	}
+
+	for _, trade := range trades {
+		price, errP := decimal.NewFromString(trade.Price)
+		volume, errV := decimal.NewFromString(trade.Volume)
+		if errP != nil || errV != nil {
+			continue
+		}
+		market := strings.ToLower(trade.Market)
+		if trade.Side == "buy" || trade.Side == "bid" {
+			cb.dayBranch.base[market] = cb.dayBranch.base[market].Add(volume)
+			cb.dayBranch.quote[market] = cb.dayBranch.quote[market].Sub(price.Mul(volume))
+		} else {
+			cb.dayBranch.base[market] = cb.dayBranch.base[market].Sub(volume)
+			cb.dayBranch.quote[market] = cb.dayBranch.quote[market].Add(price.Mul(volume))
+		}
+		if cb.Config.Fees != nil {
+			if fee, err := cb.Config.Fees.TradeFee(trade); err == nil {
+				cb.dayBranch.fees = cb.dayBranch.fees.Add(fee)
+			}
+		}
+	}
+}
+
+// dailyPnl marks the net position of the day's fills to the current mid, minus fees.
+func (cb *CircuitBreaker) dailyPnl() (decimal.Decimal, error) {
+	fees := cb.Config.Fees
+	if fees == nil {
+		return decimal.Zero, nil
+	}
+
+	cb.dayBranch.Lock()
+	defer cb.dayBranch.Unlock()
+
+	if today := cb.today(); today != cb.dayBranch.day {
+		cb.resetDay(today)
+	}
+
+	pnl := cb.dayBranch.fees.Neg()
+	for market, base := range cb.dayBranch.base {
+		mid, ok := fees.Prices.MidPrice(market)
+		if !ok {
+			return decimal.Zero, fmt.Errorf("%w: %s", ErrFeeConversion, market)
+		}
+		_, quote, ok := fees.splitMarket(market)
+		if !ok {
+			return decimal.Zero, fmt.Errorf("%w: %s", ErrUnknownMarket, market)
+		}
+		value, err := fees.Normalize(cb.dayBranch.quote[market].Add(base.Mul(mid)), quote)
+		if err != nil {
+			return decimal.Zero, err
+		}
+		pnl = pnl.Add(value)
+	}
+	return pnl, nil
+}
+
+func (Mc *MaxClient) checkBreaker() error {
+	cb := Mc.ReadCircuitBreaker()
+	if cb != nil && cb.IsTripped() {
+		return ErrCircuitOpen
+	}
+	return nil
+}
+
+func (Mc *MaxClient) breakerOrderRejected() {
+	cb := Mc.ReadCircuitBreaker()
+	if cb == nil {
+		return
+	}
+	if cb.recordReject() {
+		Mc.tripBreaker(cb, fmt.Sprintf("%d rejected orders within a minute", cb.Config.MaxRejectsPerMinute))
+	}
+}
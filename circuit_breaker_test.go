@@ -0,0 +1,131 @@
+package max_RESTfulAPI
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/sirupsen/logrus"
+)
+
+func newTestBreaker(cfg BreakerConfig) (*MaxClient, *CircuitBreaker) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	Mc := &MaxClient{logger: logger}
+	cb := NewCircuitBreaker(cfg, logger)
+	Mc.SetCircuitBreaker(cb)
+	return Mc, cb
+}
+
+func TestBreakerRejects(t *testing.T) {
+	Mc, cb := newTestBreaker(BreakerConfig{MaxRejectsPerMinute: 3})
+	trips := 0
+	cb.OnTrip = func(BreakerState) { trips++ }
+
+	Mc.breakerOrderRejected()
+	Mc.breakerOrderRejected()
+	if err := Mc.checkBreaker(); err != nil {
+		t.Fatal(err)
+	}
+	// rejects older than a minute don't count.
+	cb.stateBranch.Lock()
+	cb.stateBranch.rejects[0] = time.Now().Add(-2 * time.Minute)
+	cb.stateBranch.Unlock()
+	Mc.breakerOrderRejected()
+	if cb.IsTripped() {
+		t.Fatal("tripped on an expired reject")
+	}
+	Mc.breakerOrderRejected()
+	Mc.breakerOrderRejected()
+	if err := Mc.checkBreaker(); !errors.Is(err, ErrCircuitOpen) || trips != 1 {
+		t.Fatalf("err %v, trips %d", err, trips)
+	}
+	if state := cb.State(); !strings.Contains(state.Reason, "rejected") || state.TrippedAt.IsZero() {
+		t.Errorf("state %+v", state)
+	}
+}
+
+func TestBreakerDailyPnl(t *testing.T) {
+	prices := fixedPrices{"btcusdt": decimal.NewFromInt(110)}
+	_, cb := newTestBreaker(BreakerConfig{MaxDailyLoss: decimal.NewFromInt(5), Fees: NewFeeModel("usdt", prices)})
+
+	cb.tradesArrived([]Trade{{Market: "btcusdt", Side: "buy", Price: "100", Volume: "1", Fee: "0.001", FeeCurrency: "btc"}})
+	// 110 - 100 marked to the mid, minus 0.001 btc of fee.
+	if pnl := cb.State().DailyPnl; !pnl.Equal(decimal.RequireFromString("9.89")) {
+		t.Fatalf("pnl %s", pnl)
+	}
+	if _, trip := cb.evaluate(0); trip {
+		t.Fatal("tripped on a profit")
+	}
+
+	cb.tradesArrived([]Trade{{Market: "btcusdt", Side: "sell", Price: "94", Volume: "1", Fee: "0.094", FeeCurrency: "usdt"}})
+	if pnl := cb.State().DailyPnl; !pnl.Equal(decimal.RequireFromString("-6.204")) {
+		t.Fatalf("pnl %s", pnl)
+	}
+	if reason, trip := cb.evaluate(0); !trip || !strings.Contains(reason, "daily loss") {
+		t.Fatalf("reason %q", reason)
+	}
+
+	// the counter starts over the next day.
+	cb.dayBranch.Lock()
+	cb.dayBranch.day = "2000-01-01"
+	cb.dayBranch.Unlock()
+	if pnl := cb.State().DailyPnl; !pnl.IsZero() {
+		t.Errorf("pnl of a new day %s", pnl)
+	}
+}
+
+func TestBreakerReset(t *testing.T) {
+	Mc, cb := newTestBreaker(BreakerConfig{MaxRejectsPerMinute: 2})
+	Mc.TripBreaker("manual")
+	Mc.breakerOrderRejected()
+	if state := cb.State(); !state.Tripped || state.Reason != "manual" {
+		t.Fatalf("state %+v", state)
+	}
+
+	cb.Reset()
+	if err := Mc.checkBreaker(); err != nil || cb.State().Reason != "" {
+		t.Fatalf("err %v, state %+v", err, cb.State())
+	}
+	// the rejects before the reset are forgotten.
+	Mc.breakerOrderRejected()
+	if cb.IsTripped() {
+		t.Fatal("tripped on rejects before the reset")
+	}
+	Mc.breakerOrderRejected()
+	if !cb.IsTripped() {
+		t.Fatal("not tripped after the reset")
+	}
+}
+
+func TestBreakerStaleBooks(t *testing.T) {
+	ob := &OrderbookBranch{health: newHealthTracker("orderbook btcusdt")}
+	_, cb := newTestBreaker(BreakerConfig{StaleBookAfter: time.Minute, Books: BookPrices{"btcusdt": ob}})
+
+	// never synced.
+	if reason, trip := cb.evaluate(0); trip {
+		t.Fatalf("tripped on an unsynced book: %s", reason)
+	}
+
+	// a quiet book on a live connection.
+	ob.lastUpdatedTimestampBranch.timestamp = time.Now().Add(-time.Hour).UnixMilli()
+	ob.health.connected()
+	ob.health.message()
+	ob.health.lastMessage = time.Now().Add(-time.Hour)
+	if reason, trip := cb.evaluate(0); trip {
+		t.Fatalf("tripped on a quiet book: %s", reason)
+	}
+
+	// the connection dropped, stale since its last message.
+	ob.health.fail(errors.New("read timeout"))
+	if reason, trip := cb.evaluate(0); !trip || !strings.Contains(reason, "btcusdt orderbook stale") {
+		t.Fatalf("reason %q", reason)
+	}
+	ob.health.lastMessage = time.Now()
+	if reason, trip := cb.evaluate(0); trip {
+		t.Fatalf("tripped right after the drop: %s", reason)
+	}
+}
@@ -0,0 +1,871 @@
+package max_RESTfulAPI
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/sirupsen/logrus"
+)
+
+// ConditionalExchange is what the conditional orders trade on, MaxClient, PaperClient and MemoryExchange implement it.
+type ConditionalExchange interface {
+	AlgoExchange
+	OpenOrderRows() ([]OpenOrderRow, error)
+	// fails with ErrOrderNotFound when the exchange has no such order.
+	GetOrder(market string, id, clientId interface{}) (WsOrder, error)
+}
+
+var (
+	_ ConditionalExchange = (*MaxClient)(nil)
+	_ ConditionalExchange = (*PaperClient)(nil)
+	_ ConditionalExchange = (*MemoryExchange)(nil)
+)
+
+type ConditionalKind string
+
+const (
+	ConditionalTrailingStop ConditionalKind = "trailing_stop"
+	// a take profit limit and a stop, the first to trigger or fill cancels the other.
+	ConditionalOCO ConditionalKind = "oco"
+	// an entry, then a take profit and a stop loss as an OCO once the entry is filled.
+	ConditionalBracket ConditionalKind = "bracket"
+)
+
+type LegState string
+
+const (
+	// waits for the leg it comes after to fill.
+	LegIdle LegState = "idle"
+	// watches its trigger, or is about to be placed if it has none.
+	LegArmed    LegState = "armed"
+	LegPlacing  LegState = "placing"
+	LegPlaced   LegState = "placed"
+	LegFilled   LegState = "filled"
+	LegCanceled LegState = "canceled"
+)
+
+// a leg failing to be placed this many times in a row is canceled.
+const maxLegFailures = 3
+
+type ConditionalLeg struct {
+	Name   string          `json:"name"`
+	Side   string          `json:"side"`
+	Volume decimal.Decimal `json:"volume"`
+	// limit price of the order, zero for a market order.
+	Price decimal.Decimal `json:"price"`
+	// sells trigger when the price falls to it, buys when it rises to it. Zero places the order once armed.
+	Trigger decimal.Decimal `json:"trigger"`
+	// trailing stops keep Trigger behind the best price by TrailAmount, or by TrailPercent of it, e.g. 0.02.
+	TrailAmount  decimal.Decimal `json:"trail_amount"`
+	TrailPercent decimal.Decimal `json:"trail_percent"`
+	// best price seen while armed.
+	Extreme decimal.Decimal `json:"extreme"`
+	// index of the leg arming this one once filled, -1 for none.
+	After int `json:"after"`
+	// the other active legs of the group are canceled once this one triggers or fills.
+	Group string `json:"group"`
+
+	State     LegState        `json:"state"`
+	ClientOid string          `json:"client_oid"`
+	OrderId   int64           `json:"order_id"`
+	Filled    decimal.Decimal `json:"filled"`
+	// filled volume net of the fees charged in base, what the legs after it trade.
+	Received decimal.Decimal `json:"received"`
+	// trades counted in Filled.
+	TradeIds []int64 `json:"trade_ids"`
+	Failures int     `json:"failures"`
+}
+
+func (l *ConditionalLeg) active() bool {
+	return l.State != LegFilled && l.State != LegCanceled
+}
+
+func (l *ConditionalLeg) conditional() bool {
+	return !l.Trigger.IsZero() || l.trailing()
+}
+
+func (l *ConditionalLeg) trailing() bool {
+	return l.TrailAmount.IsPositive() || l.TrailPercent.IsPositive()
+}
+
+// track moves the trailing trigger behind the best price, true if it moved.
+func (l *ConditionalLeg) track(price decimal.Decimal) bool {
+	if !l.trailing() {
+		return false
+	}
+	if !l.Extreme.IsZero() && ((l.Side == "sell" && !price.GreaterThan(l.Extreme)) || (l.Side == "buy" && !price.LessThan(l.Extreme))) {
+		return false
+	}
+	l.Extreme = price
+	offset := l.TrailAmount
+	if l.TrailPercent.IsPositive() {
+		offset = price.Mul(l.TrailPercent)
+	}
+	if l.Side == "sell" {
+		l.Trigger = price.Sub(offset)
+	} else {
+		l.Trigger = price.Add(offset)
+	}
+	return true
+}
+
+func (l *ConditionalLeg) triggered(price decimal.Decimal) bool {
+	if l.Side == "sell" {
+		return price.LessThanOrEqual(l.Trigger)
+	}
+	return price.GreaterThanOrEqual(l.Trigger)
+}
+
+type ConditionalOrder struct {
+	Id        string            `json:"id"`
+	Kind      ConditionalKind   `json:"kind"`
+	Market    string            `json:"market"`
+	Legs      []*ConditionalLeg `json:"legs"`
+	CreatedAt time.Time         `json:"created_at"`
+}
+
+// Active is true while a leg may still trade.
+func (o *ConditionalOrder) Active() bool {
+	for _, leg := range o.Legs {
+		if leg.active() {
+			return true
+		}
+	}
+	return false
+}
+
+func (o *ConditionalOrder) clone() ConditionalOrder {
+	c := *o
+	c.Legs = make([]*ConditionalLeg, len(o.Legs))
+	for i, leg := range o.Legs {
+		l := *leg
+		l.TradeIds = append([]int64(nil), leg.TradeIds...)
+		c.Legs[i] = &l
+	}
+	return c
+}
+
+// ConditionalEngine holds trailing stops, OCO and bracket orders client side, triggers them from the
+// watched orderbooks and trade streams, and places and cancels their orders on the exchange.
+// The active orders are kept in a JSON file and loaded back on start.
+type ConditionalEngine struct {
+	// file the active orders are kept in, empty to keep them in memory only.
+	Path string
+	// how often the watched books are checked, default to 200 milliseconds.
+	Interval time.Duration
+
+	exchange ConditionalExchange
+	logger   *logrus.Logger
+
+	// the trade report, trade stream and snapshot listeners, dropped once Run stops.
+	subscriptionsBranch struct {
+		unsubscribes []func()
+		stopped      bool
+		sync.Mutex
+	}
+
+	booksBranch struct {
+		books []*OrderbookBranch
+		sync.RWMutex
+	}
+
+	ordersBranch struct {
+		orders map[string]*ConditionalOrder
+		// trailing triggers moved since the last save.
+		dirty bool
+		// while placing, fills of unknown orders are kept until the order id is known.
+		inflight int
+		orphans  []Trade
+		sync.Mutex
+	}
+}
+
+// follow-ups of a state change, run without the lock.
+type conditionalActions struct {
+	cancels []conditionalCancel
+	places  []legRef
+}
+
+type conditionalCancel struct {
+	market string
+	id     int64
+}
+
+type legRef struct {
+	order *ConditionalOrder
+	leg   int
+}
+
+// NewConditionalEngine loads the orders kept in path, and reconciles them with the trade snapshot
+// the private websocket sends on every (re)connection.
+func (Mc *MaxClient) NewConditionalEngine(path string) (*ConditionalEngine, error) {
+	e, err := NewConditionalEngine(Mc, path, Mc.logger)
+	if err != nil {
+		return nil, err
+	}
+	e.subscribed(Mc.OnTradeSnapshot(func(trades []Trade) {
+		if err := e.Reconcile(trades); err != nil {
+			Mc.logger.Warn("fail to reconcile conditional orders: ", err)
+		}
+	}))
+	return e, nil
+}
+
+// NewConditionalEngine loads the orders kept in path. Call Reconcile once connected,
+// with the recent trade reports, to catch up on what happened while stopped.
+func NewConditionalEngine(exchange ConditionalExchange, path string, logger *logrus.Logger) (*ConditionalEngine, error) {
+	e := &ConditionalEngine{Path: path, Interval: 200 * time.Millisecond, exchange: exchange, logger: logger}
+	e.ordersBranch.orders = make(map[string]*ConditionalOrder)
+	if path != "" {
+		raw, err := os.ReadFile(path)
+		switch {
+		case err == nil:
+			var orders []*ConditionalOrder
+			if err := json.Unmarshal(raw, &orders); err != nil {
+				return nil, fmt.Errorf("fail to load conditional orders: %w", err)
+			}
+			for _, o := range orders {
+				e.ordersBranch.orders[o.Id] = o
+			}
+		case !os.IsNotExist(err):
+			return nil, fmt.Errorf("fail to load conditional orders: %w", err)
+		}
+	}
+	e.subscribed(exchange.OnTradeReport(e.tradeReportArrived))
+	return e, nil
+}
+
+// subscribed keeps the unsubscribe func of a listener for stop, or calls it right away once stopped.
+func (e *ConditionalEngine) subscribed(unsubscribe func()) {
+	e.subscriptionsBranch.Lock()
+	defer e.subscriptionsBranch.Unlock()
+	if e.subscriptionsBranch.stopped {
+		unsubscribe()
+		return
+	}
+	e.subscriptionsBranch.unsubscribes = append(e.subscriptionsBranch.unsubscribes, unsubscribe)
+}
+
+// stop drops every listener, the engine doesn't trigger nor reconcile anymore.
+func (e *ConditionalEngine) stop() {
+	e.subscriptionsBranch.Lock()
+	defer e.subscriptionsBranch.Unlock()
+	e.subscriptionsBranch.stopped = true
+	for _, unsubscribe := range e.subscriptionsBranch.unsubscribes {
+		unsubscribe()
+	}
+	e.subscriptionsBranch.unsubscribes = nil
+}
+
+func (e *ConditionalEngine) stopped() bool {
+	e.subscriptionsBranch.Lock()
+	defer e.subscriptionsBranch.Unlock()
+	return e.subscriptionsBranch.stopped
+}
+
+// WatchTrades triggers the orders of the streamed markets at the last trade price.
+func (e *ConditionalEngine) WatchTrades(trades *TradeStreamBranch) {
+	e.subscribed(trades.OnTrade(func(trade PublicTrade) {
+		e.priceArrived(trade.Market, trade.Price, trade.Price)
+	}))
+}
+
+// WatchBook triggers the sells of the book market at the best bid and the buys at the best ask.
+func (e *ConditionalEngine) WatchBook(book *OrderbookBranch) {
+	e.booksBranch.Lock()
+	defer e.booksBranch.Unlock()
+	e.booksBranch.books = append(e.booksBranch.books, book)
+}
+
+// Run checks the watched books and retries the failed placements until ctx is done,
+// then drops the listeners, the stopped engine doesn't trigger nor reconcile anymore.
+// The orders stay active, they are loaded back by the next engine on the same file,
+// which picks up the fills received meanwhile with Reconcile.
+func (e *ConditionalEngine) Run(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(e.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				e.stop()
+				e.flush()
+				return
+			case <-ticker.C:
+				e.checkBooks()
+				e.run(conditionalActions{places: e.pendingPlacements()})
+				e.flush()
+			}
+		}
+	}()
+}
+
+func (e *ConditionalEngine) checkBooks() {
+	e.booksBranch.RLock()
+	books := e.booksBranch.books
+	e.booksBranch.RUnlock()
+	for _, book := range books {
+		var bid, ask decimal.Decimal
+		if bids, ok := book.GetBids(); ok {
+			bid = bids[0][0]
+		}
+		if asks, ok := book.GetAsks(); ok {
+			ask = asks[0][0]
+		}
+		e.priceArrived(book.Market, bid, ask)
+	}
+}
+
+// PlaceTrailingStop sells (or buys) volume at market once the price falls (or rises) from its best
+// by trailAmount, or by trailPercent of it when set.
+func (e *ConditionalEngine) PlaceTrailingStop(market, side string, volume, trailAmount, trailPercent decimal.Decimal) (ConditionalOrder, error) {
+	if !trailAmount.IsPositive() && !trailPercent.IsPositive() {
+		return ConditionalOrder{}, errors.New("trailing stop needs a trail amount or percent")
+	}
+	return e.submit(ConditionalTrailingStop, market, []*ConditionalLeg{
+		{Name: "stop", Side: side, Volume: volume, TrailAmount: trailAmount, TrailPercent: trailPercent, After: -1},
+	})
+}
+
+// PlaceOCO rests a limit order at limitPrice, and places a stop order at stopLimitPrice, or at market if zero,
+// once the price reaches stopPrice. Whichever fills or triggers first cancels the other.
+func (e *ConditionalEngine) PlaceOCO(market, side string, volume, limitPrice, stopPrice, stopLimitPrice decimal.Decimal) (ConditionalOrder, error) {
+	side = strings.ToLower(side)
+	if (side == "sell" && !limitPrice.GreaterThan(stopPrice)) || (side == "buy" && !limitPrice.LessThan(stopPrice)) {
+		return ConditionalOrder{}, fmt.Errorf("oco %s limit %s and stop %s are on the same side", side, limitPrice, stopPrice)
+	}
+	return e.submit(ConditionalOCO, market, []*ConditionalLeg{
+		{Name: "tp", Side: side, Volume: volume, Price: limitPrice, After: -1, Group: "oco"},
+		{Name: "sl", Side: side, Volume: volume, Price: stopLimitPrice, Trigger: stopPrice, After: -1, Group: "oco"},
+	})
+}
+
+// PlaceBracket enters with a limit order at entryPrice, or at market if zero. Once it is filled, what was received
+// is closed by a take profit limit and a market stop loss as an OCO.
+func (e *ConditionalEngine) PlaceBracket(market, side string, volume, entryPrice, takeProfit, stopLoss decimal.Decimal) (ConditionalOrder, error) {
+	side = strings.ToLower(side)
+	exit := "sell"
+	if side == "sell" {
+		exit = "buy"
+	}
+	if (side == "buy" && !takeProfit.GreaterThan(stopLoss)) || (side == "sell" && !takeProfit.LessThan(stopLoss)) {
+		return ConditionalOrder{}, fmt.Errorf("bracket %s take profit %s and stop loss %s are on the same side", side, takeProfit, stopLoss)
+	}
+	return e.submit(ConditionalBracket, market, []*ConditionalLeg{
+		{Name: "entry", Side: side, Volume: volume, Price: entryPrice, After: -1},
+		{Name: "tp", Side: exit, Price: takeProfit, After: 0, Group: "exit"},
+		{Name: "sl", Side: exit, Trigger: stopLoss, After: 0, Group: "exit"},
+	})
+}
+
+func (e *ConditionalEngine) submit(kind ConditionalKind, market string, legs []*ConditionalLeg) (ConditionalOrder, error) {
+	market = NormalizeMarket(market)
+	pricePrec, volPrec, _, ok := e.marketInfo(market)
+	if !ok {
+		return ConditionalOrder{}, fmt.Errorf("%w: %s", ErrUnknownMarket, market)
+	}
+	o := &ConditionalOrder{
+		Id:        "cond-" + strconv.FormatInt(time.Now().UnixNano(), 36),
+		Kind:      kind,
+		Market:    market,
+		Legs:      legs,
+		CreatedAt: time.Now(),
+	}
+	for _, leg := range legs {
+		leg.Side = strings.ToLower(leg.Side)
+		if leg.Side != "buy" && leg.Side != "sell" {
+			return ConditionalOrder{}, fmt.Errorf("unknown side %q", leg.Side)
+		}
+		leg.Volume = leg.Volume.RoundFloor(volPrec)
+		leg.Price = leg.Price.Round(pricePrec)
+		leg.State = LegIdle
+		if leg.After < 0 {
+			if !leg.Volume.IsPositive() {
+				return ConditionalOrder{}, errors.New("volume should be positive")
+			}
+			leg.State = LegArmed
+		}
+	}
+
+	e.ordersBranch.Lock()
+	e.ordersBranch.orders[o.Id] = o
+	e.save()
+	snapshot := o.clone()
+	e.ordersBranch.Unlock()
+	e.logger.Infof("conditional %s %s on %s submitted", o.Id, kind, market)
+
+	e.run(conditionalActions{places: e.pendingPlacements()})
+	return snapshot, nil
+}
+
+func (e *ConditionalEngine) marketInfo(market string) (pricePrec, volPrec int32, base string, ok bool) {
+	for _, m := range e.exchange.ReadMarkets() {
+		if m.Id == market {
+			return int32(m.QuoteUnitPrecision), int32(m.BaseUnitPrecision), NormalizeCurrency(m.BaseUnit), true
+		}
+	}
+	return 8, 8, "", false
+}
+
+// Cancel cancels the active legs of the order, what was filled stays filled.
+func (e *ConditionalEngine) Cancel(id string) error {
+	e.ordersBranch.Lock()
+	o, ok := e.ordersBranch.orders[id]
+	if !ok {
+		e.ordersBranch.Unlock()
+		return fmt.Errorf("no conditional order %s", id)
+	}
+	var actions conditionalActions
+	for _, leg := range o.Legs {
+		if !leg.active() {
+			continue
+		}
+		if leg.State == LegPlaced {
+			actions.cancels = append(actions.cancels, conditionalCancel{o.Market, leg.OrderId})
+		}
+		leg.State = LegCanceled
+	}
+	e.save()
+	e.ordersBranch.Unlock()
+	e.logger.Infof("conditional %s canceled", id)
+	e.run(actions)
+	return nil
+}
+
+// Orders returns a copy of every order of the engine, active or not, oldest first.
+func (e *ConditionalEngine) Orders() []ConditionalOrder {
+	e.ordersBranch.Lock()
+	defer e.ordersBranch.Unlock()
+	orders := make([]ConditionalOrder, 0, len(e.ordersBranch.orders))
+	for _, o := range e.ordersBranch.orders {
+		orders = append(orders, o.clone())
+	}
+	sort.Slice(orders, func(i, j int) bool { return orders[i].CreatedAt.Before(orders[j].CreatedAt) })
+	return orders
+}
+
+func (e *ConditionalEngine) Order(id string) (ConditionalOrder, bool) {
+	e.ordersBranch.Lock()
+	defer e.ordersBranch.Unlock()
+	o, ok := e.ordersBranch.orders[id]
+	if !ok {
+		return ConditionalOrder{}, false
+	}
+	return o.clone(), true
+}
+
+// priceArrived moves the trailing triggers and fires the triggered legs, sells at sellPrice and buys at buyPrice.
+// A zero price leaves the legs of its side as they are.
+func (e *ConditionalEngine) priceArrived(market string, sellPrice, buyPrice decimal.Decimal) {
+	if e.stopped() {
+		return
+	}
+	market = NormalizeMarket(market)
+	var fired []legRef
+	e.ordersBranch.Lock()
+	for _, o := range e.ordersBranch.orders {
+		if o.Market != market {
+			continue
+		}
+		for i, leg := range o.Legs {
+			if leg.State != LegArmed || !leg.conditional() {
+				continue
+			}
+			price := sellPrice
+			if leg.Side == "buy" {
+				price = buyPrice
+			}
+			if price.IsZero() {
+				continue
+			}
+			if leg.track(price) {
+				e.ordersBranch.dirty = true
+			}
+			if leg.triggered(price) {
+				leg.State = LegPlacing
+				fired = append(fired, legRef{o, i})
+				e.logger.Infof("conditional %s %s triggered at %s, trigger %s", o.Id, leg.Name, price, leg.Trigger)
+			}
+		}
+	}
+	if len(fired) != 0 {
+		e.save()
+	}
+	e.ordersBranch.Unlock()
+
+	for _, ref := range fired {
+		e.fire(ref)
+	}
+}
+
+// fire cancels the group of a triggered leg, then places it for what its group left unfilled.
+func (e *ConditionalEngine) fire(ref legRef) {
+	e.ordersBranch.Lock()
+	var actions conditionalActions
+	leg := ref.order.Legs[ref.leg]
+	// a sibling filled meanwhile.
+	if leg.State != LegPlacing {
+		e.ordersBranch.Unlock()
+		return
+	}
+	filled := decimal.Zero
+	for j, other := range ref.order.Legs {
+		if j == ref.leg || leg.Group == "" || other.Group != leg.Group {
+			continue
+		}
+		filled = filled.Add(other.Filled)
+		e.cancelLeg(ref.order, j, &actions)
+	}
+	leg.Volume = leg.Volume.Sub(filled)
+	if !leg.Volume.IsPositive() {
+		leg.State = LegCanceled
+	} else {
+		actions.places = append(actions.places, ref)
+	}
+	e.save()
+	e.ordersBranch.Unlock()
+
+	// the cancels go first, a sibling filling meanwhile is reported and cuts the leg no more.
+	e.run(conditionalActions{cancels: actions.cancels})
+	e.run(conditionalActions{places: actions.places})
+}
+
+// cancelLeg cancels an active leg and the legs waiting for it, with ordersBranch locked.
+func (e *ConditionalEngine) cancelLeg(o *ConditionalOrder, i int, actions *conditionalActions) {
+	leg := o.Legs[i]
+	if !leg.active() {
+		return
+	}
+	if leg.State == LegPlaced {
+		actions.cancels = append(actions.cancels, conditionalCancel{o.Market, leg.OrderId})
+	}
+	leg.State = LegCanceled
+	for j, next := range o.Legs {
+		if next.After == i {
+			e.cancelLeg(o, j, actions)
+		}
+	}
+}
+
+// pendingPlacements takes the armed legs without a trigger, to be placed.
+func (e *ConditionalEngine) pendingPlacements() []legRef {
+	e.ordersBranch.Lock()
+	defer e.ordersBranch.Unlock()
+	var refs []legRef
+	for _, o := range e.ordersBranch.orders {
+		for i, leg := range o.Legs {
+			if leg.State == LegArmed && !leg.conditional() {
+				leg.State = LegPlacing
+				refs = append(refs, legRef{o, i})
+			}
+		}
+	}
+	return refs
+}
+
+func (e *ConditionalEngine) run(actions conditionalActions) {
+	for _, c := range actions.cancels {
+		// fails when the order was filled meanwhile, its fills still count.
+		if _, err := e.exchange.CancelOrder(c.market, c.id, nil); err != nil {
+			e.logger.Debugf("fail to cancel conditional order %d: %v", c.id, err)
+		}
+	}
+	for _, ref := range actions.places {
+		e.place(ref)
+	}
+}
+
+func (e *ConditionalEngine) place(ref legRef) {
+	o := ref.order
+	e.ordersBranch.Lock()
+	leg := o.Legs[ref.leg]
+	if leg.State != LegPlacing {
+		e.ordersBranch.Unlock()
+		return
+	}
+	leg.ClientOid = o.Id + "-" + leg.Name
+	side, ordType, price, volume := leg.Side, "limit", leg.Price, leg.Volume
+	if price.IsZero() {
+		ordType = "market"
+	}
+	e.ordersBranch.inflight++
+	e.save()
+	e.ordersBranch.Unlock()
+
+	order, err := e.exchange.PlaceTaggedOrder(o.Market, side, ordType, floatOf(price), floatOf(volume), leg.ClientOid)
+
+	e.ordersBranch.Lock()
+	e.ordersBranch.inflight--
+	orphans := e.ordersBranch.orphans
+	if e.ordersBranch.inflight == 0 {
+		e.ordersBranch.orphans = nil
+	}
+	var actions conditionalActions
+	switch {
+	case err != nil:
+		leg.Failures++
+		if leg.Failures >= maxLegFailures {
+			e.cancelLeg(o, ref.leg, &actions)
+		} else if leg.State == LegPlacing {
+			leg.State = LegArmed
+		}
+		e.logger.Warnf("conditional %s fail to place %s: %v", o.Id, leg.Name, err)
+	case leg.State == LegCanceled:
+		// canceled while placing.
+		actions.cancels = append(actions.cancels, conditionalCancel{o.Market, order.Id})
+		leg.OrderId = order.Id
+	default:
+		leg.Failures = 0
+		leg.OrderId = order.Id
+		leg.State = LegPlaced
+		e.logger.Infof("conditional %s placed %s %s %s %s@%s", o.Id, leg.Name, side, ordType, volume, price)
+		for _, trade := range orphans {
+			if trade.Oid == order.Id {
+				e.filled(o, ref.leg, trade, &actions)
+			}
+		}
+	}
+	e.save()
+	e.ordersBranch.Unlock()
+	e.run(actions)
+}
+
+func (e *ConditionalEngine) tradeReportArrived(trade Trade) {
+	e.ordersBranch.Lock()
+	o, i, ok := e.legOf(trade.Oid)
+	if !ok {
+		if e.ordersBranch.inflight > 0 {
+			e.ordersBranch.orphans = append(e.ordersBranch.orphans, trade)
+		}
+		e.ordersBranch.Unlock()
+		return
+	}
+	var actions conditionalActions
+	e.filled(o, i, trade, &actions)
+	e.save()
+	e.ordersBranch.Unlock()
+	e.run(actions)
+}
+
+func (e *ConditionalEngine) legOf(oid int64) (*ConditionalOrder, int, bool) {
+	if oid == 0 {
+		return nil, 0, false
+	}
+	for _, o := range e.ordersBranch.orders {
+		for i, leg := range o.Legs {
+			if leg.OrderId == oid {
+				return o, i, true
+			}
+		}
+	}
+	return nil, 0, false
+}
+
+// filled books a trade of a leg once, with ordersBranch locked.
+func (e *ConditionalEngine) filled(o *ConditionalOrder, i int, trade Trade, actions *conditionalActions) {
+	leg := o.Legs[i]
+	for _, id := range leg.TradeIds {
+		if id == trade.Id {
+			return
+		}
+	}
+	leg.TradeIds = append(leg.TradeIds, trade.Id)
+	volume := decimalOrZero(trade.Volume)
+	leg.Filled = leg.Filled.Add(volume)
+	leg.Received = leg.Received.Add(volume)
+	if _, _, base, _ := e.marketInfo(o.Market); NormalizeCurrency(trade.FeeCurrency) == base {
+		leg.Received = leg.Received.Sub(decimalOrZero(trade.Fee))
+	}
+	if leg.active() && leg.Filled.GreaterThanOrEqual(leg.Volume) {
+		e.legFilled(o, i, actions)
+	}
+}
+
+// legFilled cancels the group of a filled leg and arms the legs after it, with ordersBranch locked.
+func (e *ConditionalEngine) legFilled(o *ConditionalOrder, i int, actions *conditionalActions) {
+	leg := o.Legs[i]
+	leg.State = LegFilled
+	e.logger.Infof("conditional %s %s filled %s", o.Id, leg.Name, leg.Filled)
+	for j, other := range o.Legs {
+		if j != i && leg.Group != "" && other.Group == leg.Group {
+			e.cancelLeg(o, j, actions)
+		}
+	}
+	_, volPrec, _, _ := e.marketInfo(o.Market)
+	for j, next := range o.Legs {
+		if next.After != i || next.State != LegIdle {
+			continue
+		}
+		next.Volume = leg.Received.RoundFloor(volPrec)
+		next.State = LegArmed
+		if !next.conditional() {
+			next.State = LegPlacing
+			actions.places = append(actions.places, legRef{o, j})
+		}
+	}
+}
+
+// Reconcile catches up with what happened while the engine was stopped or disconnected:
+// it books the trades of the legs among trades, then looks up on the exchange the orders of the placed legs
+// which are no longer open, and of the legs left placing by their client oid.
+// An order closed with executed volume fills its leg, which cancels its group, one closed without any cancels it.
+// A leg left placing is armed again only if the exchange has no order of its client oid.
+func (e *ConditionalEngine) Reconcile(trades []Trade) error {
+	if e.stopped() {
+		return nil
+	}
+	var actions conditionalActions
+	e.ordersBranch.Lock()
+	for _, trade := range trades {
+		if o, i, ok := e.legOf(trade.Oid); ok {
+			e.filled(o, i, trade, &actions)
+		}
+	}
+	e.ordersBranch.Unlock()
+
+	rows, err := e.exchange.OpenOrderRows()
+	if err != nil {
+		e.run(actions)
+		return fmt.Errorf("fail to get open orders: %w", err)
+	}
+	open := make(map[int64]bool, len(rows))
+	for _, row := range rows {
+		if id, err := strconv.ParseInt(row.Oid, 10, 64); err == nil {
+			open[id] = true
+		}
+	}
+
+	// looked up without the lock, the legs are checked again once it is back.
+	type lookup struct {
+		ref       legRef
+		id        int64
+		clientOid string
+	}
+	var lookups []lookup
+	e.ordersBranch.Lock()
+	for _, o := range e.ordersBranch.orders {
+		for i, leg := range o.Legs {
+			switch {
+			case leg.State == LegPlaced && !open[leg.OrderId]:
+				lookups = append(lookups, lookup{ref: legRef{o, i}, id: leg.OrderId})
+			case leg.State == LegPlacing && e.ordersBranch.inflight == 0:
+				if leg.ClientOid == "" {
+					leg.ClientOid = o.Id + "-" + leg.Name
+				}
+				lookups = append(lookups, lookup{ref: legRef{o, i}, clientOid: leg.ClientOid})
+			}
+		}
+	}
+	e.ordersBranch.Unlock()
+
+	var lookupErr error
+	for _, l := range lookups {
+		var order WsOrder
+		if l.id != 0 {
+			order, err = e.exchange.GetOrder(l.ref.order.Market, l.id, nil)
+		} else {
+			order, err = e.exchange.GetOrder(l.ref.order.Market, nil, l.clientOid)
+		}
+		if err != nil && !errors.Is(err, ErrOrderNotFound) {
+			// left as it is until the next reconcile.
+			lookupErr = err
+			continue
+		}
+		e.ordersBranch.Lock()
+		e.reconcileLeg(l.ref, l.id, order, err, &actions)
+		e.ordersBranch.Unlock()
+	}
+
+	e.ordersBranch.Lock()
+	e.save()
+	e.ordersBranch.Unlock()
+
+	e.run(actions)
+	e.run(conditionalActions{places: e.pendingPlacements()})
+	if lookupErr != nil {
+		return fmt.Errorf("fail to look up conditional orders: %w", lookupErr)
+	}
+	return nil
+}
+
+// reconcileLeg settles a leg with the state of its order on the exchange, with ordersBranch locked.
+// id is the order id of a placed leg, zero for a leg left placing, which was looked up by client oid.
+func (e *ConditionalEngine) reconcileLeg(ref legRef, id int64, order WsOrder, err error, actions *conditionalActions) {
+	o, leg := ref.order, ref.order.Legs[ref.leg]
+	switch {
+	case id == 0 && leg.State != LegPlacing, id != 0 && (leg.State != LegPlaced || leg.OrderId != id):
+		// moved on meanwhile.
+		return
+	case errors.Is(err, ErrOrderNotFound) && id == 0:
+		e.logger.Infof("conditional %s %s never reached the exchange, armed again", o.Id, leg.Name)
+		leg.State = LegArmed
+		return
+	case errors.Is(err, ErrOrderNotFound):
+		e.logger.Warnf("conditional %s %s order %d is not found, taken as canceled", o.Id, leg.Name, id)
+		e.cancelLeg(o, ref.leg, actions)
+		return
+	}
+
+	leg.OrderId, leg.State = order.Id, LegPlaced
+	if order.State != "done" && order.State != "cancel" {
+		return
+	}
+	// the fees of fills missing from the trades are unknown, they are left in Received.
+	executed := decimalOrZero(order.ExecutedVolume)
+	if executed.GreaterThan(leg.Filled) {
+		leg.Received = leg.Received.Add(executed.Sub(leg.Filled))
+		leg.Filled = executed
+	}
+	if leg.Filled.IsPositive() {
+		e.legFilled(o, ref.leg, actions)
+		return
+	}
+	e.logger.Warnf("conditional %s %s order %d was canceled without fills", o.Id, leg.Name, order.Id)
+	e.cancelLeg(o, ref.leg, actions)
+}
+
+// flush saves the moved trailing triggers.
+func (e *ConditionalEngine) flush() {
+	e.ordersBranch.Lock()
+	defer e.ordersBranch.Unlock()
+	if e.ordersBranch.dirty {
+		e.save()
+	}
+}
+
+// save writes the active orders to Path, with ordersBranch locked.
+func (e *ConditionalEngine) save() {
+	e.ordersBranch.dirty = false
+	if e.Path == "" {
+		return
+	}
+	orders := make([]*ConditionalOrder, 0, len(e.ordersBranch.orders))
+	for _, o := range e.ordersBranch.orders {
+		if o.Active() {
+			orders = append(orders, o)
+		}
+	}
+	sort.Slice(orders, func(i, j int) bool { return orders[i].CreatedAt.Before(orders[j].CreatedAt) })
+	raw, err := json.MarshalIndent(orders, "", "  ")
+	if err != nil {
+		e.logger.Warn("fail to save conditional orders: ", err)
+		return
+	}
+	// written aside then renamed, so a crash never leaves half a file.
+	tmp := e.Path + ".tmp"
+	if err := os.WriteFile(tmp, raw, 0600); err != nil {
+		e.logger.Warn("fail to save conditional orders: ", err)
+		return
+	}
+	if err := os.Rename(tmp, e.Path); err != nil {
+		e.logger.Warn("fail to save conditional orders: ", err)
+	}
+}
@@ -0,0 +1,249 @@
+package max_RESTfulAPI
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/sirupsen/logrus"
+)
+
+func TestConditionalOrders(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	markets := []Market{{Id: "btcusdt", BaseUnit: "btc", QuoteUnit: "usdt", BaseUnitPrecision: 4, QuoteUnitPrecision: 2}}
+	d := decimal.RequireFromString
+	path := filepath.Join(t.TempDir(), "conditional.json")
+
+	// the bracket exits with what the entry received, net of the fee, and the stop loss cancels the take profit.
+	ex := NewMemoryExchange(markets)
+	e, err := NewConditionalEngine(ex, path, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bracket, err := e.PlaceBracket("BTC/USDT", "buy", d("1"), d("100"), d("110"), d("95"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, _ := ex.OpenOrderRows()
+	if len(rows) != 1 || rows[0].Side != "buy" || !rows[0].Price.Equal(d("100")) {
+		t.Fatalf("entry %+v", rows)
+	}
+	ex.Fill(mustOid(t, rows[0].Oid), d("100"), d("1"), d("0.001"), true)
+	rows, _ = ex.OpenOrderRows()
+	if len(rows) != 1 || rows[0].Side != "sell" || !rows[0].Price.Equal(d("110")) || !rows[0].Qty.Equal(d("0.999")) {
+		t.Fatalf("take profit %+v", rows)
+	}
+	e.priceArrived("btcusdt", d("96"), d("96.1"))
+	if rows, _ := ex.OpenOrderRows(); len(rows) != 1 {
+		t.Fatalf("stop loss triggered above the stop: %+v", rows)
+	}
+	e.priceArrived("btcusdt", d("94.9"), d("95"))
+	placed := ex.Placed()
+	if rows, _ := ex.OpenOrderRows(); len(rows) != 0 || len(placed) != 3 || placed[2].OrdType != "market" || placed[2].Volume != "0.999" {
+		t.Fatalf("stop loss %+v, open %+v", placed, rows)
+	}
+	ex.Fill(placed[2].Id, d("94.9"), d("0.999"), d("0.1"), false)
+	if o, _ := e.Order(bracket.Id); o.Active() || o.Legs[2].State != LegFilled || o.Legs[1].State != LegCanceled {
+		t.Errorf("bracket %+v %+v %+v", o.Legs[0], o.Legs[1], o.Legs[2])
+	}
+
+	// a trailing stop survives a restart with its trigger.
+	trailing, err := e.PlaceTrailingStop("btcusdt", "sell", d("0.5"), decimal.Zero, d("0.05"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.priceArrived("btcusdt", d("100"), d("100"))
+	e.priceArrived("btcusdt", d("120"), d("120"))
+	e.priceArrived("btcusdt", d("115"), d("115"))
+	e.flush()
+
+	ex2 := NewMemoryExchange(markets)
+	e2, err := NewConditionalEngine(ex2, path, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	orders := e2.Orders()
+	if len(orders) != 1 || orders[0].Id != trailing.Id || !orders[0].Legs[0].Trigger.Equal(d("114")) {
+		t.Fatalf("loaded %+v", orders)
+	}
+	e2.priceArrived("btcusdt", d("113.9"), d("114"))
+	if placed := ex2.Placed(); len(placed) != 1 || placed[0].Side != "sell" || placed[0].Volume != "0.5" {
+		t.Fatalf("trailing stop %+v", placed)
+	}
+
+	// reconciling books the missed fills once and cancels the legs whose order vanished without any.
+	oco, err := e2.PlaceOCO("btcusdt", "sell", d("1"), d("110"), d("90"), decimal.Zero)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry, err := e2.PlaceBracket("btcusdt", "buy", d("1"), d("100"), d("110"), d("90"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	o, _ := e2.Order(oco.Id)
+	ex2.CancelOrder("btcusdt", o.Legs[0].OrderId, nil)
+	b, _ := e2.Order(entry.Id)
+	ex2.CancelOrder("btcusdt", b.Legs[0].OrderId, nil)
+	missed := Trade{Id: 77, Oid: b.Legs[0].OrderId, Price: "100", Volume: "1", Market: "btcusdt", Side: "buy"}
+	for i := 0; i < 2; i++ {
+		if err := e2.Reconcile([]Trade{missed}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if o, _ := e2.Order(oco.Id); o.Legs[0].State != LegCanceled || o.Legs[1].State != LegArmed {
+		t.Errorf("oco after reconcile %+v %+v", o.Legs[0], o.Legs[1])
+	}
+	b, _ = e2.Order(entry.Id)
+	if b.Legs[0].State != LegFilled || !b.Legs[0].Filled.Equal(d("1")) || b.Legs[1].State != LegPlaced || !b.Legs[1].Volume.Equal(d("1")) {
+		t.Errorf("bracket after reconcile %+v %+v", b.Legs[0], b.Legs[1])
+	}
+}
+
+func TestConditionalRestart(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	markets := []Market{{Id: "btcusdt", BaseUnit: "btc", QuoteUnit: "usdt", BaseUnitPrecision: 4, QuoteUnitPrecision: 2}}
+	d := decimal.RequireFromString
+	path := filepath.Join(t.TempDir(), "conditional.json")
+
+	// the take profit fills while the process is down, the restarted engine must not leave the stop loss armed.
+	ex := NewMemoryExchange(markets)
+	e, err := NewConditionalEngine(ex, path, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	oco, err := e.PlaceOCO("btcusdt", "sell", d("1"), d("110"), d("90"), decimal.Zero)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tp := mustOid(t, mustRows(t, ex)[0].Oid)
+	e.Path = "" // the process dies.
+	ex.Fill(tp, d("110"), d("1"), d("0.11"), true)
+
+	e2, err := NewConditionalEngine(ex, path, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the fill is older than the trade snapshot.
+	if err := e2.Reconcile(nil); err != nil {
+		t.Fatal(err)
+	}
+	o, _ := e2.Order(oco.Id)
+	if o.Active() || o.Legs[0].State != LegFilled || !o.Legs[0].Filled.Equal(d("1")) || o.Legs[1].State != LegCanceled {
+		t.Fatalf("oco after restart %+v %+v", o.Legs[0], o.Legs[1])
+	}
+	e2.priceArrived("btcusdt", d("80"), d("80"))
+	if placed := ex.Placed(); len(placed) != 1 {
+		t.Fatalf("stop loss sold again after the take profit: %+v", placed)
+	}
+
+	// the process dies between sending a market stop and saving its order id, the restart must not send it again.
+	path = filepath.Join(t.TempDir(), "conditional.json")
+	e3, err := NewConditionalEngine(ex, path, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	trailing, err := e3.PlaceTrailingStop("btcusdt", "sell", d("0.5"), d("5"), decimal.Zero)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e3.priceArrived("btcusdt", d("100"), d("100"))
+	saved, _ := os.ReadFile(path)
+	e3.priceArrived("btcusdt", d("95"), d("95"))
+	e3.Path = ""
+	stop := ex.Placed()[1]
+	ex.Fill(stop.Id, d("95"), d("0.5"), d("0.05"), false)
+
+	var orders []*ConditionalOrder
+	json.Unmarshal(saved, &orders)
+	orders[0].Legs[0].State = LegPlacing
+	saved, _ = json.Marshal(orders)
+	os.WriteFile(path, saved, 0600)
+
+	e4, err := NewConditionalEngine(ex, path, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := e4.Reconcile(nil); err != nil {
+		t.Fatal(err)
+	}
+	o, _ = e4.Order(trailing.Id)
+	if o.Active() || o.Legs[0].OrderId != stop.Id || !o.Legs[0].Filled.Equal(d("0.5")) {
+		t.Fatalf("trailing stop after restart %+v", o.Legs[0])
+	}
+	if placed := ex.Placed(); len(placed) != 2 {
+		t.Fatalf("market stop sent twice: %+v", placed)
+	}
+
+	// one never sent is armed again.
+	orders[0].Legs[0].ClientOid = "cond-unknown-stop"
+	saved, _ = json.Marshal(orders)
+	os.WriteFile(path, saved, 0600)
+	e5, _ := NewConditionalEngine(ex, path, logger)
+	if err := e5.Reconcile(nil); err != nil {
+		t.Fatal(err)
+	}
+	if o, _ := e5.Order(trailing.Id); o.Legs[0].State != LegArmed {
+		t.Fatalf("unsent stop %+v", o.Legs[0])
+	}
+}
+
+func mustRows(t *testing.T, ex Exchange) []OpenOrderRow {
+	t.Helper()
+	rows, err := ex.OpenOrderRows()
+	if err != nil || len(rows) == 0 {
+		t.Fatalf("open orders %+v: %v", rows, err)
+	}
+	return rows
+}
+
+// test a stopped engine drops its listeners and neither triggers nor reconciles
+func TestConditionalStop(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	d := decimal.RequireFromString
+	ex := NewMemoryExchange([]Market{{Id: "btcusdt", BaseUnit: "btc", QuoteUnit: "usdt", BaseUnitPrecision: 4, QuoteUnitPrecision: 2}})
+	e, err := NewConditionalEngine(ex, "", logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	trades := newTradeStreamBranch([]string{"btcusdt"}, TradeStreamConfig{BufferSize: -1}, logger)
+	e.WatchTrades(trades)
+	if _, err := e.PlaceTrailingStop("btcusdt", "sell", d("1"), d("5"), decimal.Zero); err != nil {
+		t.Fatal(err)
+	}
+	trades.tradesArrived([]PublicTrade{{Market: "btcusdt", Price: d("100"), Volume: d("1")}})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	e.Run(ctx)
+	cancel()
+	deadline := time.Now().Add(time.Second)
+	for !e.stopped() {
+		if time.Now().After(deadline) {
+			t.Fatal("engine not stopped")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	if len(trades.listenersBranch.listeners) != 0 || len(ex.listenersBranch.listeners) != 0 {
+		t.Fatalf("listeners left: %d trade, %d trade report", len(trades.listenersBranch.listeners), len(ex.listenersBranch.listeners))
+	}
+	// below the trigger of 95.
+	e.priceArrived("btcusdt", d("90"), d("90"))
+	if err := e.Reconcile(nil); err != nil {
+		t.Fatal(err)
+	}
+	if placed := ex.Placed(); len(placed) != 0 {
+		t.Fatalf("stopped engine placed %+v", placed)
+	}
+	// watching after the stop doesn't subscribe either.
+	e.WatchTrades(trades)
+	if len(trades.listenersBranch.listeners) != 0 {
+		t.Fatal("subscribed after the stop")
+	}
+}
@@ -0,0 +1,116 @@
+package max_RESTfulAPI
+
+import (
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+// NormalizeCurrency returns the canonical symbol of a currency, the lowercase MAX id, e.g. " USDT" -> "usdt".
+// Every currency keyed map of the client uses it.
+func NormalizeCurrency(currency string) string {
+	return strings.ToLower(strings.TrimSpace(currency))
+}
+
+// NormalizeMarket returns the canonical MAX market id, e.g. "BTC/TWD" or "btc-twd" -> "btctwd".
+func NormalizeMarket(market string) string {
+	market = strings.ToLower(strings.TrimSpace(market))
+	return strings.NewReplacer("/", "", "-", "", "_", "").Replace(market)
+}
+
+// DepositEnabled is true if any network takes deposits, or if the networks are unknown.
+func (c Currency) DepositEnabled() bool {
+	if len(c.Networks) == 0 {
+		return true
+	}
+	for _, n := range c.Networks {
+		if n.DepositEnabled {
+			return true
+		}
+	}
+	return false
+}
+
+// WithdrawEnabled is true if any network allows withdrawals, or if the networks are unknown.
+func (c Currency) WithdrawEnabled() bool {
+	if len(c.Networks) == 0 {
+		return true
+	}
+	for _, n := range c.Networks {
+		if n.WithdrawalEnabled {
+			return true
+		}
+	}
+	return false
+}
+
+// MinWithdrawal is the lowest minimum withdrawal amount of the enabled networks, zero if unknown.
+func (c Currency) MinWithdrawal() decimal.Decimal {
+	min := decimal.Zero
+	for _, n := range c.Networks {
+		if !n.WithdrawalEnabled {
+			continue
+		}
+		if min.IsZero() || n.MinWithdrawalAmount.LessThan(min) {
+			min = n.MinWithdrawalAmount
+		}
+	}
+	return min
+}
+
+// Network finds the network of a currency version, e.g. the erc20 of a usdt withdrawal address.
+func (c Currency) Network(version string) (CurrencyNetwork, bool) {
+	version = strings.ToLower(strings.TrimSpace(version))
+	if version == "" {
+		return CurrencyNetwork{}, false
+	}
+	for _, n := range c.Networks {
+		if strings.ToLower(n.NetworkProtocol) == version || strings.ToLower(n.Id) == version {
+			return n, true
+		}
+	}
+	return CurrencyNetwork{}, false
+}
+
+// Round truncates an amount to the precision of the currency.
+func (c Currency) Round(amount decimal.Decimal) decimal.Decimal {
+	return amount.Truncate(int32(c.Precision))
+}
+
+func normalizeCurrencies(currencies []Currency) []Currency {
+	for i := range currencies {
+		currencies[i].Id = NormalizeCurrency(currencies[i].Id)
+	}
+	return currencies
+}
+
+func normalizeMarkets(markets []Market) []Market {
+	for i := range markets {
+		markets[i].Id = NormalizeMarket(markets[i].Id)
+		markets[i].BaseUnit = NormalizeCurrency(markets[i].BaseUnit)
+		markets[i].QuoteUnit = NormalizeCurrency(markets[i].QuoteUnit)
+	}
+	return markets
+}
+
+// Currency looks a currency up in the registry of the client by any casing of its symbol.
+func (Mc *MaxClient) Currency(symbol string) (Currency, bool) {
+	symbol = NormalizeCurrency(symbol)
+	Mc.CurrenciesBranch.RLock()
+	defer Mc.CurrenciesBranch.RUnlock()
+	c, ok := Mc.CurrenciesBranch.byId[symbol]
+	return c, ok
+}
+
+func (Mc *MaxClient) setCurrencies(currencies []Currency) {
+	currencies = normalizeCurrencies(currencies)
+	byId := make(map[string]Currency, len(currencies))
+	for _, c := range currencies {
+		byId[c.Id] = c
+	}
+
+	Mc.CurrenciesBranch.Lock()
+	defer Mc.CurrenciesBranch.Unlock()
+	Mc.CurrenciesBranch.Currencies = currencies
+	Mc.CurrenciesBranch.byId = byId
+}
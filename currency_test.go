@@ -0,0 +1,35 @@
+package max_RESTfulAPI
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestNormalizeSymbols(t *testing.T) {
+	for in, want := range map[string]string{"BTC/TWD": "btctwd", "eth-usdt": "ethusdt", " Max_Twd ": "maxtwd"} {
+		if got := NormalizeMarket(in); got != want {
+			t.Errorf("NormalizeMarket(%q) = %q, want %q", in, got, want)
+		}
+	}
+	if got := NormalizeCurrency(" USDT "); got != "usdt" {
+		t.Errorf("NormalizeCurrency = %q", got)
+	}
+
+	var Mc MaxClient
+	Mc.setCurrencies([]Currency{{Id: "USDT", Precision: 2, Networks: []CurrencyNetwork{
+		{WithdrawalEnabled: true, MinWithdrawalAmount: decimal.NewFromInt(10)},
+		{WithdrawalEnabled: true, MinWithdrawalAmount: decimal.NewFromInt(3)},
+		{WithdrawalEnabled: false, MinWithdrawalAmount: decimal.NewFromInt(1)},
+	}}})
+	c, ok := Mc.Currency("usdt")
+	if !ok {
+		t.Fatal("usdt not found")
+	}
+	if !c.WithdrawEnabled() || c.DepositEnabled() {
+		t.Errorf("enabled: withdraw %v, deposit %v", c.WithdrawEnabled(), c.DepositEnabled())
+	}
+	if !c.MinWithdrawal().Equal(decimal.NewFromInt(3)) {
+		t.Errorf("min withdrawal %s", c.MinWithdrawal())
+	}
+}
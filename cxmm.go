@@ -0,0 +1,420 @@
+package max_RESTfulAPI
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/sirupsen/logrus"
+)
+
+// ReferenceSource provides the best bid and ask of a market on the hedge venue.
+type ReferenceSource interface {
+	ReferencePrice(market string) (bid, ask decimal.Decimal, ok bool)
+}
+
+// CXMMExchange is the venue CXMM quotes on, its fills come through OnTradeReport.
+type CXMMExchange interface {
+	Exchange
+	OnTradeReport(fn func(Trade)) (unsubscribe func())
+}
+
+var (
+	_ CXMMExchange = (*MaxClient)(nil)
+	_ CXMMExchange = (*PaperClient)(nil)
+	_ CXMMExchange = (*MemoryExchange)(nil)
+)
+
+type CXMMConfig struct {
+	Market string
+	// quotes per side, default to 3.
+	Levels int
+	// size of every quote, in SizeUnit.
+	Size decimal.Decimal
+	// currency of Size, the quote currency sizes by notional, anything else by base volume.
+	// Default to the BaseOrderUnit of the client.
+	SizeUnit string
+
+	// distance of the first quote from the reference bid/ask, as a fraction, e.g. 0.002.
+	Spread decimal.Decimal
+	// added distance of every next quote, as a fraction.
+	LevelStep decimal.Decimal
+
+	// base inventory at which the quotes are skewed by MaxSkew, and stop on the side growing it.
+	MaxInventory decimal.Decimal
+	// fraction both sides move against the inventory at MaxInventory, e.g. 0.001.
+	MaxSkew decimal.Decimal
+
+	// a quote is only replaced when its target moved more than this fraction, e.g. 0.0005.
+	Hysteresis decimal.Decimal
+	// how often the book and the reference are checked, default to 200 milliseconds.
+	Interval time.Duration
+
+	// called with every fill, to hedge it on the reference venue.
+	OnFill func(HedgingOrder)
+}
+
+// CXMM quotes post only ladders on MAX around the price of a reference venue,
+// skewed by the inventory, and books every fill as a HedgingOrder.
+type CXMM struct {
+	Config    CXMMConfig
+	Reference ReferenceSource
+
+	exchange    CXMMExchange
+	book        *OrderbookBranch
+	logger      *logrus.Logger
+	unsubscribe func()
+
+	base, quote        string
+	pricePrec, volPrec int32
+
+	quotesBranch struct {
+		// by side then level, id 0 for no quote.
+		quotes    map[string][]cxmmQuote
+		inventory decimal.Decimal
+		// every quote id placed, with the time it was canceled, zero while it rests.
+		ids map[int64]time.Time
+		sync.Mutex
+	}
+
+	// trade reports of the market received since the last step.
+	fillsBranch struct {
+		trades []Trade
+		sync.Mutex
+	}
+
+	hedgesBranch struct {
+		orders []HedgingOrder
+		profit decimal.Decimal
+		fees   decimal.Decimal
+		sync.RWMutex
+	}
+}
+
+type cxmmQuote struct {
+	id     int64
+	price  decimal.Decimal
+	volume decimal.Decimal
+}
+
+// NewCXMM quotes on the client, sized by its BaseOrderUnit unless cfg.SizeUnit is set.
+// book may be nil, it keeps the quotes from crossing the MAX book.
+func (Mc *MaxClient) NewCXMM(cfg CXMMConfig, reference ReferenceSource, book *OrderbookBranch) *CXMM {
+	if cfg.SizeUnit == "" {
+		cfg.SizeUnit = Mc.ReadBaseOrderUnit()
+	}
+	return NewCXMM(Mc, reference, book, cfg, Mc.logger)
+}
+
+func NewCXMM(exchange CXMMExchange, reference ReferenceSource, book *OrderbookBranch, cfg CXMMConfig, logger *logrus.Logger) *CXMM {
+	cfg.Market = NormalizeMarket(cfg.Market)
+	cfg.SizeUnit = NormalizeCurrency(cfg.SizeUnit)
+	if cfg.Levels <= 0 {
+		cfg.Levels = 3
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = 200 * time.Millisecond
+	}
+	c := &CXMM{Config: cfg, Reference: reference, exchange: exchange, book: book, logger: logger}
+	c.base, c.quote, _ = splitMarket(exchange.ReadMarkets(), cfg.Market)
+	c.pricePrec, c.volPrec = 8, 8
+	for _, m := range exchange.ReadMarkets() {
+		if m.Id == cfg.Market {
+			c.pricePrec, c.volPrec = int32(m.QuoteUnitPrecision), int32(m.BaseUnitPrecision)
+		}
+	}
+	c.quotesBranch.quotes = map[string][]cxmmQuote{
+		"buy":  make([]cxmmQuote, cfg.Levels),
+		"sell": make([]cxmmQuote, cfg.Levels),
+	}
+	c.quotesBranch.ids = make(map[int64]time.Time)
+	c.unsubscribe = exchange.OnTradeReport(c.tradeReportArrived)
+	return c
+}
+
+// tradeReportArrived keeps the trades of the market for the next step, which tells its own fills apart.
+// A quote filled before its placement returned is known by then.
+func (c *CXMM) tradeReportArrived(trade Trade) {
+	if NormalizeMarket(trade.Market) != c.Config.Market {
+		return
+	}
+	c.fillsBranch.Lock()
+	defer c.fillsBranch.Unlock()
+	c.fillsBranch.trades = append(c.fillsBranch.trades, trade)
+}
+
+// Run quotes until ctx is done, then cancels the quotes and stops listening to fills.
+func (c *CXMM) Run(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(c.Config.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				c.cancelAll()
+				c.unsubscribe()
+				return
+			case <-ticker.C:
+				c.step()
+			}
+		}
+	}()
+}
+
+// step books the fills of the quotes then requotes.
+func (c *CXMM) step() {
+	c.fillsBranch.Lock()
+	trades := c.fillsBranch.trades
+	c.fillsBranch.trades = nil
+	c.fillsBranch.Unlock()
+
+	for _, trade := range trades {
+		if c.ownQuote(trade.Oid) {
+			c.filled(tradeReportRow(trade))
+		}
+	}
+	c.pruneQuoteIds()
+	c.requote()
+}
+
+func (c *CXMM) ownQuote(id int64) bool {
+	c.quotesBranch.Lock()
+	defer c.quotesBranch.Unlock()
+	_, ok := c.quotesBranch.ids[id]
+	return ok
+}
+
+// pruneQuoteIds forgets the quotes canceled long enough ago that no fill of them is still on the way.
+func (c *CXMM) pruneQuoteIds() {
+	c.quotesBranch.Lock()
+	defer c.quotesBranch.Unlock()
+	for id, canceledAt := range c.quotesBranch.ids {
+		if !canceledAt.IsZero() && time.Since(canceledAt) > time.Minute {
+			delete(c.quotesBranch.ids, id)
+		}
+	}
+}
+
+// Inventory is the base volume bought minus sold since the start.
+func (c *CXMM) Inventory() decimal.Decimal {
+	c.quotesBranch.Lock()
+	defer c.quotesBranch.Unlock()
+	return c.quotesBranch.inventory
+}
+
+// targets returns the ladder of a side, zero prices for the levels not to quote.
+func (c *CXMM) targets(side string, refBid, refAsk, inventory decimal.Decimal) []cxmmQuote {
+	one := decimal.NewFromInt(1)
+	skew := decimal.Zero
+	if c.Config.MaxInventory.IsPositive() {
+		ratio := inventory.Div(c.Config.MaxInventory)
+		if ratio.GreaterThan(one) {
+			ratio = one
+		} else if ratio.LessThan(one.Neg()) {
+			ratio = one.Neg()
+		}
+		skew = c.Config.MaxSkew.Mul(ratio)
+
+		// stop the side growing the inventory past the limit.
+		if (side == "buy" && inventory.GreaterThanOrEqual(c.Config.MaxInventory)) ||
+			(side == "sell" && inventory.LessThanOrEqual(c.Config.MaxInventory.Neg())) {
+			return make([]cxmmQuote, c.Config.Levels)
+		}
+	}
+
+	var bestBid, bestAsk decimal.Decimal
+	if c.book != nil {
+		if bids, ok := c.book.GetBids(); ok {
+			bestBid = bids[0][0]
+		}
+		if asks, ok := c.book.GetAsks(); ok {
+			bestAsk = asks[0][0]
+		}
+	}
+
+	quotes := make([]cxmmQuote, c.Config.Levels)
+	for i := range quotes {
+		offset := c.Config.Spread.Add(c.Config.LevelStep.Mul(decimal.NewFromInt(int64(i))))
+		var price decimal.Decimal
+		if side == "buy" {
+			price = refBid.Mul(one.Sub(offset).Sub(skew)).RoundFloor(c.pricePrec)
+			// post only would be rejected.
+			if bestAsk.IsPositive() && price.GreaterThanOrEqual(bestAsk) {
+				continue
+			}
+		} else {
+			price = refAsk.Mul(one.Add(offset).Sub(skew)).RoundCeil(c.pricePrec)
+			if bestBid.IsPositive() && price.LessThanOrEqual(bestBid) {
+				continue
+			}
+		}
+		if !price.IsPositive() {
+			continue
+		}
+		volume := c.Config.Size
+		if c.Config.SizeUnit == c.quote {
+			volume = c.Config.Size.Div(price)
+		}
+		volume = volume.RoundFloor(c.volPrec)
+		if volume.IsPositive() {
+			quotes[i] = cxmmQuote{price: price, volume: volume}
+		}
+	}
+	return quotes
+}
+
+// requote replaces the quotes whose target moved past the hysteresis.
+func (c *CXMM) requote() {
+	refBid, refAsk, ok := c.Reference.ReferencePrice(c.Config.Market)
+	if !ok || !refBid.IsPositive() || !refAsk.IsPositive() {
+		// quoting blind is worse than not quoting.
+		c.cancelAll()
+		return
+	}
+
+	c.quotesBranch.Lock()
+	defer c.quotesBranch.Unlock()
+	for _, side := range []string{"buy", "sell"} {
+		targets := c.targets(side, refBid, refAsk, c.quotesBranch.inventory)
+		quotes := c.quotesBranch.quotes[side]
+		for i, target := range targets {
+			current := quotes[i]
+			if current.id != 0 && target.price.IsPositive() && c.withinHysteresis(current.price, target.price) {
+				continue
+			}
+			if current.id != 0 {
+				if _, err := c.exchange.CancelOrder(c.Config.Market, current.id, nil); err != nil {
+					c.logger.Warnf("fail to cancel quote %d: %v", current.id, err)
+				}
+				c.quotesBranch.ids[current.id] = time.Now()
+				quotes[i] = cxmmQuote{}
+			}
+			if !target.price.IsPositive() {
+				continue
+			}
+			price, _ := target.price.Float64()
+			volume, _ := target.volume.Float64()
+			order, err := c.exchange.PlacePostOnlyOrder(c.Config.Market, side, price, volume)
+			if err != nil {
+				c.logger.Warnf("fail to quote %s %s@%s: %v", side, target.volume, target.price, err)
+				continue
+			}
+			target.id = order.Id
+			quotes[i] = target
+			c.quotesBranch.ids[order.Id] = time.Time{}
+		}
+	}
+}
+
+func (c *CXMM) withinHysteresis(current, target decimal.Decimal) bool {
+	return current.Sub(target).Abs().LessThanOrEqual(target.Mul(c.Config.Hysteresis))
+}
+
+func (c *CXMM) cancelAll() {
+	c.quotesBranch.Lock()
+	defer c.quotesBranch.Unlock()
+	for side, quotes := range c.quotesBranch.quotes {
+		for i, q := range quotes {
+			if q.id == 0 {
+				continue
+			}
+			if _, err := c.exchange.CancelOrder(c.Config.Market, q.id, nil); err != nil {
+				c.logger.Warnf("fail to cancel quote %d: %v", q.id, err)
+			}
+			c.quotesBranch.ids[q.id] = time.Now()
+			c.quotesBranch.quotes[side][i] = cxmmQuote{}
+		}
+	}
+}
+
+// filled books a fill as a HedgingOrder, its profit is the edge against the reference price
+// the hedge would trade at, net of the MAX fee, in the quote currency.
+func (c *CXMM) filled(row TradeReportRow) {
+	volume := row.FilledQty
+	c.quotesBranch.Lock()
+	if row.Side == "buy" {
+		c.quotesBranch.inventory = c.quotesBranch.inventory.Add(volume)
+	} else {
+		c.quotesBranch.inventory = c.quotesBranch.inventory.Sub(volume)
+	}
+	id, _ := strconv.ParseInt(row.Oid, 10, 64)
+	for side, quotes := range c.quotesBranch.quotes {
+		for i, q := range quotes {
+			if q.id != id {
+				continue
+			}
+			q.volume = q.volume.Sub(volume)
+			if !q.volume.IsPositive() {
+				q = cxmmQuote{}
+				delete(c.quotesBranch.ids, id)
+			}
+			c.quotesBranch.quotes[side][i] = q
+		}
+	}
+	c.quotesBranch.Unlock()
+
+	fee := row.Fee
+	if NormalizeCurrency(row.FeeCurrency) == c.base {
+		fee = fee.Mul(row.Price)
+	}
+	refBid, refAsk, ok := c.Reference.ReferencePrice(c.Config.Market)
+	profit := decimal.Zero
+	if ok {
+		if row.Side == "buy" {
+			profit = refBid.Sub(row.Price).Mul(volume)
+		} else {
+			profit = row.Price.Sub(refAsk).Mul(volume)
+		}
+	}
+	profit = profit.Sub(fee)
+
+	signed := volume
+	if row.Side == "sell" {
+		signed = volume.Neg()
+	}
+	h := HedgingOrder{
+		Market:         c.Config.Market,
+		Base:           c.base,
+		Quote:          c.quote,
+		Profit:         floatOf(profit),
+		Volume:         floatOf(signed),
+		Timestamp:      row.Timestamp,
+		AbsVolume:      floatOf(volume),
+		MaxFee:         floatOf(row.Fee),
+		MaxFeeCurrency: row.FeeCurrency,
+		MaxMaker:       row.IsMaker,
+	}
+
+	c.hedgesBranch.Lock()
+	c.hedgesBranch.profit = c.hedgesBranch.profit.Add(profit)
+	c.hedgesBranch.fees = c.hedgesBranch.fees.Add(fee)
+	h.TotalProfit = floatOf(c.hedgesBranch.profit)
+	c.hedgesBranch.orders = append(c.hedgesBranch.orders, h)
+	c.hedgesBranch.Unlock()
+
+	c.logger.Infof("cxmm %s %s %s@%s, profit %s %s", c.Config.Market, row.Side, volume, row.Price, profit, c.quote)
+	if c.Config.OnFill != nil {
+		c.Config.OnFill(h)
+	}
+}
+
+func floatOf(d decimal.Decimal) float64 {
+	f, _ := d.Float64()
+	return f
+}
+
+// HedgingOrders returns a copy of the booked fills.
+func (c *CXMM) HedgingOrders() []HedgingOrder {
+	c.hedgesBranch.RLock()
+	defer c.hedgesBranch.RUnlock()
+	return append([]HedgingOrder(nil), c.hedgesBranch.orders...)
+}
+
+// Stats returns the total profit and the total MAX fees, in the quote currency.
+func (c *CXMM) Stats() (profit, fees decimal.Decimal) {
+	c.hedgesBranch.RLock()
+	defer c.hedgesBranch.RUnlock()
+	return c.hedgesBranch.profit, c.hedgesBranch.fees
+}
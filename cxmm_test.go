@@ -0,0 +1,99 @@
+package max_RESTfulAPI
+
+import (
+	"io"
+	"strconv"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/sirupsen/logrus"
+)
+
+type staticReference struct{ bid, ask decimal.Decimal }
+
+func (s *staticReference) ReferencePrice(string) (decimal.Decimal, decimal.Decimal, bool) {
+	return s.bid, s.ask, true
+}
+
+func TestCXMMQuoting(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	ex := NewMemoryExchange([]Market{{Id: "btcusdt", BaseUnit: "btc", QuoteUnit: "usdt", BaseUnitPrecision: 4, QuoteUnitPrecision: 2}})
+	ref := &staticReference{bid: decimal.NewFromInt(100), ask: decimal.NewFromInt(101)}
+	c := NewCXMM(ex, ref, nil, CXMMConfig{
+		Market:       "btcusdt",
+		Levels:       2,
+		Size:         decimal.NewFromInt(50),
+		SizeUnit:     "usdt",
+		Spread:       decimal.RequireFromString("0.01"),
+		LevelStep:    decimal.RequireFromString("0.01"),
+		MaxInventory: decimal.NewFromInt(1),
+		MaxSkew:      decimal.RequireFromString("0.02"),
+		Hysteresis:   decimal.RequireFromString("0.005"),
+	}, logger)
+
+	c.step()
+	rows, _ := ex.OpenOrderRows()
+	if len(rows) != 4 {
+		t.Fatalf("quotes %+v", rows)
+	}
+	// bids 99 and 98, asks 102.01 and 103.02, 50 usdt each.
+	if !rows[0].Price.Equal(decimal.NewFromInt(99)) || !rows[0].Qty.Equal(decimal.RequireFromString("0.505")) {
+		t.Errorf("first bid %+v", rows[0])
+	}
+	if !rows[2].Price.Equal(decimal.RequireFromString("102.01")) {
+		t.Errorf("first ask %+v", rows[2])
+	}
+
+	// a small move stays within the hysteresis.
+	ref.bid = decimal.RequireFromString("100.2")
+	c.step()
+	if len(ex.Placed()) != 4 {
+		t.Fatalf("requoted within hysteresis: %d orders", len(ex.Placed()))
+	}
+
+	// buying 0.5 btc skews both sides down by half of MaxSkew.
+	if _, err := ex.Fill(mustOid(t, rows[0].Oid), decimal.NewFromInt(99), decimal.RequireFromString("0.5"), decimal.RequireFromString("0.0005"), true); err != nil {
+		t.Fatal(err)
+	}
+	c.step()
+	if !c.Inventory().Equal(decimal.RequireFromString("0.5")) {
+		t.Errorf("inventory %s", c.Inventory())
+	}
+	hedges := c.HedgingOrders()
+	// (100.2 - 99) * 0.5 - 0.0005 * 99
+	if len(hedges) != 1 || hedges[0].Volume != 0.5 || hedges[0].Profit != 0.5505 {
+		t.Errorf("hedges %+v", hedges)
+	}
+	firstAsk := rows[2].Oid
+	rows, _ = ex.OpenOrderRows()
+	for _, row := range rows {
+		if row.Oid == firstAsk {
+			t.Errorf("ask not skewed: %+v", row)
+		}
+	}
+
+	// fills of other orders are left to their owner.
+	other, err := ex.PlaceLimitOrder("btcusdt", "sell", 99, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ex.Fill(other.Id, decimal.NewFromInt(99), decimal.NewFromInt(1), decimal.Zero, false); err != nil {
+		t.Fatal(err)
+	}
+	c.step()
+	if len(c.HedgingOrders()) != 1 || !c.Inventory().Equal(decimal.RequireFromString("0.5")) {
+		t.Errorf("booked a foreign fill, inventory %s", c.Inventory())
+	}
+	if reports, _ := ex.TradeReportRows(); len(reports) != 2 {
+		t.Errorf("trade reports taken: %+v", reports)
+	}
+}
+
+func mustOid(t *testing.T, oid string) int64 {
+	id, err := strconv.ParseInt(oid, 10, 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
@@ -0,0 +1,217 @@
+package max_RESTfulAPI
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+// ErrOrderNotFound is returned by GetOrder when the exchange has no such order.
+var ErrOrderNotFound = errors.New("order not found")
+
+// Exchange is the contract of the modularized arbitrage framework, with typed rows.
+// MaxClient, PaperClient and MemoryExchange implement it, LegacyAdapter turns it back into [][]string rows.
+type Exchange interface {
+	BalanceRows() ([]BalanceRow, error)
+	OpenOrderRows() ([]OpenOrderRow, error)
+	// takes the trade reports received since the last call.
+	TradeReportRows() ([]TradeReportRow, error)
+
+	PlaceLimitOrder(market string, side string, price, volume float64) (WsOrder, error)
+	PlacePostOnlyOrder(market string, side string, price, volume float64) (WsOrder, error)
+	PlaceMarketOrder(market string, side string, volume float64) (WsOrder, error)
+	CancelOrder(market string, id, clientId interface{}) (WsOrder, error)
+	CancelAllOrders() ([]WsOrder, error)
+
+	ReadMarkets() []Market
+}
+
+var (
+	_ Exchange = (*MaxClient)(nil)
+	_ Exchange = (*PaperClient)(nil)
+	_ Exchange = (*MemoryExchange)(nil)
+)
+
+type BalanceRow struct {
+	Asset     string
+	Available decimal.Decimal
+	// available plus locked.
+	Total decimal.Decimal
+}
+
+type OpenOrderRow struct {
+	Oid        string
+	Symbol     string
+	Product    string
+	Subaccount string
+	Price      decimal.Decimal
+	Qty        decimal.Decimal
+	Side       string
+	ExecType   string
+	// remaining volume.
+	UnfilledQty decimal.Decimal
+}
+
+type TradeReportRow struct {
+	Oid        string
+	Symbol     string
+	Product    string
+	Subaccount string
+	Price      decimal.Decimal
+	Qty        decimal.Decimal
+	Side       string
+	// "limit" for maker fills, "market" for taker fills.
+	ExecType string
+	Fee      decimal.Decimal
+	// not part of the legacy row.
+	FeeCurrency string
+	FilledQty   decimal.Decimal
+	// in milliseconds.
+	Timestamp int64
+	IsMaker   bool
+}
+
+// Strings is the legacy row: asset, available, total. The legacy rows keep the asset uppercase.
+func (r BalanceRow) Strings() []string {
+	return []string{strings.ToUpper(r.Asset), r.Available.String(), r.Total.String()}
+}
+
+// Strings is the legacy row: oid, symbol, product, subaccount, price, qty, side, execType, unfilledQty.
+func (r OpenOrderRow) Strings() []string {
+	return []string{r.Oid, r.Symbol, r.Product, r.Subaccount, r.Price.String(), r.Qty.String(), r.Side, r.ExecType, r.UnfilledQty.String()}
+}
+
+// Strings is the legacy row: oid, symbol, product, subaccount, price, qty, side, execType, fee, filledQty, timestamp, isMaker.
+func (r TradeReportRow) Strings() []string {
+	return []string{r.Oid, r.Symbol, r.Product, r.Subaccount, r.Price.String(), r.Qty.String(), r.Side, r.ExecType,
+		r.Fee.String(), r.FilledQty.String(), strconv.FormatInt(r.Timestamp, 10), strconv.FormatBool(r.IsMaker)}
+}
+
+func openOrderRow(order WsOrder) OpenOrderRow {
+	return OpenOrderRow{
+		Oid:         fmt.Sprint(order.Id),
+		Symbol:      order.Market,
+		Product:     "spot",
+		Price:       decimalOrZero(order.Price),
+		Qty:         decimalOrZero(order.Volume),
+		Side:        order.Side,
+		ExecType:    order.OrdType,
+		UnfilledQty: decimalOrZero(order.RemainingVolume),
+	}
+}
+
+func tradeReportRow(trade Trade) TradeReportRow {
+	execType := "limit"
+	if !trade.Maker {
+		execType = "market"
+	}
+	return TradeReportRow{
+		Oid:         fmt.Sprint(trade.Oid),
+		Symbol:      trade.Market,
+		Product:     "spot",
+		Price:       decimalOrZero(trade.Price),
+		Qty:         decimalOrZero(trade.Volume),
+		Side:        trade.Side,
+		ExecType:    execType,
+		Fee:         decimalOrZero(trade.Fee),
+		FeeCurrency: trade.FeeCurrency,
+		FilledQty:   decimalOrZero(trade.Volume),
+		Timestamp:   trade.Timestamp,
+		IsMaker:     trade.Maker,
+	}
+}
+
+func decimalOrZero(s string) decimal.Decimal {
+	d, err := decimal.NewFromString(s)
+	if err != nil {
+		return decimal.Zero
+	}
+	return d
+}
+
+func (Mc *MaxClient) BalanceRows() ([]BalanceRow, error) {
+	member, _, err := Mc.ApiClient.PrivateApi.GetApiV2MembersAccounts(context.Background(), Mc.signer)
+	if err != nil {
+		return []BalanceRow{}, err
+	}
+	rows := make([]BalanceRow, 0, len(member.Accounts))
+	for _, account := range member.Accounts {
+		available, locked := decimalOrZero(account.Balance), decimalOrZero(account.Locked)
+		rows = append(rows, BalanceRow{Asset: NormalizeCurrency(account.Currency), Available: available, Total: available.Add(locked)})
+	}
+	return rows, nil
+}
+
+func (Mc *MaxClient) OpenOrderRows() ([]OpenOrderRow, error) {
+	orders, _, err := Mc.ApiClient.PrivateApi.GetApiV2Orders(context.Background(), Mc.signer, "all", nil)
+	if err != nil {
+		return []OpenOrderRow{}, err
+	}
+	rows := make([]OpenOrderRow, 0, len(orders))
+	for _, order := range orders {
+		rows = append(rows, openOrderRow(WsOrder(order)))
+	}
+	return rows, nil
+}
+
+func (Mc *MaxClient) TradeReportRows() ([]TradeReportRow, error) {
+	Mc.TradeReportBranch.Lock()
+	trades := Mc.TradeReportBranch.TradeReports
+	Mc.TradeReportBranch.TradeReports = []Trade{}
+	Mc.TradeReportBranch.Unlock()
+
+	rows := make([]TradeReportRow, 0, len(trades))
+	for _, trade := range trades {
+		rows = append(rows, tradeReportRow(trade))
+	}
+	return rows, nil
+}
+
+// LegacyAdapter serves an Exchange to the frameworks reading positional [][]string rows.
+type LegacyAdapter struct {
+	Exchange
+}
+
+// GetBalances() ([][]string, bool)     // []string{asset, available, total}
+func (l LegacyAdapter) GetBalances() ([][]string, bool) {
+	rows, err := l.BalanceRows()
+	if err != nil {
+		return [][]string{}, false
+	}
+	var balances [][]string
+	for _, row := range rows {
+		balances = append(balances, row.Strings())
+	}
+	return balances, true
+}
+
+// GetOpenOrders() ([][]string, bool)   // []string{oid, symbol, product, subaccount, price, qty, side, execType, UnfilledQty}
+func (l LegacyAdapter) GetOpenOrders() ([][]string, bool) {
+	rows, err := l.OpenOrderRows()
+	if err != nil {
+		return [][]string{}, false
+	}
+	var openOrders [][]string
+	for _, row := range rows {
+		openOrders = append(openOrders, row.Strings())
+	}
+	return openOrders, true
+}
+
+// GetTradeReports() ([][]string, bool) // []string{oid, symbol, product, subaccount, price, qty, side, execType, fee, filledQty, timestamp, isMaker}
+// ok is false when there is no new report.
+func (l LegacyAdapter) GetTradeReports() ([][]string, bool) {
+	rows, err := l.TradeReportRows()
+	if err != nil {
+		return [][]string{}, false
+	}
+	var tradeReports [][]string
+	for _, row := range rows {
+		tradeReports = append(tradeReports, row.Strings())
+	}
+	return tradeReports, len(tradeReports) != 0
+}
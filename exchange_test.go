@@ -0,0 +1,78 @@
+package max_RESTfulAPI
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+// test the legacy rows built from the typed rows of the in-memory exchange
+func TestLegacyAdapterRows(t *testing.T) {
+	m := NewMemoryExchange([]Market{{Id: "ethusdt", BaseUnit: "ETH", QuoteUnit: "USDT"}})
+	m.SetBalance("USDT", decimal.NewFromInt(90), decimal.NewFromInt(10))
+	var ex Exchange = m
+	legacy := LegacyAdapter{ex}
+
+	balances, ok := legacy.GetBalances()
+	if !ok || !reflect.DeepEqual(balances, [][]string{{"USDT", "90", "100"}}) {
+		t.Fatalf("balances %v", balances)
+	}
+	// the typed rows are normalized.
+	if rows, _ := ex.BalanceRows(); len(rows) != 1 || rows[0].Asset != "usdt" {
+		t.Fatalf("balance rows %+v", rows)
+	}
+
+	order, err := ex.PlaceLimitOrder("ETH/USDT", "buy", 2000.5, 0.3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ex.PlaceLimitOrder("btcusdt", "buy", 1, 1); err == nil {
+		t.Error("unknown market accepted")
+	}
+	if _, err := m.Fill(order.Id, decimal.RequireFromString("2000.5"), decimal.RequireFromString("0.1"), decimal.RequireFromString("0.00001"), true); err != nil {
+		t.Fatal(err)
+	}
+
+	openOrders, _ := legacy.GetOpenOrders()
+	if !reflect.DeepEqual(openOrders, [][]string{{"1", "ethusdt", "spot", "", "2000.5", "0.3", "buy", "limit", "0.2"}}) {
+		t.Fatalf("open orders %v", openOrders)
+	}
+
+	reports, ok := legacy.GetTradeReports()
+	if !ok || len(reports) != 1 {
+		t.Fatalf("reports %v", reports)
+	}
+	want := []string{"1", "ethusdt", "spot", "", "2000.5", "0.1", "buy", "limit", "0.00001", "0.1"}
+	if !reflect.DeepEqual(reports[0][:10], want) || reports[0][11] != "true" {
+		t.Errorf("report %v", reports[0])
+	}
+	if _, ok := legacy.GetTradeReports(); ok {
+		t.Error("reports not taken")
+	}
+}
+
+// test the in-memory exchange cancels by client oid like MaxClient
+func TestMemoryExchangeCancel(t *testing.T) {
+	m := NewMemoryExchange([]Market{{Id: "ethusdt", BaseUnit: "ETH", QuoteUnit: "USDT"}})
+	order, err := m.PlaceTaggedOrder("ethusdt", "buy", "limit", 2000, 1, "algo-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.CancelOrder("ethusdt", nil, "algo-2"); err == nil {
+		t.Fatal("canceled an unknown client oid")
+	}
+	canceled, err := m.CancelOrder("ethusdt", nil, "algo-1")
+	if err != nil || canceled.Id != order.Id || canceled.State != "cancel" {
+		t.Fatalf("canceled %+v, err %v", canceled, err)
+	}
+	if _, err := m.CancelOrder("ethusdt", order.Id, nil); err == nil {
+		t.Fatal("canceled twice")
+	}
+	if got, _ := m.GetOrder("ethusdt", nil, "algo-1"); got.State != "cancel" {
+		t.Fatalf("order %+v", got)
+	}
+	if _, err := m.CancelOrder("ethusdt", nil, nil); err == nil {
+		t.Fatal("canceled without an id")
+	}
+}
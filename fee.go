@@ -0,0 +1,273 @@
+package max_RESTfulAPI
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/shopspring/decimal"
+)
+
+// FeeTier is the maker/taker fee rate of one MAX VIP level.
+type FeeTier struct {
+	Level int64
+	Maker decimal.Decimal
+	Taker decimal.Decimal
+}
+
+// DefaultMaxFeeSchedule is the spot fee schedule by VIP level, override FeeModel.Schedule if MAX changes it.
+var DefaultMaxFeeSchedule = []FeeTier{
+	{Level: 0, Maker: decimal.RequireFromString("0.00045"), Taker: decimal.RequireFromString("0.0015")},
+	{Level: 1, Maker: decimal.RequireFromString("0.00036"), Taker: decimal.RequireFromString("0.00135")},
+	{Level: 2, Maker: decimal.RequireFromString("0.0003"), Taker: decimal.RequireFromString("0.00125")},
+	{Level: 3, Maker: decimal.RequireFromString("0.00025"), Taker: decimal.RequireFromString("0.0011")},
+	{Level: 4, Maker: decimal.RequireFromString("0.0002"), Taker: decimal.RequireFromString("0.001")},
+	{Level: 5, Maker: decimal.RequireFromString("0.00015"), Taker: decimal.RequireFromString("0.0009")},
+	{Level: 6, Maker: decimal.RequireFromString("0.0001"), Taker: decimal.RequireFromString("0.0008")},
+	{Level: 7, Maker: decimal.RequireFromString("0.00005"), Taker: decimal.RequireFromString("0.0007")},
+	{Level: 8, Maker: decimal.Zero, Taker: decimal.RequireFromString("0.0006")},
+	{Level: 9, Maker: decimal.Zero, Taker: decimal.RequireFromString("0.0005")},
+}
+
+// fee currency of MAX token, paying fees with it gets a discount.
+const maxTokenCurrency = "max"
+
+var (
+	ErrFeeConversion = errors.New("no price route to convert fee")
+	ErrUnknownMarket = errors.New("unknown market")
+)
+
+// FeeEstimate is the expected fee of a prospective order.
+type FeeEstimate struct {
+	Rate     decimal.Decimal
+	Currency string
+	Amount   decimal.Decimal
+	// fee amount normalized into the reporting currency.
+	Reported decimal.Decimal
+}
+
+// FeeModel interprets trade fees and normalizes them into one reporting currency.
+type FeeModel struct {
+	ReportCurrency   string
+	Prices           PriceSource
+	Schedule         []FeeTier
+	MaxTokenDiscount decimal.Decimal
+	PayWithMaxToken  bool
+
+	levelBranch struct {
+		level int64
+		sync.RWMutex
+	}
+
+	marketsBranch struct {
+		markets []Market
+		sync.RWMutex
+	}
+}
+
+func NewFeeModel(reportCurrency string, prices PriceSource) *FeeModel {
+	return &FeeModel{
+		ReportCurrency:   NormalizeCurrency(reportCurrency),
+		Prices:           prices,
+		Schedule:         DefaultMaxFeeSchedule,
+		MaxTokenDiscount: decimal.RequireFromString("0.2"),
+	}
+}
+
+// NewFeeModel builds a fee model with the markets and the VIP level of this account.
+func (Mc *MaxClient) NewFeeModel(reportCurrency string, prices PriceSource) (*FeeModel, error) {
+	f := NewFeeModel(reportCurrency, prices)
+	f.SetMarkets(Mc.ReadMarkets())
+	if err := Mc.SyncFeeLevel(f); err != nil {
+		return f, err
+	}
+	return f, nil
+}
+
+// SyncFeeLevel sets the VIP level of the fee model from Member.Level.
+func (Mc *MaxClient) SyncFeeLevel(f *FeeModel) error {
+	member, err := Mc.GetAccount()
+	if err != nil {
+		return err
+	}
+	f.SetLevel(member.Level)
+	return nil
+}
+
+func (f *FeeModel) SetLevel(level int64) {
+	f.levelBranch.Lock()
+	defer f.levelBranch.Unlock()
+	f.levelBranch.level = level
+}
+
+func (f *FeeModel) Level() int64 {
+	f.levelBranch.RLock()
+	defer f.levelBranch.RUnlock()
+	return f.levelBranch.level
+}
+
+func (f *FeeModel) SetMarkets(markets []Market) {
+	f.marketsBranch.Lock()
+	defer f.marketsBranch.Unlock()
+	f.marketsBranch.markets = markets
+}
+
+// Rates returns the schedule maker/taker rate of the current level, without any discount.
+func (f *FeeModel) Rates() (maker, taker decimal.Decimal) {
+	level := f.Level()
+	if len(f.Schedule) == 0 {
+		return decimal.Zero, decimal.Zero
+	}
+	tier := f.Schedule[0]
+	for _, t := range f.Schedule {
+		if t.Level <= level && t.Level >= tier.Level {
+			tier = t
+		}
+	}
+	return tier.Maker, tier.Taker
+}
+
+// EffectiveRate is the fee rate actually paid, strategies should add it to their quote edges.
+func (f *FeeModel) EffectiveRate(maker bool) decimal.Decimal {
+	makerRate, takerRate := f.Rates()
+	rate := takerRate
+	if maker {
+		rate = makerRate
+	}
+	if f.PayWithMaxToken {
+		rate = rate.Mul(decimal.NewFromInt(1).Sub(f.MaxTokenDiscount))
+	}
+	return rate
+}
+
+// EstimateFee estimates the fee of an order, buy orders are charged in base and sell orders in quote
+// unless fees are paid with MAX token.
+func (f *FeeModel) EstimateFee(market, side string, price, volume float64, maker bool) (FeeEstimate, error) {
+	base, quote, ok := f.splitMarket(market)
+	if !ok {
+		return FeeEstimate{}, fmt.Errorf("%w: %s", ErrUnknownMarket, market)
+	}
+
+	rate := f.EffectiveRate(maker)
+	p, v := decimal.NewFromFloat(price), decimal.NewFromFloat(volume)
+	notional := p.Mul(v)
+
+	estimate := FeeEstimate{Rate: rate}
+	switch {
+	case f.PayWithMaxToken:
+		amount, ok := convertAmount(f.Prices, notional.Mul(rate), quote, maxTokenCurrency)
+		if !ok {
+			return FeeEstimate{}, ErrFeeConversion
+		}
+		estimate.Currency, estimate.Amount = maxTokenCurrency, amount
+	case strings.ToLower(side) == "buy":
+		estimate.Currency, estimate.Amount = base, v.Mul(rate)
+	default:
+		estimate.Currency, estimate.Amount = quote, notional.Mul(rate)
+	}
+
+	reported, err := f.Normalize(estimate.Amount, estimate.Currency)
+	if err != nil {
+		return FeeEstimate{}, err
+	}
+	estimate.Reported = reported
+	return estimate, nil
+}
+
+// Normalize converts a fee amount into the reporting currency.
+func (f *FeeModel) Normalize(amount decimal.Decimal, currency string) (decimal.Decimal, error) {
+	reported, ok := convertAmount(f.Prices, amount, currency, f.ReportCurrency)
+	if !ok {
+		return decimal.Zero, fmt.Errorf("%w: %s to %s", ErrFeeConversion, currency, f.ReportCurrency)
+	}
+	return reported, nil
+}
+
+// TradeFee returns the fee of a fill in the reporting currency.
+func (f *FeeModel) TradeFee(trade Trade) (decimal.Decimal, error) {
+	if trade.Fee == "" {
+		return decimal.Zero, nil
+	}
+	amount, err := decimal.NewFromString(trade.Fee)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	return f.Normalize(amount, trade.FeeCurrency)
+}
+
+// TotalFees sums the fees of fills in the reporting currency.
+func (f *FeeModel) TotalFees(trades []Trade) (decimal.Decimal, error) {
+	total := decimal.Zero
+	for _, trade := range trades {
+		fee, err := f.TradeFee(trade)
+		if err != nil {
+			return total, err
+		}
+		total = total.Add(fee)
+	}
+	return total, nil
+}
+
+// TradeFeeRate is the fee of a fill over its notional, both in the reporting currency.
+func (f *FeeModel) TradeFeeRate(trade Trade) (decimal.Decimal, error) {
+	_, quote, ok := f.splitMarket(trade.Market)
+	if !ok {
+		return decimal.Zero, fmt.Errorf("%w: %s", ErrUnknownMarket, trade.Market)
+	}
+	price, err := decimal.NewFromString(trade.Price)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	volume, err := decimal.NewFromString(trade.Volume)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	notional, err := f.Normalize(price.Mul(volume), quote)
+	if err != nil || notional.IsZero() {
+		return decimal.Zero, err
+	}
+	fee, err := f.TradeFee(trade)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	return fee.Div(notional), nil
+}
+
+// HedgingOrderFees returns the MAX leg and the hedge leg fees of a hedging order in the reporting currency.
+func (f *FeeModel) HedgingOrderFees(h HedgingOrder) (maxFee, hedgeFee decimal.Decimal, err error) {
+	maxFee, err = f.Normalize(decimal.NewFromFloat(h.MaxFee), h.MaxFeeCurrency)
+	if err != nil {
+		return decimal.Zero, decimal.Zero, err
+	}
+	hedgeFee, err = f.Normalize(decimal.NewFromFloat(h.Fee), h.FeeCurrency)
+	if err != nil {
+		return maxFee, decimal.Zero, err
+	}
+	return maxFee, hedgeFee, nil
+}
+
+func (f *FeeModel) splitMarket(market string) (base, quote string, ok bool) {
+	f.marketsBranch.RLock()
+	defer f.marketsBranch.RUnlock()
+	return splitMarket(f.marketsBranch.markets, market)
+}
+
+// common quote currencies on MAX, used when the market list is not loaded.
+var quoteCurrencies = []string{"usdt", "twd", "btc", "eth", "usdc"}
+
+// splitMarket returns base and quote unit of a market id like btctwd.
+func splitMarket(markets []Market, market string) (base, quote string, ok bool) {
+	market = NormalizeMarket(market)
+	for _, m := range markets {
+		if m.Id == market {
+			return NormalizeCurrency(m.BaseUnit), NormalizeCurrency(m.QuoteUnit), true
+		}
+	}
+	for _, q := range quoteCurrencies {
+		if strings.HasSuffix(market, q) && len(market) > len(q) {
+			return strings.TrimSuffix(market, q), q, true
+		}
+	}
+	return "", "", false
+}
@@ -0,0 +1,86 @@
+package max_RESTfulAPI
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+type staticPrices map[string]decimal.Decimal
+
+func (s staticPrices) MidPrice(market string) (decimal.Decimal, bool) {
+	price, ok := s[market]
+	return price, ok
+}
+
+// test fee normalization through direct, inverse and bridged markets
+func TestFeeModelNormalize(t *testing.T) {
+	prices := staticPrices{
+		"usdttwd": decimal.NewFromInt(30),
+		"maxusdt": decimal.RequireFromString("0.5"),
+		"btcusdt": decimal.NewFromInt(20000),
+	}
+	f := NewFeeModel("twd", prices)
+
+	cases := []struct {
+		amount   string
+		currency string
+		want     string
+	}{
+		{"10", "twd", "10"},
+		{"1", "usdt", "30"},
+		{"2", "max", "30"},
+		{"0.001", "btc", "600"},
+	}
+	for _, c := range cases {
+		got, err := f.Normalize(decimal.RequireFromString(c.amount), c.currency)
+		if err != nil {
+			t.Fatal(c.currency, err)
+		}
+		if !got.Equal(decimal.RequireFromString(c.want)) {
+			t.Errorf("%s %s: got %s, want %s", c.amount, c.currency, got, c.want)
+		}
+	}
+
+	if _, err := f.Normalize(decimal.NewFromInt(1), "doge"); err == nil {
+		t.Error("expected error for currency without price route")
+	}
+}
+
+// test estimated fee currency and effective rate with MAX token discount
+func TestFeeModelEstimate(t *testing.T) {
+	prices := staticPrices{
+		"usdttwd": decimal.NewFromInt(30),
+		"maxtwd":  decimal.NewFromInt(15),
+	}
+	f := NewFeeModel("twd", prices)
+	f.SetLevel(0)
+
+	buy, err := f.EstimateFee("usdttwd", "buy", 30, 100, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if buy.Currency != "usdt" || !buy.Amount.Equal(decimal.RequireFromString("0.15")) || !buy.Reported.Equal(decimal.RequireFromString("4.5")) {
+		t.Errorf("unexpected buy estimate %+v", buy)
+	}
+
+	sell, err := f.EstimateFee("usdttwd", "sell", 30, 100, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sell.Currency != "twd" || !sell.Amount.Equal(decimal.RequireFromString("1.35")) {
+		t.Errorf("unexpected sell estimate %+v", sell)
+	}
+
+	f.PayWithMaxToken = true
+	if rate := f.EffectiveRate(false); !rate.Equal(decimal.RequireFromString("0.0012")) {
+		t.Errorf("unexpected discounted rate %s", rate)
+	}
+	withMax, err := f.EstimateFee("usdttwd", "buy", 30, 100, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if withMax.Currency != "max" || !withMax.Reported.Round(8).Equal(decimal.RequireFromString("3.6")) {
+		t.Errorf("unexpected MAX token estimate %+v", withMax)
+	}
+}
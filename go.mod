@@ -7,6 +7,7 @@ require (
 	github.com/json-iterator/go v1.1.12
 	github.com/shopspring/decimal v1.3.1
 	github.com/sirupsen/logrus v1.9.3
+	golang.org/x/crypto v0.11.0
 	golang.org/x/oauth2 v0.11.0
 )
 
@@ -0,0 +1,181 @@
+package max_RESTfulAPI
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// ConnState is the connection state of a websocket component.
+type ConnState string
+
+const (
+	ConnStateConnecting   ConnState = "connecting"
+	ConnStateConnected    ConnState = "connected"
+	ConnStateDisconnected ConnState = "disconnected"
+	ConnStateClosed       ConnState = "closed"
+)
+
+// the message rate is re-measured every window.
+const healthRateWindow = 10 * time.Second
+
+// Health is the connection health of one websocket component.
+type Health struct {
+	Component   string
+	State       ConnState
+	LastMessage time.Time
+	Reconnects  int64
+	LastError   string
+	// messages per second over the last rate window.
+	MessageRate float64
+}
+
+// Fresh tells if the component is connected and got a message within maxAge.
+func (h Health) Fresh(maxAge time.Duration) bool {
+	return h.State == ConnStateConnected && time.Since(h.LastMessage) <= maxAge
+}
+
+// HealthReporter is implemented by every websocket component.
+type HealthReporter interface {
+	Health() Health
+}
+
+// HealthReport is the aggregate health of the components watched by MaxClient.
+type HealthReport struct {
+	Time       time.Time
+	Healthy    bool
+	Components []Health
+}
+
+type healthTracker struct {
+	component   string
+	state       ConnState
+	everUp      bool
+	lastMessage time.Time
+	reconnects  int64
+	lastErr     string
+
+	windowStart time.Time
+	windowCount int64
+	rate        float64
+
+	sync.RWMutex
+}
+
+func newHealthTracker(component string) *healthTracker {
+	return &healthTracker{component: component, state: ConnStateConnecting, windowStart: time.Now()}
+}
+
+func (h *healthTracker) connecting() {
+	h.Lock()
+	defer h.Unlock()
+	h.state = ConnStateConnecting
+}
+
+func (h *healthTracker) connected() {
+	h.Lock()
+	defer h.Unlock()
+	if h.everUp {
+		h.reconnects++
+	}
+	h.everUp = true
+	h.state = ConnStateConnected
+}
+
+func (h *healthTracker) fail(err error) {
+	h.Lock()
+	defer h.Unlock()
+	if err != nil {
+		h.lastErr = err.Error()
+	}
+	if h.state != ConnStateClosed {
+		h.state = ConnStateDisconnected
+	}
+}
+
+func (h *healthTracker) closed() {
+	h.Lock()
+	defer h.Unlock()
+	h.state = ConnStateClosed
+}
+
+func (h *healthTracker) message() {
+	h.Lock()
+	defer h.Unlock()
+	now := time.Now()
+	h.lastMessage = now
+	h.windowCount++
+	h.rollWindow(now)
+}
+
+func (h *healthTracker) rollWindow(now time.Time) {
+	if elapsed := now.Sub(h.windowStart); elapsed >= healthRateWindow {
+		h.rate = float64(h.windowCount) / elapsed.Seconds()
+		h.windowStart = now
+		h.windowCount = 0
+	}
+}
+
+func (h *healthTracker) snapshot() Health {
+	h.Lock()
+	defer h.Unlock()
+	h.rollWindow(time.Now())
+	return Health{
+		Component:   h.component,
+		State:       h.state,
+		LastMessage: h.lastMessage,
+		Reconnects:  h.reconnects,
+		LastError:   h.lastErr,
+		MessageRate: h.rate,
+	}
+}
+
+// Health of the private trade report websocket.
+func (Mc *MaxClient) WsHealth() Health {
+	if Mc.WsClient.health == nil {
+		return Health{Component: "private websocket", State: ConnStateClosed}
+	}
+	return Mc.WsClient.health.snapshot()
+}
+
+// WatchHealth adds components, e.g. orderbooks and trade streams, to the aggregate health report.
+func (Mc *MaxClient) WatchHealth(components ...HealthReporter) {
+	Mc.HealthBranch.Lock()
+	defer Mc.HealthBranch.Unlock()
+	Mc.HealthBranch.components = append(Mc.HealthBranch.components, components...)
+}
+
+// HealthReport returns the health of the private websocket and every watched component.
+func (Mc *MaxClient) HealthReport() HealthReport {
+	Mc.HealthBranch.RLock()
+	components := Mc.HealthBranch.components
+	Mc.HealthBranch.RUnlock()
+
+	report := HealthReport{Time: time.Now(), Healthy: true}
+	report.Components = append(report.Components, Mc.WsHealth())
+	for _, c := range components {
+		report.Components = append(report.Components, c.Health())
+	}
+	for _, h := range report.Components {
+		if h.State != ConnStateConnected {
+			report.Healthy = false
+		}
+	}
+	return report
+}
+
+// SubscribeHealth calls fn with the aggregate health report every interval until ctx is done.
+func (Mc *MaxClient) SubscribeHealth(ctx context.Context, interval time.Duration, fn func(HealthReport)) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				fn(Mc.HealthReport())
+			}
+		}
+	}()
+}
@@ -0,0 +1,106 @@
+package max_RESTfulAPI
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/sirupsen/logrus"
+)
+
+// waitHealth polls the health of a component until ok accepts it.
+func waitHealth(t *testing.T, c HealthReporter, what string, ok func(Health) bool) Health {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		h := c.Health()
+		if ok(h) {
+			return h
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("not %s: %+v", what, h)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// test the health of a trade stream follows its frames through a drop, a reconnect and the shutdown
+func TestHealthTransitions(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	o := newTradeStreamBranch([]string{"btcusdt"}, TradeStreamConfig{}, logger)
+	Mc := &MaxClient{logger: logger}
+	Mc.WatchHealth(o)
+
+	step := make(chan struct{})
+	proceed := make(chan struct{})
+	e := newTestWsEngine(t, wsConfig{MinBackoff: 100 * time.Millisecond}, func(n int, conn *websocket.Conn) {
+		if _, _, err := conn.ReadMessage(); err != nil {
+			t.Error(err)
+			return
+		}
+		// dialed and subscribed, but not confirmed yet.
+		step <- struct{}{}
+		<-proceed
+		conn.WriteMessage(websocket.TextMessage, []byte(`{"c":"trade","e":"subscribed","s":[{"channel":"trade","market":"btcusdt"}],"T":1000}`))
+		conn.WriteMessage(websocket.TextMessage, tradeFrame("snapshot", int64(n)))
+		step <- struct{}{}
+		<-proceed
+	})
+	e.health = o.health
+	e.handle = o.handleMaxTradeSocketMsg
+	e.subscribe = func() ([][]byte, error) { return [][]byte{[]byte("sub")}, nil }
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		e.run(ctx)
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	<-step
+	if h := o.Health(); h.State != ConnStateConnecting || !h.LastMessage.IsZero() {
+		t.Fatalf("health before the subscribed frame %+v", h)
+	}
+	proceed <- struct{}{}
+	<-step
+	h := waitHealth(t, o, "connected", func(h Health) bool { return h.State == ConnStateConnected && len(o.GetTrades()) == 1 })
+	if h.Reconnects != 0 || h.LastMessage.IsZero() || !h.Fresh(time.Second) || !Mc.HealthReport().Components[1].Fresh(time.Second) {
+		t.Fatalf("health %+v", h)
+	}
+	// the private websocket of the client is not running.
+	if Mc.HealthReport().Healthy {
+		t.Fatal("healthy without the private websocket")
+	}
+
+	// the server drops the connection.
+	proceed <- struct{}{}
+	h = waitHealth(t, o, "disconnected", func(h Health) bool { return h.State == ConnStateDisconnected })
+	if h.LastError == "" || h.Fresh(time.Hour) {
+		t.Fatalf("health %+v", h)
+	}
+
+	// the reconnect is only counted once confirmed by the subscribed frame.
+	<-step
+	if h := o.Health(); h.State != ConnStateConnecting || h.Reconnects != 0 {
+		t.Fatalf("health while reconnecting %+v", h)
+	}
+	proceed <- struct{}{}
+	<-step
+	h = waitHealth(t, o, "reconnected", func(h Health) bool { return h.State == ConnStateConnected })
+	if h.Reconnects != 1 {
+		t.Fatalf("health %+v", h)
+	}
+
+	cancel()
+	<-done
+	if h := o.Health(); h.State != ConnStateClosed {
+		t.Fatalf("health after the shutdown %+v", h)
+	}
+	go func() { proceed <- struct{}{} }()
+}
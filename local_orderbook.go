@@ -9,21 +9,14 @@ import (
 	"sync"
 	"time"
 
-	"github.com/gorilla/websocket"
 	"github.com/sirupsen/logrus"
 	log "github.com/sirupsen/logrus"
 )
 
 type OrderbookBranch struct {
-	connBranch struct {
-		conn *websocket.Conn
-		sync.Mutex
-	}
+	engine *wsEngine
+	cancel context.CancelFunc
 
-	onErrBranch struct {
-		onErr bool
-		sync.RWMutex
-	}
 	keeper OrderBookKeeper
 
 	Market string
@@ -34,6 +27,9 @@ type OrderbookBranch struct {
 	}
 
 	logger *log.Logger
+	health *healthTracker
+	// clock of the staleness check, the virtual clock under replay. Default to time.Now.
+	now func() time.Time
 }
 
 type bookstruct struct {
@@ -45,98 +41,28 @@ type bookstruct struct {
 	Timestamp int64               `json:"T,omitempty"`
 }
 
+// the book is out of sync and has to be re-snapshotted by reconnecting.
+var errBookResync = errors.New("orderbook out of sync")
+
 func SpotLocalOrderbook(ctx context.Context, symbol string, logger *logrus.Logger) *OrderbookBranch {
 	var o OrderbookBranch
 	o.Market = strings.ToLower(symbol)
 	o.logger = logger
-
-	go o.maintain(ctx, symbol)
-
-	go func() {
-		for {
-			select {
-			case <-ctx.Done():
-				return
-			default:
-				time.Sleep(60 * time.Second)
-				message := []byte("ping")
-				o.wsWriteMsg(websocket.PingMessage, message)
-			}
+	o.health = newHealthTracker("orderbook " + o.Market)
+
+	ctx, o.cancel = context.WithCancel(ctx)
+	o.engine = newWsEngine("orderbook "+o.Market, wsConfig{}, logger, o.health)
+	o.engine.subscribe = func() ([][]byte, error) {
+		subMsg, err := maxSubscribeBookMessage(o.Market)
+		if err != nil {
+			return nil, errors.New("❌ fail to construct subscribtion message")
 		}
-	}()
-	return &o
-}
-
-func (o *OrderbookBranch) maintain(ctx context.Context, symbol string) {
-	o.wsOnErrTurn(false)
-	duration := time.Second * 30
-	var url string = "wss://max-stream.maicoin.com/ws"
-
-	// wait 5 second, if the hand shake fail, will terminate the dail
-	dailCtx, _ := context.WithDeadline(ctx, time.Now().Add(time.Second*5))
-	conn, _, err := websocket.DefaultDialer.DialContext(dailCtx, url, nil)
-	if err != nil {
-		log.Print("❌ local orderbook dial:", err)
-		defer o.maintain(ctx, symbol)
-		time.Sleep(1 * time.Second)
-		return
-	}
-
-	o.setConn(conn)
-
-	subMsg, err := maxSubscribeBookMessage(symbol)
-	if err != nil {
-		log.Print(errors.New("❌ fail to construct subscribtion message"))
-		o.wsOnErrTurn(true)
-	}
-
-	o.wsWriteMsg(websocket.TextMessage, subMsg)
-	if err != nil {
-		o.wsOnErrTurn(true)
-		log.Print(errors.New("❌ fail to subscribe websocket"))
+		return [][]byte{subMsg}, nil
 	}
+	o.engine.handle = o.handleMaxBookSocketMsg
 
-	time.Sleep(time.Second)
-
-mainloop:
-	for {
-		select {
-		case <-ctx.Done():
-			o.Close()
-			return
-		default:
-			if o.isWsOnErr() {
-				break mainloop
-			}
-
-			_, msg, err := o.connBranch.conn.ReadMessage()
-
-			if err != nil {
-				log.Print("❌ orderbook maintain read:", err)
-				o.wsOnErrTurn(true)
-				time.Sleep(time.Second)
-				break mainloop
-			}
-			o.connBranch.conn.SetReadDeadline(time.Now().Add(duration))
-
-			errh := o.handleMaxBookSocketMsg(msg)
-			if errh != nil {
-				//log.Println("❌ orderbook maintain handle:", errh)
-				o.wsOnErrTurn(true)
-				time.Sleep(time.Second)
-				break mainloop
-			}
-		} // end select
-		time.Sleep(time.Millisecond)
-	} // end for
-
-	o.Close()
-
-	if !o.isWsOnErr() {
-		return
-	}
-	time.Sleep(500 * time.Millisecond)
-	o.maintain(ctx, symbol)
+	go o.engine.run(ctx)
+	return &o
 }
 
 // default for the depth 10 (max).
@@ -178,6 +104,7 @@ func (o *OrderbookBranch) handleMaxBookSocketMsg(msg []byte) error {
 	switch event {
 	case "subscribed":
 		log.Println("✅ Max", o.Market, "orderbook websocket connected.")
+		o.health.connected()
 	case "snapshot":
 		err2 = o.parseOrderbookSnapshotMsg(msgMap)
 	case "update":
@@ -209,7 +136,7 @@ func (o *OrderbookBranch) parseOrderbookUpdateMsg(msgMap map[string]interface{})
 
 	wrongTime := false
 	o.lastUpdatedTimestampBranch.Lock()
-	if time.Now().UnixMilli()-book.Timestamp > 5000 {
+	if o.clock().UnixMilli()-book.Timestamp > 5000 {
 		o.lastUpdatedTimestampBranch.timestamp = book.Timestamp
 		wrongTime = true
 	} else if book.Timestamp < o.lastUpdatedTimestampBranch.timestamp {
@@ -220,8 +147,7 @@ func (o *OrderbookBranch) parseOrderbookUpdateMsg(msgMap map[string]interface{})
 	o.lastUpdatedTimestampBranch.Unlock()
 
 	if wrongTime {
-		o.wsOnErrTurn(true)
-		return nil
+		return errBookResync
 	}
 
 	// update
@@ -246,48 +172,156 @@ func (o *OrderbookBranch) parseOrderbookSnapshotMsg(msgMap map[string]interface{
 	if book.Market != o.Market {
 		return errors.New("wrong market")
 	}
-	// snapshot
+	// snapshot, under the timestamp lock so a concurrent Bootstrap can't overwrite it.
+	o.lastUpdatedTimestampBranch.Lock()
+	defer o.lastUpdatedTimestampBranch.Unlock()
 	if err := o.keeper.handleSnapshot(book.Bids, book.Asks); err != nil {
 		return err
 	}
+	o.lastUpdatedTimestampBranch.timestamp = book.Timestamp
+	return nil
+}
+
+// Bootstrap seeds the book from the REST depth, so it is readable before the websocket snapshot arrives.
+// It does nothing once the websocket snapshot is in.
+func (o *OrderbookBranch) Bootstrap(ctx context.Context, api *APIClient) error {
+	depth, _, err := api.PublicApi.GetApiV2Depth(ctx, o.Market, 50)
+	if err != nil {
+		return err
+	}
 
 	o.lastUpdatedTimestampBranch.Lock()
 	defer o.lastUpdatedTimestampBranch.Unlock()
-	o.lastUpdatedTimestampBranch.timestamp = book.Timestamp
+	if o.lastUpdatedTimestampBranch.timestamp != 0 {
+		return nil
+	}
+	if err := o.keeper.SeedFromDepth(depth); err != nil {
+		return err
+	}
+	// depth is in seconds, any websocket update is newer than it.
+	o.lastUpdatedTimestampBranch.timestamp = depth.Timestamp * 1000
 	return nil
 }
 
-func (o *OrderbookBranch) wsOnErrTurn(b bool) {
-	o.onErrBranch.Lock()
-	defer o.onErrBranch.Unlock()
-	o.onErrBranch.onErr = b
+// BookCheckConfig configures the periodic REST vs local book consistency check.
+type BookCheckConfig struct {
+	// default to 1 minute.
+	Interval time.Duration
+	// max relative difference of best bid and best ask to the REST depth, default to 0.005.
+	Tolerance decimal.Decimal
+	// drifted checks in a row before resyncing, default to 2 as the two books are never taken at the same time.
+	Consecutive int
+	// called when the book is resynced because of drift.
+	OnDrift func(market string, reason string)
+}
+
+// RunConsistencyCheck periodically compares the local book to the REST depth and
+// resyncs the websocket when they drift apart.
+func (o *OrderbookBranch) RunConsistencyCheck(ctx context.Context, api *APIClient, cfg BookCheckConfig) {
+	if cfg.Interval <= 0 {
+		cfg.Interval = time.Minute
+	}
+	if !cfg.Tolerance.IsPositive() {
+		cfg.Tolerance = decimal.NewFromFloat(0.005)
+	}
+	if cfg.Consecutive <= 0 {
+		cfg.Consecutive = 2
+	}
+
+	go func() {
+		ticker := time.NewTicker(cfg.Interval)
+		defer ticker.Stop()
+		drifted := 0
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				depth, _, err := api.PublicApi.GetApiV2Depth(ctx, o.Market, 1)
+				if err != nil {
+					o.logger.Warn("fail to get depth for consistency check: ", err)
+					continue
+				}
+				reason, ok := o.checkDepth(depth, cfg.Tolerance)
+				if ok {
+					drifted = 0
+					continue
+				}
+				drifted++
+				if drifted < cfg.Consecutive {
+					continue
+				}
+				drifted = 0
+				o.logger.Warnf("%s orderbook drifted: %s, resyncing", o.Market, reason)
+				if cfg.OnDrift != nil {
+					cfg.OnDrift(o.Market, reason)
+				}
+				o.RefreshOrderBook()
+			}
+		}
+	}()
+}
+
+// checkDepth compares the top of the local book to the REST depth.
+func (o *OrderbookBranch) checkDepth(depth Depth, tolerance decimal.Decimal) (reason string, ok bool) {
+	bids, asks := o.keeper.Get()
+	if len(bids) == 0 || len(asks) == 0 {
+		return "local book is empty", false
+	}
+	if bids[0][0].GreaterThanOrEqual(asks[0][0]) {
+		return fmt.Sprintf("local book is crossed, bid %s ask %s", bids[0][0], asks[0][0]), false
+	}
+	if len(depth.Bids) == 0 || len(depth.Asks) == 0 {
+		// nothing to compare with.
+		return "", true
+	}
+	if !withinTolerance(bids[0][0], depth.Bids[0].Price, tolerance) {
+		return fmt.Sprintf("best bid %s, REST %s", bids[0][0], depth.Bids[0].Price), false
+	}
+	if !withinTolerance(asks[0][0], depth.Asks[0].Price, tolerance) {
+		return fmt.Sprintf("best ask %s, REST %s", asks[0][0], depth.Asks[0].Price), false
+	}
+	return "", true
 }
 
-func (o *OrderbookBranch) isWsOnErr() (onErr bool) {
-	o.onErrBranch.RLock()
-	defer o.onErrBranch.RUnlock()
-	onErr = o.onErrBranch.onErr
-	return
+func withinTolerance(local, remote, tolerance decimal.Decimal) bool {
+	if !remote.IsPositive() {
+		return true
+	}
+	return local.Sub(remote).Abs().Div(remote).LessThanOrEqual(tolerance)
 }
 
-func (o *OrderbookBranch) setConn(conn *websocket.Conn) {
-	o.connBranch.Lock()
-	defer o.connBranch.Unlock()
-	o.connBranch.conn = conn
+func (o *OrderbookBranch) clock() time.Time {
+	if o.now != nil {
+		return o.now()
+	}
+	return time.Now()
 }
 
+// Close stops the orderbook websocket.
 func (o *OrderbookBranch) Close() {
-	o.connBranch.Lock()
-	defer o.connBranch.Unlock()
-	o.connBranch.conn.Close()
+	if o.cancel != nil {
+		o.cancel()
+	}
+}
+
+// LastUpdated is the exchange time of the last applied snapshot or update.
+func (o *OrderbookBranch) LastUpdated() time.Time {
+	o.lastUpdatedTimestampBranch.RLock()
+	defer o.lastUpdatedTimestampBranch.RUnlock()
+	return time.UnixMilli(o.lastUpdatedTimestampBranch.timestamp)
 }
 
-func (o *OrderbookBranch) wsWriteMsg(msgType int, data []byte) {
-	o.connBranch.Lock()
-	defer o.connBranch.Unlock()
-	o.connBranch.conn.WriteMessage(msgType, data)
+// Health of the orderbook websocket.
+func (o *OrderbookBranch) Health() Health {
+	return o.health.snapshot()
 }
 
+// RefreshOrderBook drops the websocket connection, the book is re-snapshotted on reconnect.
 func (o *OrderbookBranch) RefreshOrderBook() error {
+	if o.engine == nil {
+		return errWsNotConnected
+	}
+	o.engine.reconnect()
 	return nil
 }
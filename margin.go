@@ -0,0 +1,259 @@
+package max_RESTfulAPI
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/sirupsen/logrus"
+)
+
+// Borrow borrows amount of currency into the M-wallet.
+func (Mc *MaxClient) Borrow(currency string, amount decimal.Decimal) (Loan, error) {
+	loan, _, err := Mc.ApiClient.PrivateApi.PostApiV3WalletMLoan(context.Background(), Mc.signer, NormalizeCurrency(currency), amount.String())
+	if err != nil {
+		return Loan{}, fmt.Errorf("fail to borrow %s %s: %w", amount, currency, err)
+	}
+	Mc.logger.Infof("Borrow %s %s into M-wallet.", amount, currency)
+	return loan, nil
+}
+
+// Repay repays amount of currency of the M-wallet loans, interest first.
+func (Mc *MaxClient) Repay(currency string, amount decimal.Decimal) (Repayment, error) {
+	repayment, _, err := Mc.ApiClient.PrivateApi.PostApiV3WalletMRepayment(context.Background(), Mc.signer, NormalizeCurrency(currency), amount.String())
+	if err != nil {
+		return Repayment{}, fmt.Errorf("fail to repay %s %s: %w", amount, currency, err)
+	}
+	Mc.logger.Infof("Repay %s %s of M-wallet.", amount, currency)
+	return repayment, nil
+}
+
+// GetLoans gets the latest loans of a currency.
+func (Mc *MaxClient) GetLoans(currency string) ([]Loan, error) {
+	loans, _, err := Mc.ApiClient.PrivateApi.GetApiV3WalletMLoans(context.Background(), Mc.signer, NormalizeCurrency(currency), nil)
+	if err != nil {
+		return []Loan{}, err
+	}
+	return loans, nil
+}
+
+// GetRepayments gets the latest repayments of a currency.
+func (Mc *MaxClient) GetRepayments(currency string) ([]Repayment, error) {
+	repayments, _, err := Mc.ApiClient.PrivateApi.GetApiV3WalletMRepayments(context.Background(), Mc.signer, NormalizeCurrency(currency), nil)
+	if err != nil {
+		return []Repayment{}, err
+	}
+	return repayments, nil
+}
+
+// GetInterests gets the latest interests charged on a currency.
+func (Mc *MaxClient) GetInterests(currency string) ([]Interest, error) {
+	interests, _, err := Mc.ApiClient.PrivateApi.GetApiV3WalletMInterests(context.Background(), Mc.signer, NormalizeCurrency(currency), nil)
+	if err != nil {
+		return []Interest{}, err
+	}
+	return interests, nil
+}
+
+// GetMaxBorrowable gets how much of every currency can still be borrowed.
+func (Mc *MaxClient) GetMaxBorrowable() (map[string]decimal.Decimal, error) {
+	limits, _, err := Mc.ApiClient.PrivateApi.GetApiV3WalletMLimits(context.Background(), Mc.signer)
+	if err != nil {
+		return map[string]decimal.Decimal{}, err
+	}
+	return limits, nil
+}
+
+// GetADRatio gets the current AD ratio of the M-wallet.
+func (Mc *MaxClient) GetADRatio() (ADRatio, error) {
+	ratio, _, err := Mc.ApiClient.PrivateApi.GetApiV3WalletMAdRatio(context.Background(), Mc.signer)
+	if err != nil {
+		return ADRatio{}, err
+	}
+	return ratio, nil
+}
+
+// ADLevel is how close the M-wallet is to liquidation.
+type ADLevel int
+
+const (
+	ADLevelSafe ADLevel = iota
+	ADLevelWarn
+	ADLevelDeleverage
+)
+
+func (l ADLevel) String() string {
+	switch l {
+	case ADLevelWarn:
+		return "warn"
+	case ADLevelDeleverage:
+		return "deleverage"
+	default:
+		return "safe"
+	}
+}
+
+type ADRatioConfig struct {
+	// alert when the AD ratio falls below, e.g. 1.5.
+	WarnBelow decimal.Decimal
+	// deleverage when the AD ratio falls below, e.g. 1.25. MAX liquidates at a lower ratio.
+	DeleverageBelow decimal.Decimal
+	// time between two deleverage calls while the ratio stays low, default to 1 minute.
+	DeleverageCooldown time.Duration
+
+	// called whenever the level changes.
+	OnAlert func(ratio ADRatio, level ADLevel)
+	// reduces the debt, e.g. cancels orders, sells collateral and repays. Only logged if nil.
+	// It runs on its own goroutine, one call at a time, so it may take as long as it needs.
+	Deleverage func(ratio ADRatio) error
+}
+
+// ADRatioMonitor follows the AD ratio of the M-wallet from the private websocket.
+type ADRatioMonitor struct {
+	Config ADRatioConfig
+
+	engine *wsEngine
+	cancel context.CancelFunc
+
+	ratioBranch struct {
+		ratio          ADRatio
+		level          ADLevel
+		updatedAt      time.Time
+		lastDeleverage time.Time
+		// a Deleverage call is running.
+		deleveraging bool
+		sync.RWMutex
+	}
+
+	logger *logrus.Logger
+	health *healthTracker
+}
+
+type adRatioMsg struct {
+	Event string `json:"e"`
+	AD    struct {
+		ADRatio     decimal.Decimal `json:"ad"`
+		AssetInUsdt decimal.Decimal `json:"as"`
+		DebtInUsdt  decimal.Decimal `json:"db"`
+	} `json:"ad"`
+}
+
+// MonitorADRatio starts following the AD ratio, seeded from the REST api.
+func (Mc *MaxClient) MonitorADRatio(ctx context.Context, cfg ADRatioConfig) *ADRatioMonitor {
+	if cfg.DeleverageCooldown <= 0 {
+		cfg.DeleverageCooldown = time.Minute
+	}
+	m := &ADRatioMonitor{Config: cfg, logger: Mc.logger, health: newHealthTracker("ad ratio")}
+
+	if ratio, err := Mc.GetADRatio(); err != nil {
+		Mc.logger.Warn("fail to get AD ratio: ", err)
+	} else {
+		m.update(ratio)
+	}
+
+	ctx, m.cancel = context.WithCancel(ctx)
+	m.engine = newWsEngine("ad ratio", wsConfig{PingInterval: time.Minute, ReadTimeout: 5 * time.Minute}, Mc.logger, m.health)
+	m.engine.subscribe = func() ([][]byte, error) {
+		subMsg, err := privateAuthMessage(Mc.signer, Mc.ApiClient.Nonce, "ad_ratio")
+		if err != nil {
+			return nil, fmt.Errorf("❌ fail to construct subscribtion message: %w", err)
+		}
+		return [][]byte{subMsg}, nil
+	}
+	m.engine.handle = m.handleADRatioMsg
+
+	go m.engine.run(ctx)
+	return m
+}
+
+func (m *ADRatioMonitor) handleADRatioMsg(msg []byte) error {
+	var adMsg adRatioMsg
+	if err := json.Unmarshal(msg, &adMsg); err != nil {
+		return errors.New("fail to unmarshal message")
+	}
+
+	switch adMsg.Event {
+	case "authenticated":
+		m.logger.Info("✅ MAX AD ratio websocket connected")
+		m.health.connected()
+	case "ad_ratio_snapshot", "ad_ratio_update":
+		m.update(ADRatio(adMsg.AD))
+	case "error":
+		return fmt.Errorf("ad ratio websocket error: %s", msg)
+	}
+	return nil
+}
+
+// update records the ratio, alerts on level changes and deleverages when needed.
+func (m *ADRatioMonitor) update(ratio ADRatio) {
+	level := m.levelOf(ratio)
+
+	m.ratioBranch.Lock()
+	prev := m.ratioBranch.level
+	m.ratioBranch.ratio = ratio
+	m.ratioBranch.level = level
+	m.ratioBranch.updatedAt = time.Now()
+	deleverage := level == ADLevelDeleverage && !m.ratioBranch.deleveraging && time.Since(m.ratioBranch.lastDeleverage) >= m.Config.DeleverageCooldown
+	if deleverage {
+		m.ratioBranch.lastDeleverage = time.Now()
+		m.ratioBranch.deleveraging = m.Config.Deleverage != nil
+	}
+	m.ratioBranch.Unlock()
+
+	if level != prev {
+		m.logger.Warnf("M-wallet AD ratio %s, level %s -> %s", ratio.ADRatio, prev, level)
+		if m.Config.OnAlert != nil {
+			m.Config.OnAlert(ratio, level)
+		}
+	}
+
+	if deleverage {
+		if m.Config.Deleverage == nil {
+			m.logger.Errorf("M-wallet AD ratio %s below %s, no deleverage configured", ratio.ADRatio, m.Config.DeleverageBelow)
+			return
+		}
+		// off the websocket goroutine, the ratio keeps updating while it runs.
+		go func() {
+			if err := m.Config.Deleverage(ratio); err != nil {
+				m.logger.Error("fail to deleverage: ", err)
+			}
+			m.ratioBranch.Lock()
+			m.ratioBranch.deleveraging = false
+			m.ratioBranch.Unlock()
+		}()
+	}
+}
+
+func (m *ADRatioMonitor) levelOf(ratio ADRatio) ADLevel {
+	// without debt there is nothing to liquidate.
+	if !ratio.DebtInUsdt.IsPositive() {
+		return ADLevelSafe
+	}
+	switch {
+	case m.Config.DeleverageBelow.IsPositive() && ratio.ADRatio.LessThan(m.Config.DeleverageBelow):
+		return ADLevelDeleverage
+	case m.Config.WarnBelow.IsPositive() && ratio.ADRatio.LessThan(m.Config.WarnBelow):
+		return ADLevelWarn
+	default:
+		return ADLevelSafe
+	}
+}
+
+// Ratio returns the last AD ratio, its level and when it was received.
+func (m *ADRatioMonitor) Ratio() (ADRatio, ADLevel, time.Time) {
+	m.ratioBranch.RLock()
+	defer m.ratioBranch.RUnlock()
+	return m.ratioBranch.ratio, m.ratioBranch.level, m.ratioBranch.updatedAt
+}
+
+func (m *ADRatioMonitor) Close() {
+	m.cancel()
+}
+
+// Health of the AD ratio websocket.
+func (m *ADRatioMonitor) Health() Health {
+	return m.health.snapshot()
+}
@@ -0,0 +1,117 @@
+package max_RESTfulAPI
+
+import (
+	"io"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/sirupsen/logrus"
+)
+
+// test the AD ratio monitor alerts on level changes and deleverages below the threshold
+func TestADRatioMonitorLevels(t *testing.T) {
+	var alerts []ADLevel
+	var deleverages int32
+	m := &ADRatioMonitor{
+		Config: ADRatioConfig{
+			WarnBelow:       decimal.NewFromFloat(1.5),
+			DeleverageBelow: decimal.NewFromFloat(1.25),
+			OnAlert:         func(_ ADRatio, level ADLevel) { alerts = append(alerts, level) },
+			Deleverage:      func(ADRatio) error { atomic.AddInt32(&deleverages, 1); return nil },
+		},
+		logger: logrus.New(),
+		health: newHealthTracker("ad ratio"),
+	}
+	m.Config.DeleverageCooldown = time.Hour
+
+	feed := func(ratio string) {
+		m.handleADRatioMsg([]byte(`{"c":"user","e":"ad_ratio_update","ad":{"ad":"` + ratio + `","as":"1000","db":"500"}}`))
+	}
+	feed("2")
+	feed("1.4")
+	feed("1.1")
+	feed("1.0")
+	feed("1.6")
+
+	want := []ADLevel{ADLevelWarn, ADLevelDeleverage, ADLevelSafe}
+	if len(alerts) != len(want) {
+		t.Fatalf("alerts %v, want %v", alerts, want)
+	}
+	for i := range want {
+		if alerts[i] != want[i] {
+			t.Fatalf("alerts %v, want %v", alerts, want)
+		}
+	}
+	// the second low ratio is within the cooldown.
+	waitDeleveraged(t, m)
+	if n := atomic.LoadInt32(&deleverages); n != 1 {
+		t.Fatalf("deleveraged %d times", n)
+	}
+	if ratio, level, _ := m.Ratio(); level != ADLevelSafe || !ratio.ADRatio.Equal(decimal.NewFromFloat(1.6)) {
+		t.Fatalf("ratio %v level %v", ratio, level)
+	}
+}
+
+// waitDeleveraged waits for the running Deleverage call of m to return.
+func waitDeleveraged(t *testing.T, m *ADRatioMonitor) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		m.ratioBranch.RLock()
+		running := m.ratioBranch.deleveraging
+		m.ratioBranch.RUnlock()
+		if !running {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("deleverage still running")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// test a slow deleverage doesn't hold the ratio updates, and runs once at a time
+func TestADRatioMonitorSlowDeleverage(t *testing.T) {
+	var calls int32
+	release := make(chan struct{})
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	m := &ADRatioMonitor{
+		Config: ADRatioConfig{
+			DeleverageBelow:    decimal.NewFromFloat(1.25),
+			DeleverageCooldown: time.Nanosecond,
+			Deleverage: func(ADRatio) error {
+				atomic.AddInt32(&calls, 1)
+				<-release
+				return nil
+			},
+		},
+		logger: logger,
+		health: newHealthTracker("ad ratio"),
+	}
+	feed := func(ratio string) {
+		m.handleADRatioMsg([]byte(`{"c":"user","e":"ad_ratio_update","ad":{"ad":"` + ratio + `","as":"1000","db":"500"}}`))
+	}
+
+	feed("1.1")
+	feed("1.0")
+	feed("0.9")
+	if ratio, _, _ := m.Ratio(); !ratio.ADRatio.Equal(decimal.NewFromFloat(0.9)) {
+		t.Fatalf("ratio %v", ratio)
+	}
+	close(release)
+	waitDeleveraged(t, m)
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Fatalf("deleveraged %d times while running", n)
+	}
+
+	// past the cooldown, the next low ratio deleverages again.
+	time.Sleep(time.Millisecond)
+	feed("1.0")
+	waitDeleveraged(t, m)
+	if n := atomic.LoadInt32(&calls); n != 2 {
+		t.Fatalf("deleveraged %d times", n)
+	}
+}
@@ -13,24 +13,36 @@ import (
 var json = jsoniter.ConfigCompatibleWithStandardLibrary
 
 func NewMaxClient(ctx context.Context, APIKEY, APISECRET string, logger *logrus.Logger) *MaxClient {
+	return NewMaxClientWithSigner(ctx, NewHMACSigner(APIKEY, APISECRET), logger)
+}
+
+// NewMaxClientWithSigner creates a client whose requests are signed by signer, so the api secret may live outside the process.
+func NewMaxClientWithSigner(ctx context.Context, signer Signer, logger *logrus.Logger) *MaxClient {
 	// api client
 	cfg := NewConfiguration()
 	apiclient := NewAPIClient(cfg)
+	apiclient.Nonce.StartSync(ctx, apiclient, 10*time.Minute, logger)
 
 	// Get markets []Market
 	markets, _, err := apiclient.PublicApi.GetApiV2Markets(ctx)
 	if err != nil {
 		logger.Error(err)
 	}
+	// Get currencies []Currency
+	currencies, _, err := apiclient.PublicApi.GetApiV2Currencies(ctx)
+	if err != nil {
+		logger.Error(err)
+	}
 	m := MaxClient{}
-	m.apiKey = APIKEY
-	m.apiSecret = APISECRET
+	m.signer = signer
 	_, cancel := context.WithCancel(ctx)
 	m.cancelFunc = &cancel
 	m.ShutingBranch.shut = false
 	m.ApiClient = apiclient
-	m.MarketsBranch.Markets = markets
+	m.MarketsBranch.Markets = normalizeMarkets(markets)
+	m.setCurrencies(currencies)
 	m.logger = logger
+	m.WsClient.health = newHealthTracker("private websocket")
 
 	return &m
 }
@@ -2,21 +2,16 @@ package max_RESTfulAPI
 
 import (
 	"context"
-	"fmt"
-	"io/ioutil"
 	"net/http"
-	"net/url"
-	"strings"
-	"time"
+
+	"github.com/shopspring/decimal"
 )
 
 /*
 PrivateApiService
 create a sell/buy order
 * @param ctx context.Context for authentication, logging, tracing, etc.
-@param xMAXACCESSKEY access key
-@param xMAXPAYLOAD encoded payload
-@param xMAXSIGNATURE encrypted signature
+@param signer signs the payload with the api key, see Signer
 @param market unique market id, check /api/v2/markets for available markets
 @param side &#39;sell&#39; or &#39;buy&#39;
 @param volume total amount to sell/buy, an order could be partially executed
@@ -28,101 +23,19 @@ create a sell/buy order
 
 @return Order
 */
-func (a *PrivateApiService) PostApiV2Orders(ctx context.Context, xMAXACCESSKEY string, xMAXSECRET string, market string, side string, volume string, localVarOptionals map[string]interface{}) (Order, *http.Response, error) {
-	var (
-		localVarHTTPMethod = strings.ToUpper("Post")
-		localVarFileName   string
-		localVarFileBytes  []byte
-		successPayload     Order
-	)
-
-	// create path and map variables
-	localVarPath := a.client.cfg.BasePath + "/api/v2/orders"
-
-	localVarHeaderParams := make(map[string]string)
-	localVarQueryParams := url.Values{}
-	localVarFormParams := url.Values{}
-	localVarPostBody := make(map[string]interface{})
-	localVarPostBody["nonce"] = time.Now().UnixMilli()
-	localVarPostBody["path"] = "/api/v2/orders"
-
-	if err := typeCheckParameter(localVarOptionals["price"], "string", "price"); err != nil {
-		return successPayload, nil, err
-	}
-	if err := typeCheckParameter(localVarOptionals["stop_price"], "string", "stop_price"); err != nil {
-		return successPayload, nil, err
-	}
-	if err := typeCheckParameter(localVarOptionals["ord_type"], "string", "ord_type"); err != nil {
-		return successPayload, nil, err
-	}
-
-	// to determine the Content-Type header
-	localVarHTTPContentTypes := []string{"application/json"}
-
-	// set Content-Type header
-	localVarHTTPContentType := selectHeaderContentType(localVarHTTPContentTypes)
-	if localVarHTTPContentType != "" {
-		localVarHeaderParams["Content-Type"] = localVarHTTPContentType
-	}
-
-	// to determine the Accept header
-	localVarHTTPHeaderAccepts := []string{
-		"application/json",
-	}
-
-	// set Accept header
-	localVarHTTPHeaderAccept := selectHeaderAccept(localVarHTTPHeaderAccepts)
-	if localVarHTTPHeaderAccept != "" {
-		localVarHeaderParams["Accept"] = localVarHTTPHeaderAccept
-	}
-
-	localVarPostBody["market"] = parameterToString(market, "")
-	localVarPostBody["side"] = parameterToString(side, "")
-	localVarPostBody["volume"] = parameterToString(volume, "")
-	if localVarTempParam, localVarOk := localVarOptionals["price"].(string); localVarOk {
-		localVarPostBody["price"] = parameterToString(localVarTempParam, "")
-	}
-	if localVarTempParam, localVarOk := localVarOptionals["stop_price"].(string); localVarOk {
-		localVarPostBody["stop_price"] = parameterToString(localVarTempParam, "")
-	}
-	if localVarTempParam, localVarOk := localVarOptionals["ord_type"].(string); localVarOk {
-		localVarPostBody["ord_type"] = parameterToString(localVarTempParam, "")
-	}
-
-	xMAXPAYLOAD, xMAXSIGNATURE := makePayloadAndSignature(localVarPostBody, xMAXSECRET)
-	localVarHeaderParams["X-MAX-ACCESSKEY"] = parameterToString(xMAXACCESSKEY, "")
-	localVarHeaderParams["X-MAX-PAYLOAD"] = parameterToString(xMAXPAYLOAD, "")
-	localVarHeaderParams["X-MAX-SIGNATURE"] = parameterToString(xMAXSIGNATURE, "")
-
-	r, err := a.client.prepareRequest(ctx, localVarPath, localVarHTTPMethod, localVarPostBody, localVarHeaderParams, localVarQueryParams, localVarFormParams, localVarFileName, localVarFileBytes)
-	if err != nil {
-		return successPayload, nil, err
-	}
-
-	localVarHTTPResponse, err := a.client.callAPI(r)
-	if err != nil || localVarHTTPResponse == nil {
-		return successPayload, localVarHTTPResponse, err
-	}
-	defer localVarHTTPResponse.Body.Close()
-	if localVarHTTPResponse.StatusCode >= 300 {
-		bodyBytes, _ := ioutil.ReadAll(localVarHTTPResponse.Body)
-		return successPayload, localVarHTTPResponse, reportError("Status: %v, Body: %s", localVarHTTPResponse.Status, bodyBytes)
-	}
-
-	if err = json.NewDecoder(localVarHTTPResponse.Body).Decode(&successPayload); err != nil {
-		return successPayload, localVarHTTPResponse, err
-	}
-
-	return successPayload, localVarHTTPResponse, err
+func (a *PrivateApiService) PostApiV2Orders(ctx context.Context, signer Signer, market string, side string, volume string, localVarOptionals map[string]interface{}) (Order, *http.Response, error) {
+	var successPayload Order
+	params := map[string]interface{}{"market": market, "side": side, "volume": volume}
+	copyOptionals(params, localVarOptionals, "price", "stop_price", "ord_type", "client_oid")
+	resp, err := a.client.DoPrivate(ctx, signer, "POST", "/api/v2/orders", params, &successPayload)
+	return successPayload, resp, err
 }
 
 /*
 PrivateApiService create multiple sell/buy orders
 create multiple sell/buy orders, please put your orders as an array in json body
 * @param ctx context.Context for authentication, logging, tracing, etc.
-@param xMAXACCESSKEY access key
-@param xMAXPAYLOAD encoded payload
-@param xMAXSIGNATURE encrypted signature
+@param signer signs the payload with the api key, see Signer
 @param market unique market id, check /api/v2/markets for available markets
 @param ordersSide &#39;sell&#39; or &#39;buy&#39;
 @param ordersVolume total amount to sell/buy, an order could be partially executed
@@ -134,82 +47,20 @@ create multiple sell/buy orders, please put your orders as an array in json body
 
 @return []Order
 */
-func (a *PrivateApiService) PostApiV2OrdersMulti(ctx context.Context, xMAXACCESSKEY string, xMAXSECRET string, market string, ordersSide []string, ordersVolume []string, localVarOptionals map[string]interface{}) ([]Order, *http.Response, error) {
-	var (
-		localVarHTTPMethod = strings.ToUpper("Post")
-		localVarFileName   string
-		localVarFileBytes  []byte
-		successPayload     []Order
-	)
-
-	// create path and map variables
-	localVarPath := a.client.cfg.BasePath + "/api/v2/orders/multi/onebyone"
-
-	localVarHeaderParams := make(map[string]string)
-	localVarQueryParams := url.Values{}
-	localVarFormParams := url.Values{}
-	localVarPostBody := make(map[string]interface{})
-	localVarPostBody["nonce"] = time.Now().UnixMilli()
-	localVarPostBody["path"] = "/api/v2/orders/multi/onebyone"
-
-	// to determine the Content-Type header
-	localVarHTTPContentTypes := []string{"application/json"}
-
-	// set Content-Type header
-	localVarHTTPContentType := selectHeaderContentType(localVarHTTPContentTypes)
-	if localVarHTTPContentType != "" {
-		localVarHeaderParams["Content-Type"] = localVarHTTPContentType
-	}
-
-	// to determine the Accept header
-	localVarHTTPHeaderAccepts := []string{
-		"application/json",
-	}
-
-	// set Accept header
-	localVarHTTPHeaderAccept := selectHeaderAccept(localVarHTTPHeaderAccepts)
-	if localVarHTTPHeaderAccept != "" {
-		localVarHeaderParams["Accept"] = localVarHTTPHeaderAccept
-	}
-
-	localVarPostBody["market"] = parameterToString(market, "")
-	localVarPostBody["orders[side]"] = parameterToString(ordersSide, "csv")
-	localVarPostBody["orders[volume]"] = parameterToString(ordersVolume, "csv")
-	if localVarTempParam, localVarOk := localVarOptionals["orders[price]"].([]string); localVarOk {
-		localVarPostBody["orders[price]"] = parameterToString(localVarTempParam, "csv")
-	}
-	if localVarTempParam, localVarOk := localVarOptionals["orders[stop_price]"].([]string); localVarOk {
-		localVarPostBody["orders[stop_price]"] = parameterToString(localVarTempParam, "csv")
-	}
-	if localVarTempParam, localVarOk := localVarOptionals["orders[ord_type]"].([]string); localVarOk {
-		localVarPostBody["orders[ord_type]"] = parameterToString(localVarTempParam, "csv")
-	}
-
-	xMAXPAYLOAD, xMAXSIGNATURE := makePayloadAndSignature(localVarPostBody, xMAXSECRET)
-	localVarHeaderParams["X-MAX-ACCESSKEY"] = parameterToString(xMAXACCESSKEY, "")
-	localVarHeaderParams["X-MAX-PAYLOAD"] = parameterToString(xMAXPAYLOAD, "")
-	localVarHeaderParams["X-MAX-SIGNATURE"] = parameterToString(xMAXSIGNATURE, "")
-
-	r, err := a.client.prepareRequest(ctx, localVarPath, localVarHTTPMethod, localVarPostBody, localVarHeaderParams, localVarQueryParams, localVarFormParams, localVarFileName, localVarFileBytes)
-	if err != nil {
-		return successPayload, nil, err
-	}
-
-	localVarHTTPResponse, err := a.client.callAPI(r)
-	if err != nil || localVarHTTPResponse == nil {
-		return successPayload, localVarHTTPResponse, err
-	}
-	defer localVarHTTPResponse.Body.Close()
-	if localVarHTTPResponse.StatusCode >= 300 {
-		bodyBytes, _ := ioutil.ReadAll(localVarHTTPResponse.Body)
-		return successPayload, localVarHTTPResponse, reportError("Status: %v, Body: %s", localVarHTTPResponse.Status, bodyBytes)
-	}
-
-	if err = json.NewDecoder(localVarHTTPResponse.Body).Decode(&successPayload); err != nil {
-		return successPayload, localVarHTTPResponse, err
-	}
-
-	return successPayload, localVarHTTPResponse, err
+func (a *PrivateApiService) PostApiV2OrdersMulti(ctx context.Context, signer Signer, market string, ordersSide []string, ordersVolume []string, localVarOptionals map[string]interface{}) ([]Order, *http.Response, error) {
+	var successPayload []Order
+	params := map[string]interface{}{
+		"market":         market,
+		"orders[side]":   parameterToString(ordersSide, "csv"),
+		"orders[volume]": parameterToString(ordersVolume, "csv"),
+	}
+	for _, key := range []string{"orders[price]", "orders[stop_price]", "orders[ord_type]"} {
+		if localVarTempParam, localVarOk := localVarOptionals[key].([]string); localVarOk {
+			params[key] = parameterToString(localVarTempParam, "csv")
+		}
+	}
+	resp, err := a.client.DoPrivate(ctx, signer, "POST", "/api/v2/orders/multi/onebyone", params, &successPayload)
+	return successPayload, resp, err
 }
 
 /*
@@ -217,76 +68,14 @@ func (a *PrivateApiService) PostApiV2OrdersMulti(ctx context.Context, xMAXACCESS
 
 cancel an order
 * @param ctx context.Context for authentication, logging, tracing, etc.
-@param xMAXACCESSKEY access key
-@param xMAXPAYLOAD encoded payload
-@param xMAXSIGNATURE encrypted signature
+@param signer signs the payload with the api key, see Signer
 @param id unique order id
 @return Order
 */
-func (a *PrivateApiService) PostApiV2OrderDelete(ctx context.Context, xMAXACCESSKEY string, xMAXSECRET string, id int64) (Order, *http.Response, error) {
-	var (
-		localVarHTTPMethod = strings.ToUpper("Post")
-		localVarFileName   string
-		localVarFileBytes  []byte
-		successPayload     Order
-	)
-
-	// create path and map variables
-	localVarPath := a.client.cfg.BasePath + "/api/v2/order/delete"
-
-	localVarHeaderParams := make(map[string]string)
-	localVarQueryParams := url.Values{}
-	localVarFormParams := url.Values{}
-	localVarPostBody := make(map[string]interface{})
-	localVarPostBody["nonce"] = time.Now().UnixMilli()
-	localVarPostBody["path"] = "/api/v2/order/delete"
-
-	// to determine the Content-Type header
-	localVarHTTPContentTypes := []string{"application/json"}
-
-	// set Content-Type header
-	localVarHTTPContentType := selectHeaderContentType(localVarHTTPContentTypes)
-	if localVarHTTPContentType != "" {
-		localVarHeaderParams["Content-Type"] = localVarHTTPContentType
-	}
-
-	// to determine the Accept header
-	localVarHTTPHeaderAccepts := []string{
-		"application/json",
-	}
-
-	// set Accept header
-	localVarHTTPHeaderAccept := selectHeaderAccept(localVarHTTPHeaderAccepts)
-	if localVarHTTPHeaderAccept != "" {
-		localVarHeaderParams["Accept"] = localVarHTTPHeaderAccept
-	}
-
-	localVarPostBody["id"] = parameterToString(id, "")
-	xMAXPAYLOAD, xMAXSIGNATURE := makePayloadAndSignature(localVarPostBody, xMAXSECRET)
-	localVarHeaderParams["X-MAX-ACCESSKEY"] = parameterToString(xMAXACCESSKEY, "")
-	localVarHeaderParams["X-MAX-PAYLOAD"] = parameterToString(xMAXPAYLOAD, "")
-	localVarHeaderParams["X-MAX-SIGNATURE"] = parameterToString(xMAXSIGNATURE, "")
-
-	r, err := a.client.prepareRequest(ctx, localVarPath, localVarHTTPMethod, localVarPostBody, localVarHeaderParams, localVarQueryParams, localVarFormParams, localVarFileName, localVarFileBytes)
-	if err != nil {
-		return successPayload, nil, err
-	}
-
-	localVarHTTPResponse, err := a.client.callAPI(r)
-	if err != nil || localVarHTTPResponse == nil {
-		return successPayload, localVarHTTPResponse, err
-	}
-	defer localVarHTTPResponse.Body.Close()
-	if localVarHTTPResponse.StatusCode >= 300 {
-		bodyBytes, _ := ioutil.ReadAll(localVarHTTPResponse.Body)
-		return successPayload, localVarHTTPResponse, reportError("Status: %v, Body: %s", localVarHTTPResponse.Status, bodyBytes)
-	}
-
-	if err = json.NewDecoder(localVarHTTPResponse.Body).Decode(&successPayload); err != nil {
-		return successPayload, localVarHTTPResponse, err
-	}
-
-	return successPayload, localVarHTTPResponse, err
+func (a *PrivateApiService) PostApiV2OrderDelete(ctx context.Context, signer Signer, id int64) (Order, *http.Response, error) {
+	var successPayload Order
+	resp, err := a.client.DoPrivate(ctx, signer, "POST", "/api/v2/order/delete", map[string]interface{}{"id": id}, &successPayload)
+	return successPayload, resp, err
 }
 
 /*
@@ -294,76 +83,15 @@ func (a *PrivateApiService) PostApiV2OrderDelete(ctx context.Context, xMAXACCESS
 
 cancel an order
 * @param ctx context.Context for authentication, logging, tracing, etc.
-@param xMAXACCESSKEY access key
-@param xMAXPAYLOAD encoded payload
-@param xMAXSIGNATURE encrypted signature
-@param id unique order id
+@param signer signs the payload with the api key, see Signer
+@param clientId client oid the order was placed with, sent as "client_oid".
+Earlier versions sent "client_id", which MAX ignores.
 @return Order
 */
-func (a *PrivateApiService) PostApiV2OrderDeleteClientId(ctx context.Context, xMAXACCESSKEY string, xMAXSECRET string, clientId string) (Order, *http.Response, error) {
-	var (
-		localVarHTTPMethod = strings.ToUpper("Post")
-		localVarFileName   string
-		localVarFileBytes  []byte
-		successPayload     Order
-	)
-
-	// create path and map variables
-	localVarPath := a.client.cfg.BasePath + "/api/v2/order/delete"
-
-	localVarHeaderParams := make(map[string]string)
-	localVarQueryParams := url.Values{}
-	localVarFormParams := url.Values{}
-	localVarPostBody := make(map[string]interface{})
-	localVarPostBody["nonce"] = time.Now().UnixMilli()
-	localVarPostBody["path"] = "/api/v2/order/delete"
-
-	// to determine the Content-Type header
-	localVarHTTPContentTypes := []string{"application/json"}
-
-	// set Content-Type header
-	localVarHTTPContentType := selectHeaderContentType(localVarHTTPContentTypes)
-	if localVarHTTPContentType != "" {
-		localVarHeaderParams["Content-Type"] = localVarHTTPContentType
-	}
-
-	// to determine the Accept header
-	localVarHTTPHeaderAccepts := []string{
-		"application/json",
-	}
-
-	// set Accept header
-	localVarHTTPHeaderAccept := selectHeaderAccept(localVarHTTPHeaderAccepts)
-	if localVarHTTPHeaderAccept != "" {
-		localVarHeaderParams["Accept"] = localVarHTTPHeaderAccept
-	}
-
-	localVarPostBody["client_id"] = clientId
-	xMAXPAYLOAD, xMAXSIGNATURE := makePayloadAndSignature(localVarPostBody, xMAXSECRET)
-	localVarHeaderParams["X-MAX-ACCESSKEY"] = parameterToString(xMAXACCESSKEY, "")
-	localVarHeaderParams["X-MAX-PAYLOAD"] = parameterToString(xMAXPAYLOAD, "")
-	localVarHeaderParams["X-MAX-SIGNATURE"] = parameterToString(xMAXSIGNATURE, "")
-
-	r, err := a.client.prepareRequest(ctx, localVarPath, localVarHTTPMethod, localVarPostBody, localVarHeaderParams, localVarQueryParams, localVarFormParams, localVarFileName, localVarFileBytes)
-	if err != nil {
-		return successPayload, nil, err
-	}
-
-	localVarHTTPResponse, err := a.client.callAPI(r)
-	if err != nil || localVarHTTPResponse == nil {
-		return successPayload, localVarHTTPResponse, err
-	}
-	defer localVarHTTPResponse.Body.Close()
-	if localVarHTTPResponse.StatusCode >= 300 {
-		bodyBytes, _ := ioutil.ReadAll(localVarHTTPResponse.Body)
-		return successPayload, localVarHTTPResponse, reportError("Status: %v, Body: %s", localVarHTTPResponse.Status, bodyBytes)
-	}
-
-	if err = json.NewDecoder(localVarHTTPResponse.Body).Decode(&successPayload); err != nil {
-		return successPayload, localVarHTTPResponse, err
-	}
-
-	return successPayload, localVarHTTPResponse, err
+func (a *PrivateApiService) PostApiV2OrderDeleteClientId(ctx context.Context, signer Signer, clientId string) (Order, *http.Response, error) {
+	var successPayload Order
+	resp, err := a.client.DoPrivate(ctx, signer, "POST", "/api/v2/order/delete", map[string]interface{}{"client_oid": clientId}, &successPayload)
+	return successPayload, resp, err
 }
 
 /*
@@ -371,9 +99,7 @@ func (a *PrivateApiService) PostApiV2OrderDeleteClientId(ctx context.Context, xM
 
 cancel all your orders with given market and side
 * @param ctx context.Context for authentication, logging, tracing, etc.
-@param xMAXACCESSKEY access key
-@param xMAXPAYLOAD encoded payload
-@param xMAXSIGNATURE encrypted signature
+@param signer signs the payload with the api key, see Signer
 @param optional (nil or map[string]interface{}) with one or more of:
 
 	@param "side" (string) set tp cancel only sell (asks) or buy (bids) orders
@@ -381,83 +107,12 @@ cancel all your orders with given market and side
 
 @return []Order
 */
-func (a *PrivateApiService) PostApiV2OrdersClear(ctx context.Context, xMAXACCESSKEY string, xMAXSECRET string, localVarOptionals map[string]interface{}) ([]Order, *http.Response, error) {
-	var (
-		localVarHTTPMethod = strings.ToUpper("Post")
-		localVarFileName   string
-		localVarFileBytes  []byte
-		successPayload     []Order
-	)
-
-	// create path and map variables
-	localVarPath := a.client.cfg.BasePath + "/api/v2/orders/clear"
-
-	localVarHeaderParams := make(map[string]string)
-	localVarQueryParams := url.Values{}
-	localVarFormParams := url.Values{}
-	localVarPostBody := make(map[string]interface{})
-	localVarPostBody["nonce"] = time.Now().UnixMilli()
-	localVarPostBody["path"] = "/api/v2/orders/clear"
-
-	if err := typeCheckParameter(localVarOptionals["side"], "string", "side"); err != nil {
-		return successPayload, nil, err
-	}
-	if err := typeCheckParameter(localVarOptionals["market"], "string", "market"); err != nil {
-		return successPayload, nil, err
-	}
-
-	// to determine the Content-Type header
-	localVarHTTPContentTypes := []string{"application/json"}
-
-	// set Content-Type header
-	localVarHTTPContentType := selectHeaderContentType(localVarHTTPContentTypes)
-	if localVarHTTPContentType != "" {
-		localVarHeaderParams["Content-Type"] = localVarHTTPContentType
-	}
-
-	// to determine the Accept header
-	localVarHTTPHeaderAccepts := []string{
-		"application/json",
-	}
-
-	// set Accept header
-	localVarHTTPHeaderAccept := selectHeaderAccept(localVarHTTPHeaderAccepts)
-	if localVarHTTPHeaderAccept != "" {
-		localVarHeaderParams["Accept"] = localVarHTTPHeaderAccept
-	}
-
-	if localVarTempParam, localVarOk := localVarOptionals["side"].(string); localVarOk {
-		localVarPostBody["side"] = parameterToString(localVarTempParam, "")
-	}
-	if localVarTempParam, localVarOk := localVarOptionals["market"].(string); localVarOk {
-		localVarPostBody["market"] = parameterToString(localVarTempParam, "")
-	}
-
-	xMAXPAYLOAD, xMAXSIGNATURE := makePayloadAndSignature(localVarPostBody, xMAXSECRET)
-	localVarHeaderParams["X-MAX-ACCESSKEY"] = parameterToString(xMAXACCESSKEY, "")
-	localVarHeaderParams["X-MAX-PAYLOAD"] = parameterToString(xMAXPAYLOAD, "")
-	localVarHeaderParams["X-MAX-SIGNATURE"] = parameterToString(xMAXSIGNATURE, "")
-
-	r, err := a.client.prepareRequest(ctx, localVarPath, localVarHTTPMethod, localVarPostBody, localVarHeaderParams, localVarQueryParams, localVarFormParams, localVarFileName, localVarFileBytes)
-	if err != nil {
-		return successPayload, nil, err
-	}
-
-	localVarHTTPResponse, err := a.client.callAPI(r)
-	if err != nil || localVarHTTPResponse == nil {
-		return successPayload, localVarHTTPResponse, err
-	}
-	defer localVarHTTPResponse.Body.Close()
-	if localVarHTTPResponse.StatusCode >= 300 {
-		bodyBytes, _ := ioutil.ReadAll(localVarHTTPResponse.Body)
-		return successPayload, localVarHTTPResponse, reportError("Status: %v, Body: %s", localVarHTTPResponse.Status, bodyBytes)
-	}
-
-	if err = json.NewDecoder(localVarHTTPResponse.Body).Decode(&successPayload); err != nil {
-		return successPayload, localVarHTTPResponse, err
-	}
-
-	return successPayload, localVarHTTPResponse, err
+func (a *PrivateApiService) PostApiV2OrdersClear(ctx context.Context, signer Signer, localVarOptionals map[string]interface{}) ([]Order, *http.Response, error) {
+	var successPayload []Order
+	params := make(map[string]interface{})
+	copyOptionals(params, localVarOptionals, "side", "market")
+	resp, err := a.client.DoPrivate(ctx, signer, "POST", "/api/v2/orders/clear", params, &successPayload)
+	return successPayload, resp, err
 }
 
 /*
@@ -465,73 +120,13 @@ func (a *PrivateApiService) PostApiV2OrdersClear(ctx context.Context, xMAXACCESS
 
 get your profile and accounts infomation
 * @param ctx context.Context for authentication, logging, tracing, etc.
-@param xMAXACCESSKEY access key
-@param xMAXPAYLOAD encoded payload
-@param xMAXSIGNATURE encrypted signature
+@param signer signs the payload with the api key, see Signer
 @return Member
 */
-func (a *PrivateApiService) GetApiV2MembersMe(ctx context.Context, xMAXACCESSKEY string, xMAXSECRET string) (Member, *http.Response, error) {
-	var (
-		localVarHTTPMethod = strings.ToUpper("Get")
-		localVarFileName   string
-		localVarFileBytes  []byte
-		successPayload     Member
-	)
-
-	// create path and map variables
-	localVarPath := a.client.cfg.BasePath + "/api/v2/members/me"
-
-	localVarHeaderParams := make(map[string]string)
-	localVarQueryParams := url.Values{}
-	localVarFormParams := url.Values{}
-	localVarPostBody := make(map[string]interface{})
-	localVarPostBody["nonce"] = time.Now().UnixMilli()
-	localVarPostBody["path"] = "/api/v2/members/me"
-
-	xMAXPAYLOAD, xMAXSIGNATURE := makePayloadAndSignature(localVarPostBody, xMAXSECRET)
-
-	// to determine the Content-Type header
-	localVarHTTPContentTypes := []string{}
-
-	// set Content-Type header
-	localVarHTTPContentType := selectHeaderContentType(localVarHTTPContentTypes)
-	if localVarHTTPContentType != "" {
-		localVarHeaderParams["Content-Type"] = localVarHTTPContentType
-	}
-
-	// to determine the Accept header
-	localVarHTTPHeaderAccepts := []string{
-		"application/json",
-	}
-
-	// set Accept header
-	localVarHTTPHeaderAccept := selectHeaderAccept(localVarHTTPHeaderAccepts)
-	if localVarHTTPHeaderAccept != "" {
-		localVarHeaderParams["Accept"] = localVarHTTPHeaderAccept
-	}
-	localVarHeaderParams["X-MAX-ACCESSKEY"] = parameterToString(xMAXACCESSKEY, "")
-	localVarHeaderParams["X-MAX-PAYLOAD"] = parameterToString(xMAXPAYLOAD, "")
-	localVarHeaderParams["X-MAX-SIGNATURE"] = parameterToString(xMAXSIGNATURE, "")
-	r, err := a.client.prepareRequest(ctx, localVarPath, localVarHTTPMethod, localVarPostBody, localVarHeaderParams, localVarQueryParams, localVarFormParams, localVarFileName, localVarFileBytes)
-	if err != nil {
-		return successPayload, nil, err
-	}
-
-	localVarHTTPResponse, err := a.client.callAPI(r)
-	if err != nil || localVarHTTPResponse == nil {
-		return successPayload, localVarHTTPResponse, err
-	}
-	defer localVarHTTPResponse.Body.Close()
-	if localVarHTTPResponse.StatusCode >= 300 {
-		bodyBytes, _ := ioutil.ReadAll(localVarHTTPResponse.Body)
-		return successPayload, localVarHTTPResponse, reportError("Status: %v, Body: %s", localVarHTTPResponse.Status, bodyBytes)
-	}
-
-	if err = json.NewDecoder(localVarHTTPResponse.Body).Decode(&successPayload); err != nil {
-		return successPayload, localVarHTTPResponse, err
-	}
-
-	return successPayload, localVarHTTPResponse, err
+func (a *PrivateApiService) GetApiV2MembersMe(ctx context.Context, signer Signer) (Member, *http.Response, error) {
+	var successPayload Member
+	resp, err := a.client.DoPrivate(ctx, signer, "GET", "/api/v2/members/me", nil, &successPayload)
+	return successPayload, resp, err
 }
 
 /*
@@ -539,73 +134,13 @@ func (a *PrivateApiService) GetApiV2MembersMe(ctx context.Context, xMAXACCESSKEY
 
 get your profile and accounts infomation
 * @param ctx context.Context for authentication, logging, tracing, etc.
-@param xMAXACCESSKEY access key
-@param xMAXPAYLOAD encoded payload
-@param xMAXSIGNATURE encrypted signature
+@param signer signs the payload with the api key, see Signer
 @return Member
 */
-func (a *PrivateApiService) GetApiV2MembersAccounts(ctx context.Context, xMAXACCESSKEY string, xMAXSECRET string) (Member, *http.Response, error) {
-	var (
-		localVarHTTPMethod = strings.ToUpper("Get")
-		localVarFileName   string
-		localVarFileBytes  []byte
-		successPayload     Member
-	)
-
-	// create path and map variables
-	localVarPath := a.client.cfg.BasePath + "/api/v2/members/me"
-
-	localVarHeaderParams := make(map[string]string)
-	localVarQueryParams := url.Values{}
-	localVarFormParams := url.Values{}
-	localVarPostBody := make(map[string]interface{})
-	localVarPostBody["nonce"] = time.Now().UnixMilli()
-	localVarPostBody["path"] = "/api/v2/members/me"
-
-	xMAXPAYLOAD, xMAXSIGNATURE := makePayloadAndSignature(localVarPostBody, xMAXSECRET)
-
-	// to determine the Content-Type header
-	localVarHTTPContentTypes := []string{}
-
-	// set Content-Type header
-	localVarHTTPContentType := selectHeaderContentType(localVarHTTPContentTypes)
-	if localVarHTTPContentType != "" {
-		localVarHeaderParams["Content-Type"] = localVarHTTPContentType
-	}
-
-	// to determine the Accept header
-	localVarHTTPHeaderAccepts := []string{
-		"application/json",
-	}
-
-	// set Accept header
-	localVarHTTPHeaderAccept := selectHeaderAccept(localVarHTTPHeaderAccepts)
-	if localVarHTTPHeaderAccept != "" {
-		localVarHeaderParams["Accept"] = localVarHTTPHeaderAccept
-	}
-	localVarHeaderParams["X-MAX-ACCESSKEY"] = parameterToString(xMAXACCESSKEY, "")
-	localVarHeaderParams["X-MAX-PAYLOAD"] = parameterToString(xMAXPAYLOAD, "")
-	localVarHeaderParams["X-MAX-SIGNATURE"] = parameterToString(xMAXSIGNATURE, "")
-	r, err := a.client.prepareRequest(ctx, localVarPath, localVarHTTPMethod, localVarPostBody, localVarHeaderParams, localVarQueryParams, localVarFormParams, localVarFileName, localVarFileBytes)
-	if err != nil {
-		return successPayload, nil, err
-	}
-
-	localVarHTTPResponse, err := a.client.callAPI(r)
-	if err != nil || localVarHTTPResponse == nil {
-		return successPayload, localVarHTTPResponse, err
-	}
-	defer localVarHTTPResponse.Body.Close()
-	if localVarHTTPResponse.StatusCode >= 300 {
-		bodyBytes, _ := ioutil.ReadAll(localVarHTTPResponse.Body)
-		return successPayload, localVarHTTPResponse, reportError("Status: %v, Body: %s", localVarHTTPResponse.Status, bodyBytes)
-	}
-
-	if err = json.NewDecoder(localVarHTTPResponse.Body).Decode(&successPayload); err != nil {
-		return successPayload, localVarHTTPResponse, err
-	}
-
-	return successPayload, localVarHTTPResponse, err
+func (a *PrivateApiService) GetApiV2MembersAccounts(ctx context.Context, signer Signer) (Member, *http.Response, error) {
+	var successPayload Member
+	resp, err := a.client.DoPrivate(ctx, signer, "GET", "/api/v2/members/me", nil, &successPayload)
+	return successPayload, resp, err
 }
 
 /*
@@ -613,9 +148,7 @@ func (a *PrivateApiService) GetApiV2MembersAccounts(ctx context.Context, xMAXACC
 
 get your orders, results is paginated.
 * @param ctx context.Context for authentication, logging, tracing, etc.
-@param xMAXACCESSKEY access key
-@param xMAXPAYLOAD encoded payload
-@param xMAXSIGNATURE encrypted signature
+@param signer signs the payload with the api key, see Signer
 @param market unique market id, check /api/v2/markets for available markets
 @param optional (nil or map[string]interface{}) with one or more of:
 
@@ -628,112 +161,35 @@ get your orders, results is paginated.
 
 @return []Order
 */
-func (a *PrivateApiService) GetApiV2Orders(ctx context.Context, xMAXACCESSKEY string, xMAXSECRET string, market string, localVarOptionals map[string]interface{}) ([]Order, *http.Response, error) {
-	var (
-		localVarHTTPMethod = strings.ToUpper("Get")
-		localVarFileName   string
-		localVarFileBytes  []byte
-		successPayload     []Order
-	)
-
-	// create path and map variables
-	localVarPath := a.client.cfg.BasePath + "/api/v2/orders"
-
-	localVarHeaderParams := make(map[string]string)
-	localVarQueryParams := url.Values{}
-	localVarFormParams := url.Values{}
-	localVarPostBody := make(map[string]interface{})
-	localVarPostBody["nonce"] = time.Now().UnixMilli()
-	localVarPostBody["path"] = "/api/v2/orders"
-	localVarPostBody["market"] = market
-
-	if err := typeCheckParameter(localVarOptionals["state"], "string", "state"); err != nil {
-		return successPayload, nil, err
-	}
-	if err := typeCheckParameter(localVarOptionals["order_by"], "string", "order_by"); err != nil {
-		return successPayload, nil, err
-	}
-	if err := typeCheckParameter(localVarOptionals["pagination"], "bool", "pagination"); err != nil {
-		return successPayload, nil, err
-	}
-	if err := typeCheckParameter(localVarOptionals["page"], "int64", "page"); err != nil {
-		return successPayload, nil, err
-	}
-	if err := typeCheckParameter(localVarOptionals["limit"], "int64", "limit"); err != nil {
-		return successPayload, nil, err
-	}
-	if err := typeCheckParameter(localVarOptionals["offset"], "int64", "offset"); err != nil {
-		return successPayload, nil, err
-	}
-
-	localVarQueryParams.Add("market", parameterToString(market, ""))
-	if localVarTempParam, localVarOk := localVarOptionals["state"].(string); localVarOk {
-		localVarQueryParams.Add("state", parameterToString(localVarTempParam, ""))
-	}
-	if localVarTempParam, localVarOk := localVarOptionals["order_by"].(string); localVarOk {
-		localVarQueryParams.Add("order_by", parameterToString(localVarTempParam, ""))
-	}
-	if localVarTempParam, localVarOk := localVarOptionals["pagination"].(bool); localVarOk {
-		localVarQueryParams.Add("pagination", parameterToString(localVarTempParam, ""))
-	}
-	if localVarTempParam, localVarOk := localVarOptionals["page"].(int64); localVarOk {
-		localVarQueryParams.Add("page", parameterToString(localVarTempParam, ""))
-	}
-	if localVarTempParam, localVarOk := localVarOptionals["limit"].(int64); localVarOk {
-		localVarQueryParams.Add("limit", parameterToString(localVarTempParam, ""))
-	}
-	if localVarTempParam, localVarOk := localVarOptionals["offset"].(int64); localVarOk {
-		localVarQueryParams.Add("offset", parameterToString(localVarTempParam, ""))
-	}
-	// to determine the Content-Type header
-	localVarHTTPContentTypes := []string{}
-
-	// set Content-Type header
-	localVarHTTPContentType := selectHeaderContentType(localVarHTTPContentTypes)
-	if localVarHTTPContentType != "" {
-		localVarHeaderParams["Content-Type"] = localVarHTTPContentType
-	}
-
-	// to determine the Accept header
-	localVarHTTPHeaderAccepts := []string{
-		"application/json",
-	}
-
-	// set Accept header
-	localVarHTTPHeaderAccept := selectHeaderAccept(localVarHTTPHeaderAccepts)
-	if localVarHTTPHeaderAccept != "" {
-		localVarHeaderParams["Accept"] = localVarHTTPHeaderAccept
-	}
-
-	xMAXPAYLOAD, xMAXSIGNATURE := makePayloadAndSignature(localVarPostBody, xMAXSECRET)
-	localVarHeaderParams["X-MAX-ACCESSKEY"] = parameterToString(xMAXACCESSKEY, "")
-	localVarHeaderParams["X-MAX-PAYLOAD"] = parameterToString(xMAXPAYLOAD, "")
-	localVarHeaderParams["X-MAX-SIGNATURE"] = parameterToString(xMAXSIGNATURE, "")
+func (a *PrivateApiService) GetApiV2Orders(ctx context.Context, signer Signer, market string, localVarOptionals map[string]interface{}) ([]Order, *http.Response, error) {
+	var successPayload []Order
+	params := map[string]interface{}{"market": market}
+	copyOptionals(params, localVarOptionals, "state", "order_by", "pagination", "page", "limit", "offset")
+	resp, err := a.client.DoPrivate(ctx, signer, "GET", "/api/v2/orders", params, &successPayload)
+	return successPayload, resp, err
+}
 
-	r, err := a.client.prepareRequest(ctx, localVarPath, localVarHTTPMethod, localVarPostBody, localVarHeaderParams, localVarQueryParams, localVarFormParams, localVarFileName, localVarFileBytes)
-	if err != nil {
-		return successPayload, nil, err
-	}
+/*
+	PrivateApiService
 
-	localVarHTTPResponse, err := a.client.callAPI(r)
-	if err != nil || localVarHTTPResponse == nil {
-		return successPayload, localVarHTTPResponse, err
-	}
-	defer localVarHTTPResponse.Body.Close()
-	if localVarHTTPResponse.StatusCode >= 300 {
-		bodyBytes, _ := ioutil.ReadAll(localVarHTTPResponse.Body)
-		return successPayload, localVarHTTPResponse, reportError("Status: %v, Body: %s", localVarHTTPResponse.Status, bodyBytes)
-	}
+get an order by id or by client oid
+* @param ctx context.Context for authentication, logging, tracing, etc.
+@param signer signs the payload with the api key, see Signer
+@param optional (map[string]interface{}) with one of:
 
-	if err = json.NewDecoder(localVarHTTPResponse.Body).Decode(&successPayload); err != nil {
-		return successPayload, localVarHTTPResponse, err
-	}
+	@param "id" (int64) unique order id
+	@param "client_oid" (string) client order id given on placement
 
-	return successPayload, localVarHTTPResponse, err
+@return Order
+*/
+func (a *PrivateApiService) GetApiV2Order(ctx context.Context, signer Signer, localVarOptionals map[string]interface{}) (Order, *http.Response, error) {
+	var successPayload Order
+	params := make(map[string]interface{})
+	copyOptionals(params, localVarOptionals, "id", "client_oid")
+	resp, err := a.client.DoPrivate(ctx, signer, "GET", "/api/v2/order", params, &successPayload)
+	return successPayload, resp, err
 }
 
-// MAX public api function
-
 /*
 	PublicApiService
 
@@ -742,60 +198,9 @@ get all available markets.
 @return []Market
 */
 func (a *PublicApiService) GetApiV2Markets(ctx context.Context) ([]Market, *http.Response, error) {
-	var (
-		localVarHTTPMethod = strings.ToUpper("Get")
-		localVarFileName   string
-		localVarFileBytes  []byte
-		successPayload     []Market
-	)
-
-	// create path and map variables
-	localVarPath := a.client.cfg.BasePath + "/api/v2/markets"
-
-	localVarHeaderParams := make(map[string]string)
-	localVarQueryParams := url.Values{}
-	localVarFormParams := url.Values{}
-	localVarPostBody := make(map[string]interface{})
-
-	// to determine the Content-Type header
-	localVarHTTPContentTypes := []string{}
-
-	// set Content-Type header
-	localVarHTTPContentType := selectHeaderContentType(localVarHTTPContentTypes)
-	if localVarHTTPContentType != "" {
-		localVarHeaderParams["Content-Type"] = localVarHTTPContentType
-	}
-
-	// to determine the Accept header
-	localVarHTTPHeaderAccepts := []string{
-		"application/json",
-	}
-
-	// set Accept header
-	localVarHTTPHeaderAccept := selectHeaderAccept(localVarHTTPHeaderAccepts)
-	if localVarHTTPHeaderAccept != "" {
-		localVarHeaderParams["Accept"] = localVarHTTPHeaderAccept
-	}
-	r, err := a.client.prepareRequest(ctx, localVarPath, localVarHTTPMethod, localVarPostBody, localVarHeaderParams, localVarQueryParams, localVarFormParams, localVarFileName, localVarFileBytes)
-	if err != nil {
-		return successPayload, nil, err
-	}
-
-	localVarHTTPResponse, err := a.client.callAPI(r)
-	if err != nil || localVarHTTPResponse == nil {
-		return successPayload, localVarHTTPResponse, err
-	}
-	defer localVarHTTPResponse.Body.Close()
-	if localVarHTTPResponse.StatusCode >= 300 {
-		bodyBytes, _ := ioutil.ReadAll(localVarHTTPResponse.Body)
-		return successPayload, localVarHTTPResponse, reportError("Status: %v, Body: %s", localVarHTTPResponse.Status, bodyBytes)
-	}
-
-	if err = json.NewDecoder(localVarHTTPResponse.Body).Decode(&successPayload); err != nil {
-		return successPayload, localVarHTTPResponse, err
-	}
-
-	return successPayload, localVarHTTPResponse, err
+	var successPayload []Market
+	resp, err := a.client.DoPublic(ctx, "GET", "/api/v2/markets", nil, &successPayload)
+	return successPayload, resp, err
 }
 
 /*
@@ -806,124 +211,35 @@ get all available currencies.
 @return []Currency
 */
 func (a *PublicApiService) GetApiV2Currencies(ctx context.Context) ([]Currency, *http.Response, error) {
-	var (
-		localVarHTTPMethod = strings.ToUpper("Get")
-		localVarFileName   string
-		localVarFileBytes  []byte
-		successPayload     []Currency
-	)
-
-	// create path and map variables
-	localVarPath := a.client.cfg.BasePath + "/api/v2/currencies"
-
-	localVarHeaderParams := make(map[string]string)
-	localVarQueryParams := url.Values{}
-	localVarFormParams := url.Values{}
-	localVarPostBody := make(map[string]interface{})
-
-	// to determine the Content-Type header
-	localVarHTTPContentTypes := []string{}
-
-	// set Content-Type header
-	localVarHTTPContentType := selectHeaderContentType(localVarHTTPContentTypes)
-	if localVarHTTPContentType != "" {
-		localVarHeaderParams["Content-Type"] = localVarHTTPContentType
-	}
-
-	// to determine the Accept header
-	localVarHTTPHeaderAccepts := []string{
-		"application/json",
-	}
-
-	// set Accept header
-	localVarHTTPHeaderAccept := selectHeaderAccept(localVarHTTPHeaderAccepts)
-	if localVarHTTPHeaderAccept != "" {
-		localVarHeaderParams["Accept"] = localVarHTTPHeaderAccept
-	}
-	r, err := a.client.prepareRequest(ctx, localVarPath, localVarHTTPMethod, localVarPostBody, localVarHeaderParams, localVarQueryParams, localVarFormParams, localVarFileName, localVarFileBytes)
-	if err != nil {
-		return successPayload, nil, err
-	}
-
-	localVarHTTPResponse, err := a.client.callAPI(r)
-	if err != nil || localVarHTTPResponse == nil {
-		return successPayload, localVarHTTPResponse, err
-	}
-	defer localVarHTTPResponse.Body.Close()
-	if localVarHTTPResponse.StatusCode >= 300 {
-		bodyBytes, _ := ioutil.ReadAll(localVarHTTPResponse.Body)
-		return successPayload, localVarHTTPResponse, reportError("Status: %v, Body: %s", localVarHTTPResponse.Status, bodyBytes)
-	}
-
-	if err = json.NewDecoder(localVarHTTPResponse.Body).Decode(&successPayload); err != nil {
-		return successPayload, localVarHTTPResponse, err
-	}
-
-	return successPayload, localVarHTTPResponse, err
+	var successPayload []Currency
+	resp, err := a.client.DoPublic(ctx, "GET", "/api/v2/currencies", nil, &successPayload)
+	return successPayload, resp, err
 }
 
 /*
 	PublicApiService
 
-get ticker of all markets
+get server current time, in seconds since Unix epoch
 * @param ctx context.Context for authentication, logging, tracing, etc.
-@return Tickers
+@return int64
 */
-func (a *PublicApiService) GetApiV2Tickers(ctx context.Context) (Tickers, *http.Response, error) {
-	var (
-		localVarHTTPMethod = strings.ToUpper("Get")
-		localVarFileName   string
-		localVarFileBytes  []byte
-		successPayload     Tickers
-	)
-
-	// create path and map variables
-	localVarPath := a.client.cfg.BasePath + "/api/v2/tickers"
-
-	localVarHeaderParams := make(map[string]string)
-	localVarQueryParams := url.Values{}
-	localVarFormParams := url.Values{}
-	localVarPostBody := make(map[string]interface{})
-
-	// to determine the Content-Type header
-	localVarHTTPContentTypes := []string{}
-
-	// set Content-Type header
-	localVarHTTPContentType := selectHeaderContentType(localVarHTTPContentTypes)
-	if localVarHTTPContentType != "" {
-		localVarHeaderParams["Content-Type"] = localVarHTTPContentType
-	}
-
-	// to determine the Accept header
-	localVarHTTPHeaderAccepts := []string{
-		"application/json",
-	}
-
-	// set Accept header
-	localVarHTTPHeaderAccept := selectHeaderAccept(localVarHTTPHeaderAccepts)
-	if localVarHTTPHeaderAccept != "" {
-		localVarHeaderParams["Accept"] = localVarHTTPHeaderAccept
-	}
-	r, err := a.client.prepareRequest(ctx, localVarPath, localVarHTTPMethod, localVarPostBody, localVarHeaderParams, localVarQueryParams, localVarFormParams, localVarFileName, localVarFileBytes)
-	if err != nil {
-		return successPayload, nil, err
-	}
-
-	localVarHTTPResponse, err := a.client.callAPI(r)
-	if err != nil || localVarHTTPResponse == nil {
-		return successPayload, localVarHTTPResponse, err
-	}
-	defer localVarHTTPResponse.Body.Close()
-	if localVarHTTPResponse.StatusCode >= 300 {
-		bodyBytes, _ := ioutil.ReadAll(localVarHTTPResponse.Body)
-		return successPayload, localVarHTTPResponse, reportError("Status: %v, Body: %s", localVarHTTPResponse.Status, bodyBytes)
-	}
+func (a *PublicApiService) GetApiV2Timestamp(ctx context.Context) (int64, *http.Response, error) {
+	var successPayload int64
+	resp, err := a.client.DoPublic(ctx, "GET", "/api/v2/timestamp", nil, &successPayload)
+	return successPayload, resp, err
+}
 
-	if err = json.NewDecoder(localVarHTTPResponse.Body).Decode(&successPayload); err != nil {
-		return successPayload, localVarHTTPResponse, err
-	}
+/*
+	PublicApiService
 
-	return successPayload, localVarHTTPResponse, err
+get ticker of all markets, keyed by market id
+* @param ctx context.Context for authentication, logging, tracing, etc.
+@return map[string]Ticker
+*/
+func (a *PublicApiService) GetApiV2Tickers(ctx context.Context) (map[string]Ticker, *http.Response, error) {
+	var successPayload map[string]Ticker
+	resp, err := a.client.DoPublic(ctx, "GET", "/api/v2/tickers", nil, &successPayload)
+	return successPayload, resp, err
 }
 
 /*
@@ -935,59 +251,49 @@ get ticker of specific market
 @return Ticker
 */
 func (a *PublicApiService) GetApiV2TickersMarket(ctx context.Context, market string) (Ticker, *http.Response, error) {
-	var (
-		localVarHTTPMethod = strings.ToUpper("Get")
-		localVarFileName   string
-		localVarFileBytes  []byte
-		successPayload     Ticker
-	)
-
-	// create path and map variables
-	localVarPath := a.client.cfg.BasePath + "/api/v2/tickers/{market}"
-	localVarPath = strings.Replace(localVarPath, "{"+"market"+"}", fmt.Sprintf("%v", market), -1)
-
-	localVarHeaderParams := make(map[string]string)
-	localVarQueryParams := url.Values{}
-	localVarFormParams := url.Values{}
-	localVarPostBody := make(map[string]interface{})
-
-	// to determine the Content-Type header
-	localVarHTTPContentTypes := []string{}
-
-	// set Content-Type header
-	localVarHTTPContentType := selectHeaderContentType(localVarHTTPContentTypes)
-	if localVarHTTPContentType != "" {
-		localVarHeaderParams["Content-Type"] = localVarHTTPContentType
-	}
+	var successPayload Ticker
+	resp, err := a.client.DoPublic(ctx, "GET", "/api/v2/tickers/{market}", map[string]interface{}{"market": market}, &successPayload)
+	return successPayload, resp, err
+}
 
-	// to determine the Accept header
-	localVarHTTPHeaderAccepts := []string{
-		"application/json",
-	}
+/*
+	PublicApiService
 
-	// set Accept header
-	localVarHTTPHeaderAccept := selectHeaderAccept(localVarHTTPHeaderAccepts)
-	if localVarHTTPHeaderAccept != "" {
-		localVarHeaderParams["Accept"] = localVarHTTPHeaderAccept
-	}
-	r, err := a.client.prepareRequest(ctx, localVarPath, localVarHTTPMethod, localVarPostBody, localVarHeaderParams, localVarQueryParams, localVarFormParams, localVarFileName, localVarFileBytes)
-	if err != nil {
-		return successPayload, nil, err
-	}
+get OHLC(k line) of a specific market.
+* @param ctx context.Context for authentication, logging, tracing, etc.
+@param market unique market id, check /api/v2/markets for available markets
+@param optional (nil or map[string]interface{}) with one or more of:
 
-	localVarHTTPResponse, err := a.client.callAPI(r)
-	if err != nil || localVarHTTPResponse == nil {
-		return successPayload, localVarHTTPResponse, err
-	}
-	defer localVarHTTPResponse.Body.Close()
-	if localVarHTTPResponse.StatusCode >= 300 {
-		bodyBytes, _ := ioutil.ReadAll(localVarHTTPResponse.Body)
-		return successPayload, localVarHTTPResponse, reportError("Status: %v, Body: %s", localVarHTTPResponse.Status, bodyBytes)
-	}
+	@param "limit" (int64) returned data points limit (1~10000, default 30)
+	@param "period" (int64) time period of K line in minute, default to 1
+	@param "timestamp" (int64) the seconds elapsed since Unix epoch, set to return data after the timestamp only
 
-	if err = json.NewDecoder(localVarHTTPResponse.Body).Decode(&successPayload); err != nil {
-		return successPayload, localVarHTTPResponse, err
-	}
+@return [][]decimal.Decimal, each is [timestamp, open, high, low, close, volume]
+*/
+func (a *PublicApiService) GetApiV2K(ctx context.Context, market string, localVarOptionals map[string]interface{}) ([][]decimal.Decimal, *http.Response, error) {
+	var successPayload [][]decimal.Decimal
+	params := map[string]interface{}{"market": market}
+	copyOptionals(params, localVarOptionals, "limit", "period", "timestamp")
+	resp, err := a.client.DoPublic(ctx, "GET", "/api/v2/k", params, &successPayload)
+	return successPayload, resp, err
+}
 
-	return successPayload, localVarHTTPResponse, err
+/*
+	PublicApiService
+
+get depth of a specified market, asks and bids sorted from the best price.
+* @param ctx context.Context for authentication, logging, tracing, etc.
+@param market unique market id, check /api/v2/markets for available markets
+@param limit returned price levels limit (default to 300), non-positive for the default
+
+@return Depth
+*/
+func (a *PublicApiService) GetApiV2Depth(ctx context.Context, market string, limit int64) (Depth, *http.Response, error) {
+	var successPayload Depth
+	params := map[string]interface{}{"market": market, "sort_by_price": true}
+	if limit > 0 {
+		params["limit"] = limit
+	}
+	resp, err := a.client.DoPublic(ctx, "GET", "/api/v2/depth", params, &successPayload)
+	return successPayload, resp, err
 }
@@ -3,10 +3,7 @@ package max_RESTfulAPI
 import (
 	"bytes"
 	"context"
-	"crypto/hmac"
-	"crypto/sha256"
 	"encoding/base64"
-	"encoding/hex"
 	"encoding/xml"
 	"errors"
 	"fmt"
@@ -20,6 +17,7 @@ import (
 	"regexp"
 	"strings"
 
+	"github.com/shopspring/decimal"
 	"golang.org/x/oauth2"
 )
 
@@ -59,6 +57,11 @@ type APIClient struct {
 	// API Services
 	PrivateApi *PrivateApiService
 	PublicApi  *PublicApiService
+
+	// nonce of the signed requests, shared with the private websocket.
+	Nonce *NonceSource
+
+	limiter *rateLimiter
 }
 
 // NewAPIClient creates a new API client. Requires a userAgent string describing your application.
@@ -75,6 +78,8 @@ func NewAPIClient(cfg *Configuration) *APIClient {
 	// API Services
 	c.PrivateApi = (*PrivateApiService)(&c.common)
 	c.PublicApi = (*PublicApiService)(&c.common)
+	c.Nonce = NewNonceSource()
+	c.limiter = newRateLimiter(cfg.RateLimit, cfg.RateBurst)
 
 	return c
 }
@@ -86,6 +91,9 @@ type Configuration struct {
 	DefaultHeader map[string]string `json:"defaultHeader,omitempty"`
 	UserAgent     string            `json:"userAgent,omitempty"`
 	HTTPClient    *http.Client
+	// requests per second of the client, opt-in, non-positive for no limit.
+	RateLimit float64 `json:"rateLimit,omitempty"`
+	RateBurst int     `json:"rateBurst,omitempty"`
 }
 
 func NewConfiguration() *Configuration {
@@ -166,6 +174,30 @@ type Currency struct {
 
 	// fixed precision of the currency
 	Precision int64 `json:"precision,omitempty"`
+
+	// crypto or fiat
+	Type string `json:"type,omitempty"`
+
+	// can be used in the M-wallet
+	MWalletSupported bool `json:"m_wallet_supported,omitempty"`
+
+	MinBorrowAmount decimal.Decimal `json:"min_borrow_amount,omitempty"`
+
+	// networks to deposit and withdraw through
+	Networks []CurrencyNetwork `json:"networks,omitempty"`
+}
+
+// a network of a currency, e.g. erc20 or trc20 of usdt.
+type CurrencyNetwork struct {
+	Id                   string          `json:"id,omitempty"`
+	NetworkProtocol      string          `json:"network_protocol,omitempty"`
+	Precision            int64           `json:"precision,omitempty"`
+	DepositEnabled       bool            `json:"deposit_enabled,omitempty"`
+	DepositConfirmations int64           `json:"deposit_confirmations,omitempty"`
+	WithdrawalEnabled    bool            `json:"withdrawal_enabled,omitempty"`
+	WithdrawalFee        decimal.Decimal `json:"withdrawal_fee,omitempty"`
+	MinWithdrawalAmount  decimal.Decimal `json:"min_withdrawal_amount,omitempty"`
+	NeedMemo             bool            `json:"need_memo,omitempty"`
 }
 
 type Member struct {
@@ -291,25 +323,57 @@ type Ticker struct {
 	At int64 `json:"at,omitempty"`
 
 	// highest buy price
-	Buy string `json:"buy,omitempty"`
+	Buy decimal.Decimal `json:"buy,omitempty"`
 
 	// lowest sell price
-	Sell string `json:"sell,omitempty"`
+	Sell decimal.Decimal `json:"sell,omitempty"`
 
 	// price before 24 hours
-	Open string `json:"open,omitempty"`
+	Open decimal.Decimal `json:"open,omitempty"`
 
 	// lowest price within 24 hours
-	Low string `json:"low,omitempty"`
+	Low decimal.Decimal `json:"low,omitempty"`
 
 	// highest price within 24 hours
-	High string `json:"high,omitempty"`
+	High decimal.Decimal `json:"high,omitempty"`
 
 	// last traded price
-	Last string `json:"last,omitempty"`
+	Last decimal.Decimal `json:"last,omitempty"`
 
 	// traded volume within 24 hours
-	Vol string `json:"vol,omitempty"`
+	Vol decimal.Decimal `json:"vol,omitempty"`
+}
+
+// get depth of a specified market
+type Depth struct {
+	// timestamp in seconds since Unix epoch
+	Timestamp int64 `json:"timestamp,omitempty"`
+
+	LastUpdateVersion int64 `json:"last_update_version,omitempty"`
+
+	LastUpdateId int64 `json:"last_update_id,omitempty"`
+
+	Asks []DepthLevel `json:"asks,omitempty"`
+
+	Bids []DepthLevel `json:"bids,omitempty"`
+}
+
+// a price level of the depth, sent as [price, volume]
+type DepthLevel struct {
+	Price  decimal.Decimal
+	Volume decimal.Decimal
+}
+
+func (l *DepthLevel) UnmarshalJSON(data []byte) error {
+	var level []decimal.Decimal
+	if err := json.Unmarshal(data, &level); err != nil {
+		return err
+	}
+	if len(level) != 2 {
+		return fmt.Errorf("invalid depth level: %s", data)
+	}
+	l.Price, l.Volume = level[0], level[1]
+	return nil
 }
 
 // get a specific order.
@@ -354,107 +418,6 @@ type Order struct {
 	TradesCount int64 `json:"trades_count,omitempty"`
 }
 
-// get ticker of all markets
-type Tickers struct {
-	Btctwd *Ticker `json:"btctwd,omitempty"`
-
-	Ethtwd *Ticker `json:"ethtwd,omitempty"`
-
-	Ltctwd *Ticker `json:"ltctwd,omitempty"`
-
-	Mithtwd *Ticker `json:"mithtwd,omitempty"`
-
-	Bchtwd *Ticker `json:"bchtwd,omitempty"`
-
-	Usdttwd *Ticker `json:"usdttwd,omitempty"`
-
-	Ethbtc *Ticker `json:"ethbtc,omitempty"`
-
-	Mitheth *Ticker `json:"mitheth,omitempty"`
-
-	Trxtwd *Ticker `json:"trxtwd,omitempty"`
-
-	Trxbtc *Ticker `json:"trxbtc,omitempty"`
-
-	Trxeth *Ticker `json:"trxeth,omitempty"`
-
-	Trxusdt *Ticker `json:"trxusdt,omitempty"`
-
-	Btcusdt *Ticker `json:"btcusdt,omitempty"`
-
-	Ethusdt *Ticker `json:"ethusdt,omitempty"`
-
-	Bchusdt *Ticker `json:"bchusdt,omitempty"`
-
-	Ltcusdt *Ticker `json:"ltcusdt,omitempty"`
-
-	Mithbtc *Ticker `json:"mithbtc,omitempty"`
-
-	Mithusdt *Ticker `json:"mithusdt,omitempty"`
-
-	Cccxbtc *Ticker `json:"cccxbtc,omitempty"`
-
-	Cccxeth *Ticker `json:"cccxeth,omitempty"`
-
-	Cccxtwd *Ticker `json:"cccxtwd,omitempty"`
-
-	Cccxusdt *Ticker `json:"cccxusdt,omitempty"`
-
-	Eosbtc *Ticker `json:"eosbtc,omitempty"`
-
-	Eoseth *Ticker `json:"eoseth,omitempty"`
-
-	Eostwd *Ticker `json:"eostwd,omitempty"`
-
-	Eosusdt *Ticker `json:"eosusdt,omitempty"`
-
-	Palbtc *Ticker `json:"palbtc,omitempty"`
-
-	Paleth *Ticker `json:"paleth,omitempty"`
-
-	Paltwd *Ticker `json:"paltwd,omitempty"`
-
-	Batbtc *Ticker `json:"batbtc,omitempty"`
-
-	Bateth *Ticker `json:"bateth,omitempty"`
-
-	Battwd *Ticker `json:"battwd,omitempty"`
-
-	Batusdt *Ticker `json:"batusdt,omitempty"`
-
-	Zrxbtc *Ticker `json:"zrxbtc,omitempty"`
-
-	Zrxeth *Ticker `json:"zrxeth,omitempty"`
-
-	Zrxtwd *Ticker `json:"zrxtwd,omitempty"`
-
-	Zrxusdt *Ticker `json:"zrxusdt,omitempty"`
-
-	Gntbtc *Ticker `json:"gntbtc,omitempty"`
-
-	Gnteth *Ticker `json:"gnteth,omitempty"`
-
-	Gnttwd *Ticker `json:"gnttwd,omitempty"`
-
-	Gntusdt *Ticker `json:"gntusdt,omitempty"`
-
-	Omgbtc *Ticker `json:"omgbtc,omitempty"`
-
-	Omgeth *Ticker `json:"omgeth,omitempty"`
-
-	Omgtwd *Ticker `json:"omgtwd,omitempty"`
-
-	Omgusdt *Ticker `json:"omgusdt,omitempty"`
-
-	Kncbtc *Ticker `json:"kncbtc,omitempty"`
-
-	Knceth *Ticker `json:"knceth,omitempty"`
-
-	Knctwd *Ticker `json:"knctwd,omitempty"`
-
-	Kncusdt *Ticker `json:"kncusdt,omitempty"`
-}
-
 // callAPI do the request.
 func (c *APIClient) callAPI(request *http.Request) (*http.Response, error) {
 	return c.cfg.HTTPClient.Do(request)
@@ -715,12 +678,13 @@ func parameterToString(obj interface{}, collectionFormat string) string {
 	return fmt.Sprintf("%v", obj)
 }
 
-func makePayloadAndSignature(bodyMAP map[string]interface{}, apisecret string) (xMAXPAYLOAD, xMAXSIGNATURE string) {
-	bodyJson, _ := json.Marshal(bodyMAP)
+func makePayloadAndSignature(bodyMAP map[string]interface{}, signer Signer) (xMAXPAYLOAD, xMAXSIGNATURE string, err error) {
+	bodyJson, err := json.Marshal(bodyMAP)
+	if err != nil {
+		return "", "", err
+	}
 	xMAXPAYLOAD = base64.StdEncoding.EncodeToString((bodyJson))
 
-	h := hmac.New(sha256.New, []byte(apisecret))
-	h.Write([]byte(xMAXPAYLOAD))
-	xMAXSIGNATURE = hex.EncodeToString(h.Sum(nil))
+	xMAXSIGNATURE, err = signer.Sign([]byte(xMAXPAYLOAD))
 	return
 }
@@ -0,0 +1,177 @@
+package max_RESTfulAPI
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/shopspring/decimal"
+)
+
+// deposit address of a currency.
+type DepositAddress struct {
+	Currency        string `json:"currency,omitempty"`
+	CurrencyVersion string `json:"currency_version,omitempty"`
+	Address         string `json:"address,omitempty"`
+	// memo or tag of the address, if the network needs one
+	ExtraLabel string `json:"extra_label,omitempty"`
+}
+
+// whitelisted withdrawal address of the address book.
+type WithdrawAddress struct {
+	Uuid            string `json:"uuid,omitempty"`
+	Currency        string `json:"currency,omitempty"`
+	CurrencyVersion string `json:"currency_version,omitempty"`
+	Address         string `json:"address,omitempty"`
+	ExtraLabel      string `json:"extra_label,omitempty"`
+	IsInternal      bool   `json:"is_internal,omitempty"`
+
+	// timestamp in seconds since Unix epoch
+	CreatedAt int64 `json:"created_at,omitempty"`
+}
+
+type Deposit struct {
+	Uuid            string          `json:"uuid,omitempty"`
+	Currency        string          `json:"currency,omitempty"`
+	CurrencyVersion string          `json:"currency_version,omitempty"`
+	Amount          decimal.Decimal `json:"amount,omitempty"`
+	Fee             decimal.Decimal `json:"fee,omitempty"`
+	Txid            string          `json:"txid,omitempty"`
+	Confirmations   int64           `json:"confirmations,omitempty"`
+	// submitting, submitted, rejected, accepted, checking, refunded, canceled, suspect ...
+	State string `json:"state,omitempty"`
+
+	// timestamp in seconds since Unix epoch
+	CreatedAt int64 `json:"created_at,omitempty"`
+	UpdatedAt int64 `json:"updated_at,omitempty"`
+}
+
+type Withdrawal struct {
+	Uuid            string          `json:"uuid,omitempty"`
+	Currency        string          `json:"currency,omitempty"`
+	CurrencyVersion string          `json:"currency_version,omitempty"`
+	Amount          decimal.Decimal `json:"amount,omitempty"`
+	Fee             decimal.Decimal `json:"fee,omitempty"`
+	FeeCurrency     string          `json:"fee_currency,omitempty"`
+	Txid            string          `json:"txid,omitempty"`
+	// submitting, submitted, rejected, accepted, suspect, approved, processing, retryable, sent, canceled, failed, pending, confirmed ...
+	State string `json:"state,omitempty"`
+	Type  string `json:"type,omitempty"`
+	Notes string `json:"notes,omitempty"`
+
+	// timestamp in seconds since Unix epoch
+	CreatedAt int64 `json:"created_at,omitempty"`
+	UpdatedAt int64 `json:"updated_at,omitempty"`
+}
+
+/*
+	PrivateApiService
+
+get the deposit addresses of a currency
+* @param ctx context.Context for authentication, logging, tracing, etc.
+@param signer signs the payload with the api key, see Signer
+@param currency unique currency id, check /api/v2/currencies for available currencies
+@return []DepositAddress
+*/
+func (a *PrivateApiService) GetApiV2DepositAddresses(ctx context.Context, signer Signer, currency string) ([]DepositAddress, *http.Response, error) {
+	var successPayload []DepositAddress
+	resp, err := a.client.DoPrivate(ctx, signer, "GET", "/api/v2/deposit_addresses", map[string]interface{}{"currency": currency}, &successPayload)
+	return successPayload, resp, err
+}
+
+/*
+	PrivateApiService
+
+get your deposit history
+* @param ctx context.Context for authentication, logging, tracing, etc.
+@param signer signs the payload with the api key, see Signer
+@param currency unique currency id, check /api/v2/currencies for available currencies
+@param optional (nil or map[string]interface{}) with one or more of:
+
+	@param "from" (int64) target period start, in seconds
+	@param "to" (int64) target period end, in seconds
+	@param "state" (string) filter by state
+	@param "limit" (int64) returned limit (1~1000, default 50)
+	@param "offset" (int64) records to skip
+
+@return []Deposit
+*/
+func (a *PrivateApiService) GetApiV2Deposits(ctx context.Context, signer Signer, currency string, localVarOptionals map[string]interface{}) ([]Deposit, *http.Response, error) {
+	var successPayload []Deposit
+	params := map[string]interface{}{"currency": currency}
+	copyOptionals(params, localVarOptionals, "from", "to", "state", "limit", "offset")
+	resp, err := a.client.DoPrivate(ctx, signer, "GET", "/api/v2/deposits", params, &successPayload)
+	return successPayload, resp, err
+}
+
+/*
+	PrivateApiService
+
+get the whitelisted withdrawal addresses of a currency
+* @param ctx context.Context for authentication, logging, tracing, etc.
+@param signer signs the payload with the api key, see Signer
+@param currency unique currency id, check /api/v2/currencies for available currencies
+@return []WithdrawAddress
+*/
+func (a *PrivateApiService) GetApiV2WithdrawAddresses(ctx context.Context, signer Signer, currency string) ([]WithdrawAddress, *http.Response, error) {
+	var successPayload []WithdrawAddress
+	resp, err := a.client.DoPrivate(ctx, signer, "GET", "/api/v2/withdraw_addresses", map[string]interface{}{"currency": currency}, &successPayload)
+	return successPayload, resp, err
+}
+
+/*
+	PrivateApiService
+
+submit a withdrawal to a whitelisted address, IP whitelist of the api key is required
+* @param ctx context.Context for authentication, logging, tracing, etc.
+@param signer signs the payload with the api key, see Signer
+@param currency unique currency id, check /api/v2/currencies for available currencies
+@param withdrawAddressUuid uuid of the address in the address book
+@param amount amount to withdraw
+@return Withdrawal
+*/
+func (a *PrivateApiService) PostApiV2Withdrawal(ctx context.Context, signer Signer, currency string, withdrawAddressUuid string, amount string) (Withdrawal, *http.Response, error) {
+	var successPayload Withdrawal
+	params := map[string]interface{}{"currency": currency, "withdraw_address_uuid": withdrawAddressUuid, "amount": amount}
+	resp, err := a.client.DoPrivate(ctx, signer, "POST", "/api/v2/withdrawal", params, &successPayload)
+	return successPayload, resp, err
+}
+
+/*
+	PrivateApiService
+
+get your withdrawal history
+* @param ctx context.Context for authentication, logging, tracing, etc.
+@param signer signs the payload with the api key, see Signer
+@param currency unique currency id, check /api/v2/currencies for available currencies
+@param optional (nil or map[string]interface{}) with one or more of:
+
+	@param "from" (int64) target period start, in seconds
+	@param "to" (int64) target period end, in seconds
+	@param "state" (string) filter by state
+	@param "limit" (int64) returned limit (1~1000, default 50)
+	@param "offset" (int64) records to skip
+
+@return []Withdrawal
+*/
+func (a *PrivateApiService) GetApiV2Withdrawals(ctx context.Context, signer Signer, currency string, localVarOptionals map[string]interface{}) ([]Withdrawal, *http.Response, error) {
+	var successPayload []Withdrawal
+	params := map[string]interface{}{"currency": currency}
+	copyOptionals(params, localVarOptionals, "from", "to", "state", "limit", "offset")
+	resp, err := a.client.DoPrivate(ctx, signer, "GET", "/api/v2/withdrawals", params, &successPayload)
+	return successPayload, resp, err
+}
+
+/*
+	PrivateApiService
+
+cancel a withdrawal which is not processed yet
+* @param ctx context.Context for authentication, logging, tracing, etc.
+@param signer signs the payload with the api key, see Signer
+@param uuid unique withdrawal id
+@return Withdrawal
+*/
+func (a *PrivateApiService) PostApiV2WithdrawalCancel(ctx context.Context, signer Signer, uuid string) (Withdrawal, *http.Response, error) {
+	var successPayload Withdrawal
+	resp, err := a.client.DoPrivate(ctx, signer, "POST", "/api/v2/withdrawal/cancel", map[string]interface{}{"uuid": uuid}, &successPayload)
+	return successPayload, resp, err
+}
@@ -0,0 +1,403 @@
+package max_RESTfulAPI
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/shopspring/decimal"
+)
+
+// WalletType scopes the v3 orders, trades and accounts.
+type WalletType string
+
+const (
+	WalletSpot WalletType = "spot"
+	// the M-wallet, margin trading of the markets with MWalletSupported.
+	WalletM WalletType = "m"
+)
+
+// order of the v3 api.
+type WalletOrder struct {
+	Id              int64           `json:"id,omitempty"`
+	WalletType      WalletType      `json:"wallet_type,omitempty"`
+	Market          string          `json:"market,omitempty"`
+	ClientOid       string          `json:"client_oid,omitempty"`
+	GroupId         int64           `json:"group_id,omitempty"`
+	Side            string          `json:"side,omitempty"`
+	State           string          `json:"state,omitempty"`
+	OrdType         string          `json:"ord_type,omitempty"`
+	Price           decimal.Decimal `json:"price,omitempty"`
+	StopPrice       decimal.Decimal `json:"stop_price,omitempty"`
+	AvgPrice        decimal.Decimal `json:"avg_price,omitempty"`
+	Volume          decimal.Decimal `json:"volume,omitempty"`
+	RemainingVolume decimal.Decimal `json:"remaining_volume,omitempty"`
+	ExecutedVolume  decimal.Decimal `json:"executed_volume,omitempty"`
+	TradesCount     int64           `json:"trades_count,omitempty"`
+
+	// in milliseconds
+	CreatedAt int64 `json:"created_at,omitempty"`
+	UpdatedAt int64 `json:"updated_at,omitempty"`
+}
+
+// trade of the v3 api.
+type WalletTrade struct {
+	Id          int64           `json:"id,omitempty"`
+	OrderId     int64           `json:"order_id,omitempty"`
+	WalletType  WalletType      `json:"wallet_type,omitempty"`
+	Market      string          `json:"market,omitempty"`
+	Price       decimal.Decimal `json:"price,omitempty"`
+	Volume      decimal.Decimal `json:"volume,omitempty"`
+	Funds       decimal.Decimal `json:"funds,omitempty"`
+	Side        string          `json:"side,omitempty"`
+	Fee         decimal.Decimal `json:"fee,omitempty"`
+	FeeCurrency string          `json:"fee_currency,omitempty"`
+	// 'maker' or 'taker'
+	Liquidity string `json:"liquidity,omitempty"`
+
+	// in milliseconds
+	CreatedAt int64 `json:"created_at,omitempty"`
+}
+
+// account of a wallet, the principal and interest are of the M-wallet only.
+type WalletAccount struct {
+	Currency  string          `json:"currency,omitempty"`
+	Balance   decimal.Decimal `json:"balance,omitempty"`
+	Locked    decimal.Decimal `json:"locked,omitempty"`
+	Staked    decimal.Decimal `json:"staked,omitempty"`
+	Principal decimal.Decimal `json:"principal,omitempty"`
+	Interest  decimal.Decimal `json:"interest,omitempty"`
+}
+
+/*
+	PrivateApiService
+
+create a sell/buy order in a wallet
+* @param ctx context.Context for authentication, logging, tracing, etc.
+@param signer signs the payload with the api key, see Signer
+@param wallet spot or m
+@param market unique market id, check /api/v2/markets for available markets
+@param side &#39;sell&#39; or &#39;buy&#39;
+@param volume total amount to sell/buy, an order could be partially executed
+@param optional (nil or map[string]interface{}) with one or more of:
+
+	@param "price" (string) price of a unit
+	@param "stop_price" (string) price to trigger a stop order
+	@param "ord_type" (string) &#39;limit&#39;, &#39;market&#39;, &#39;stop_limit&#39;, &#39;stop_market&#39;, &#39;post_only&#39; or &#39;ioc_limit&#39;
+	@param "client_oid" (string) user specific order id, unique within 24 hours
+	@param "group_id" (int64) user specific group id
+
+@return WalletOrder
+*/
+func (a *PrivateApiService) PostApiV3WalletOrder(ctx context.Context, signer Signer, wallet WalletType, market string, side string, volume string, localVarOptionals map[string]interface{}) (WalletOrder, *http.Response, error) {
+	var successPayload WalletOrder
+	params := map[string]interface{}{"path_wallet_type": wallet, "market": market, "side": side, "volume": volume}
+	copyOptionals(params, localVarOptionals, "price", "stop_price", "ord_type", "client_oid", "group_id")
+	resp, err := a.client.DoPrivate(ctx, signer, "POST", "/api/v3/wallet/{path_wallet_type}/order", params, &successPayload)
+	return successPayload, resp, err
+}
+
+/*
+	PrivateApiService
+
+cancel an order of any wallet, by id or client_oid
+* @param ctx context.Context for authentication, logging, tracing, etc.
+@param signer signs the payload with the api key, see Signer
+@param optional (map[string]interface{}) with one of:
+
+	@param "id" (int64) unique order id
+	@param "client_oid" (string) user specific order id
+
+@return WalletOrder
+*/
+func (a *PrivateApiService) DeleteApiV3Order(ctx context.Context, signer Signer, localVarOptionals map[string]interface{}) (WalletOrder, *http.Response, error) {
+	var successPayload WalletOrder
+	params := make(map[string]interface{})
+	copyOptionals(params, localVarOptionals, "id", "client_oid")
+	resp, err := a.client.DoPrivate(ctx, signer, "DELETE", "/api/v3/order", params, &successPayload)
+	return successPayload, resp, err
+}
+
+/*
+	PrivateApiService
+
+cancel all your orders of a wallet with given market and side
+* @param ctx context.Context for authentication, logging, tracing, etc.
+@param signer signs the payload with the api key, see Signer
+@param wallet spot or m
+@param optional (nil or map[string]interface{}) with one or more of:
+
+	@param "market" (string) specify market like btctwd / ethbtc
+	@param "side" (string) set to cancel only sell or buy orders
+	@param "group_id" (int64) cancel the orders of a group
+
+@return []WalletOrder the canceled orders, the ones which failed are joined in the error
+*/
+func (a *PrivateApiService) DeleteApiV3WalletOrders(ctx context.Context, signer Signer, wallet WalletType, localVarOptionals map[string]interface{}) ([]WalletOrder, *http.Response, error) {
+	var successPayload []struct {
+		Order WalletOrder `json:"order"`
+		Error string      `json:"error"`
+	}
+	params := map[string]interface{}{"path_wallet_type": wallet}
+	copyOptionals(params, localVarOptionals, "market", "side", "group_id")
+	resp, err := a.client.DoPrivate(ctx, signer, "DELETE", "/api/v3/wallet/{path_wallet_type}/orders", params, &successPayload)
+
+	orders := make([]WalletOrder, 0, len(successPayload))
+	var errs []error
+	for _, result := range successPayload {
+		if result.Error != "" {
+			errs = append(errs, fmt.Errorf("fail to cancel order %d: %s", result.Order.Id, result.Error))
+			continue
+		}
+		orders = append(orders, result.Order)
+	}
+	if err == nil {
+		err = errors.Join(errs...)
+	}
+	return orders, resp, err
+}
+
+/*
+	PrivateApiService
+
+get the open orders of a wallet
+* @param ctx context.Context for authentication, logging, tracing, etc.
+@param signer signs the payload with the api key, see Signer
+@param wallet spot or m
+@param market unique market id, check /api/v2/markets for available markets
+@param optional (nil or map[string]interface{}) with one or more of:
+
+	@param "timestamp" (int64) milliseconds, orders created before it
+	@param "order_by" (string) asc or desc, default to desc
+	@param "limit" (int64) returned limit (1~1000, default 50)
+
+@return []WalletOrder
+*/
+func (a *PrivateApiService) GetApiV3WalletOrdersOpen(ctx context.Context, signer Signer, wallet WalletType, market string, localVarOptionals map[string]interface{}) ([]WalletOrder, *http.Response, error) {
+	var successPayload []WalletOrder
+	params := map[string]interface{}{"path_wallet_type": wallet, "market": market}
+	copyOptionals(params, localVarOptionals, "timestamp", "order_by", "limit")
+	resp, err := a.client.DoPrivate(ctx, signer, "GET", "/api/v3/wallet/{path_wallet_type}/orders/open", params, &successPayload)
+	return successPayload, resp, err
+}
+
+/*
+	PrivateApiService
+
+get your trades of a wallet
+* @param ctx context.Context for authentication, logging, tracing, etc.
+@param signer signs the payload with the api key, see Signer
+@param wallet spot or m
+@param market unique market id, check /api/v2/markets for available markets
+@param optional (nil or map[string]interface{}) with one or more of:
+
+	@param "timestamp" (int64) milliseconds, trades before or after it depending on order_by
+	@param "from_id" (int64) trades after the trade id
+	@param "order_by" (string) asc or desc, default to desc
+	@param "limit" (int64) returned limit (1~1000, default 50)
+
+@return []WalletTrade
+*/
+func (a *PrivateApiService) GetApiV3WalletTrades(ctx context.Context, signer Signer, wallet WalletType, market string, localVarOptionals map[string]interface{}) ([]WalletTrade, *http.Response, error) {
+	var successPayload []WalletTrade
+	params := map[string]interface{}{"path_wallet_type": wallet, "market": market}
+	copyOptionals(params, localVarOptionals, "timestamp", "from_id", "order_by", "limit")
+	resp, err := a.client.DoPrivate(ctx, signer, "GET", "/api/v3/wallet/{path_wallet_type}/trades", params, &successPayload)
+	return successPayload, resp, err
+}
+
+/*
+	PrivateApiService
+
+get the accounts of a wallet
+* @param ctx context.Context for authentication, logging, tracing, etc.
+@param signer signs the payload with the api key, see Signer
+@param wallet spot or m
+@param optional (nil or map[string]interface{}) with one or more of:
+
+	@param "currency" (string) account of a currency only
+
+@return []WalletAccount
+*/
+func (a *PrivateApiService) GetApiV3WalletAccounts(ctx context.Context, signer Signer, wallet WalletType, localVarOptionals map[string]interface{}) ([]WalletAccount, *http.Response, error) {
+	var successPayload []WalletAccount
+	params := map[string]interface{}{"path_wallet_type": wallet}
+	copyOptionals(params, localVarOptionals, "currency")
+	resp, err := a.client.DoPrivate(ctx, signer, "GET", "/api/v3/wallet/{path_wallet_type}/accounts", params, &successPayload)
+	return successPayload, resp, err
+}
+
+// loan of the M-wallet.
+type Loan struct {
+	Sn           string          `json:"sn,omitempty"`
+	Currency     string          `json:"currency,omitempty"`
+	Amount       decimal.Decimal `json:"amount,omitempty"`
+	State        string          `json:"state,omitempty"`
+	InterestRate decimal.Decimal `json:"interest_rate,omitempty"`
+
+	// in milliseconds
+	CreatedAt int64 `json:"created_at,omitempty"`
+}
+
+// repayment of the M-wallet.
+type Repayment struct {
+	Sn        string          `json:"sn,omitempty"`
+	Currency  string          `json:"currency,omitempty"`
+	Amount    decimal.Decimal `json:"amount,omitempty"`
+	Principal decimal.Decimal `json:"principal,omitempty"`
+	Interest  decimal.Decimal `json:"interest,omitempty"`
+	State     string          `json:"state,omitempty"`
+
+	// in milliseconds
+	CreatedAt int64 `json:"created_at,omitempty"`
+}
+
+// interest charged on a loan of the M-wallet.
+type Interest struct {
+	Currency     string          `json:"currency,omitempty"`
+	Amount       decimal.Decimal `json:"amount,omitempty"`
+	InterestRate decimal.Decimal `json:"interest_rate,omitempty"`
+	Principal    decimal.Decimal `json:"principal,omitempty"`
+
+	// in milliseconds
+	CreatedAt int64 `json:"created_at,omitempty"`
+}
+
+// AD ratio of the M-wallet, the assets over the debts, both in usdt.
+type ADRatio struct {
+	ADRatio     decimal.Decimal `json:"ad_ratio,omitempty"`
+	AssetInUsdt decimal.Decimal `json:"asset_in_usdt,omitempty"`
+	DebtInUsdt  decimal.Decimal `json:"debt_in_usdt,omitempty"`
+}
+
+/*
+	PrivateApiService
+
+borrow from the M-wallet
+* @param ctx context.Context for authentication, logging, tracing, etc.
+@param signer signs the payload with the api key, see Signer
+@param currency currency id, e.g. usdt
+@param amount amount to borrow
+@return Loan
+*/
+func (a *PrivateApiService) PostApiV3WalletMLoan(ctx context.Context, signer Signer, currency string, amount string) (Loan, *http.Response, error) {
+	var successPayload Loan
+	params := map[string]interface{}{"currency": currency, "amount": amount}
+	resp, err := a.client.DoPrivate(ctx, signer, "POST", "/api/v3/wallet/m/loan", params, &successPayload)
+	return successPayload, resp, err
+}
+
+/*
+	PrivateApiService
+
+repay a loan of the M-wallet
+* @param ctx context.Context for authentication, logging, tracing, etc.
+@param signer signs the payload with the api key, see Signer
+@param currency currency id, e.g. usdt
+@param amount amount to repay, interest first
+@return Repayment
+*/
+func (a *PrivateApiService) PostApiV3WalletMRepayment(ctx context.Context, signer Signer, currency string, amount string) (Repayment, *http.Response, error) {
+	var successPayload Repayment
+	params := map[string]interface{}{"currency": currency, "amount": amount}
+	resp, err := a.client.DoPrivate(ctx, signer, "POST", "/api/v3/wallet/m/repayment", params, &successPayload)
+	return successPayload, resp, err
+}
+
+/*
+	PrivateApiService
+
+get the loan history of the M-wallet
+* @param ctx context.Context for authentication, logging, tracing, etc.
+@param signer signs the payload with the api key, see Signer
+@param currency currency id, e.g. usdt
+@param optional (nil or map[string]interface{}) with one or more of:
+
+	@param "timestamp" (int64) milliseconds, records before or after it depending on order_by
+	@param "order_by" (string) asc or desc, default to desc
+	@param "limit" (int64) returned limit (1~1000, default 50)
+
+@return []Loan
+*/
+func (a *PrivateApiService) GetApiV3WalletMLoans(ctx context.Context, signer Signer, currency string, localVarOptionals map[string]interface{}) ([]Loan, *http.Response, error) {
+	var successPayload []Loan
+	params := map[string]interface{}{"currency": currency}
+	copyOptionals(params, localVarOptionals, "timestamp", "order_by", "limit")
+	resp, err := a.client.DoPrivate(ctx, signer, "GET", "/api/v3/wallet/m/loans", params, &successPayload)
+	return successPayload, resp, err
+}
+
+/*
+	PrivateApiService
+
+get the repayment history of the M-wallet
+* @param ctx context.Context for authentication, logging, tracing, etc.
+@param signer signs the payload with the api key, see Signer
+@param currency currency id, e.g. usdt
+@param optional (nil or map[string]interface{}) with one or more of:
+
+	@param "timestamp" (int64) milliseconds, records before or after it depending on order_by
+	@param "order_by" (string) asc or desc, default to desc
+	@param "limit" (int64) returned limit (1~1000, default 50)
+
+@return []Repayment
+*/
+func (a *PrivateApiService) GetApiV3WalletMRepayments(ctx context.Context, signer Signer, currency string, localVarOptionals map[string]interface{}) ([]Repayment, *http.Response, error) {
+	var successPayload []Repayment
+	params := map[string]interface{}{"currency": currency}
+	copyOptionals(params, localVarOptionals, "timestamp", "order_by", "limit")
+	resp, err := a.client.DoPrivate(ctx, signer, "GET", "/api/v3/wallet/m/repayments", params, &successPayload)
+	return successPayload, resp, err
+}
+
+/*
+	PrivateApiService
+
+get the interest history of the M-wallet
+* @param ctx context.Context for authentication, logging, tracing, etc.
+@param signer signs the payload with the api key, see Signer
+@param currency currency id, e.g. usdt
+@param optional (nil or map[string]interface{}) with one or more of:
+
+	@param "timestamp" (int64) milliseconds, records before or after it depending on order_by
+	@param "order_by" (string) asc or desc, default to desc
+	@param "limit" (int64) returned limit (1~1000, default 50)
+
+@return []Interest
+*/
+func (a *PrivateApiService) GetApiV3WalletMInterests(ctx context.Context, signer Signer, currency string, localVarOptionals map[string]interface{}) ([]Interest, *http.Response, error) {
+	var successPayload []Interest
+	params := map[string]interface{}{"currency": currency}
+	copyOptionals(params, localVarOptionals, "timestamp", "order_by", "limit")
+	resp, err := a.client.DoPrivate(ctx, signer, "GET", "/api/v3/wallet/m/interests", params, &successPayload)
+	return successPayload, resp, err
+}
+
+/*
+	PrivateApiService
+
+get the max borrowable amount of every currency of the M-wallet
+* @param ctx context.Context for authentication, logging, tracing, etc.
+@param signer signs the payload with the api key, see Signer
+@return map[string]decimal.Decimal, keyed by currency
+*/
+func (a *PrivateApiService) GetApiV3WalletMLimits(ctx context.Context, signer Signer) (map[string]decimal.Decimal, *http.Response, error) {
+	var successPayload map[string]decimal.Decimal
+	resp, err := a.client.DoPrivate(ctx, signer, "GET", "/api/v3/wallet/m/limits", nil, &successPayload)
+	return successPayload, resp, err
+}
+
+/*
+	PrivateApiService
+
+get the AD ratio of the M-wallet
+* @param ctx context.Context for authentication, logging, tracing, etc.
+@param signer signs the payload with the api key, see Signer
+@return ADRatio
+*/
+func (a *PrivateApiService) GetApiV3WalletMAdRatio(ctx context.Context, signer Signer) (ADRatio, *http.Response, error) {
+	var successPayload ADRatio
+	resp, err := a.client.DoPrivate(ctx, signer, "GET", "/api/v3/wallet/m/ad_ratio", nil, &successPayload)
+	return successPayload, resp, err
+}
@@ -4,14 +4,12 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net/http"
 	"strconv"
-	"strings"
-
-	"github.com/shopspring/decimal"
 )
 
 func (Mc *MaxClient) GetAccount() (Member, error) {
-	member, _, err := Mc.ApiClient.PrivateApi.GetApiV2MembersAccounts(context.Background(), Mc.apiKey, Mc.apiSecret)
+	member, _, err := Mc.ApiClient.PrivateApi.GetApiV2MembersAccounts(context.Background(), Mc.signer)
 	if err != nil {
 		return Member{}, errors.New("fail to get account")
 	}
@@ -22,13 +20,13 @@ func (Mc *MaxClient) GetAccount() (Member, error) {
 func (Mc *MaxClient) GetBalance() (map[string]Balance, error) {
 	localbalance := map[string]Balance{}
 
-	member, _, err := Mc.ApiClient.PrivateApi.GetApiV2MembersAccounts(context.Background(), Mc.apiKey, Mc.apiSecret)
+	member, _, err := Mc.ApiClient.PrivateApi.GetApiV2MembersAccounts(context.Background(), Mc.signer)
 	if err != nil {
 		return map[string]Balance{}, err
 	}
 	Accounts := member.Accounts
 	for i := 0; i < len(Accounts); i++ {
-		currency := Accounts[i].Currency
+		currency := NormalizeCurrency(Accounts[i].Currency)
 		balance, err := strconv.ParseFloat(Accounts[i].Balance, 64)
 		if err != nil {
 
@@ -54,7 +52,7 @@ func (Mc *MaxClient) GetBalance() (map[string]Balance, error) {
 
 // Get orders of the coresponding $market
 func (Mc *MaxClient) GetOrders(market string) (map[int64]WsOrder, error) {
-	orders, _, err := Mc.ApiClient.PrivateApi.GetApiV2Orders(context.Background(), Mc.apiKey, Mc.apiSecret, market, nil)
+	orders, _, err := Mc.ApiClient.PrivateApi.GetApiV2Orders(context.Background(), Mc.signer, market, nil)
 	if err != nil {
 		return map[int64]WsOrder{}, err
 	}
@@ -72,7 +70,7 @@ func (Mc *MaxClient) GetOrders(market string) (map[int64]WsOrder, error) {
 func (Mc *MaxClient) GetAllOrders() (map[int64]WsOrder, error) {
 	newOrders := map[int64]WsOrder{}
 
-	orders, _, err := Mc.ApiClient.PrivateApi.GetApiV2Orders(context.Background(), Mc.apiKey, Mc.apiSecret, "all", nil)
+	orders, _, err := Mc.ApiClient.PrivateApi.GetApiV2Orders(context.Background(), Mc.signer, "all", nil)
 
 	if err != nil {
 		return map[int64]WsOrder{}, err
@@ -92,16 +90,28 @@ func (Mc *MaxClient) GetMarkets() ([]Market, error) {
 	if err != nil {
 		return []Market{}, err
 	}
-	Mc.MarketsBranch.Markets = markets
+	Mc.MarketsBranch.Markets = normalizeMarkets(markets)
 	return markets, nil
 }
 
+func (Mc *MaxClient) GetCurrencies() ([]Currency, error) {
+	currencies, _, err := Mc.ApiClient.PublicApi.GetApiV2Currencies(context.Background())
+	if err != nil {
+		return []Currency{}, err
+	}
+	Mc.setCurrencies(currencies)
+	return Mc.ReadCurrencies(), nil
+}
+
 func (Mc *MaxClient) CancelAllOrders() ([]WsOrder, error) {
 
-	canceledOrders, _, err := Mc.ApiClient.PrivateApi.PostApiV2OrdersClear(context.Background(), Mc.apiKey, Mc.apiSecret, nil)
+	canceledOrders, _, err := Mc.ApiClient.PrivateApi.PostApiV2OrdersClear(context.Background(), Mc.signer, nil)
 	if err != nil {
 		return []WsOrder{}, errors.New("fail to cancel all orders")
 	}
+	if g := Mc.ReadRiskGate(); g != nil {
+		g.allOrdersCanceled()
+	}
 	canceledWsOrders := make([]WsOrder, 0, len(canceledOrders))
 	for i := 0; i < len(canceledOrders); i++ {
 		canceledWsOrders = append(canceledWsOrders, WsOrder(canceledOrders[i]))
@@ -115,22 +125,49 @@ func (Mc *MaxClient) CancelOrder(market string, id, clientId interface{}) (wsOrd
 	if clientId == nil && id == nil {
 		return WsOrder{}, errors.New("no order found")
 	} else if clientId == nil {
-		canceledorder, _, err = Mc.ApiClient.PrivateApi.PostApiV2OrderDelete(context.Background(), Mc.apiKey, Mc.apiSecret, id.(int64))
+		canceledorder, _, err = Mc.ApiClient.PrivateApi.PostApiV2OrderDelete(context.Background(), Mc.signer, id.(int64))
 		if err != nil {
 			return WsOrder{}, errors.New("fail to cancel order" + strconv.Itoa(int(id.(int64))))
 		}
 		Mc.logger.Info("Cancel Order ", id, "by CancelOrder func.")
 	} else if id == nil {
-		canceledorder, _, err = Mc.ApiClient.PrivateApi.PostApiV2OrderDeleteClientId(context.Background(), Mc.apiKey, Mc.apiSecret, clientId.(string))
+		canceledorder, _, err = Mc.ApiClient.PrivateApi.PostApiV2OrderDeleteClientId(context.Background(), Mc.signer, clientId.(string))
 		if err != nil {
 			return WsOrder{}, errors.New("fail to cancel order" + clientId.(string))
 		}
 		Mc.logger.Info("Cancel Order with client_id ", clientId.(string), "by CancelOrder func.")
 	}
 
+	if g := Mc.ReadRiskGate(); g != nil {
+		g.orderCanceled(WsOrder(canceledorder))
+	}
+
 	return WsOrder(canceledorder), nil
 }
 
+// GetOrder gets an order by id, or by client oid when id is nil.
+// It fails with ErrOrderNotFound when MAX has no such order.
+func (Mc *MaxClient) GetOrder(market string, id, clientId interface{}) (WsOrder, error) {
+	params := make(map[string]interface{})
+	switch {
+	case id != nil:
+		params["id"] = id
+	case clientId != nil:
+		params["client_oid"] = clientId
+	default:
+		return WsOrder{}, errors.New("no order found")
+	}
+	order, _, err := Mc.ApiClient.PrivateApi.GetApiV2Order(context.Background(), Mc.signer, params)
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound {
+		return WsOrder{}, fmt.Errorf("%w: %v", ErrOrderNotFound, err)
+	}
+	if err != nil {
+		return WsOrder{}, fmt.Errorf("fail to get order: %w", err)
+	}
+	return WsOrder(order), nil
+}
+
 /*
 "side" (string) set tp cancel only sell (asks) or buy (bids) orders
 "market" (string) specify market like btctwd / ethbtc
@@ -144,149 +181,90 @@ func (Mc *MaxClient) CancelOrders(market, side interface{}) ([]WsOrder, error) {
 	if side != nil {
 		params["side"] = side.(string)
 	}
-	// bug exist
-	canceledOrders, _, err := Mc.ApiClient.PrivateApi.PostApiV2OrdersClear(context.Background(), Mc.apiKey, Mc.apiSecret, params)
+	canceledOrders, _, err := Mc.ApiClient.PrivateApi.PostApiV2OrdersClear(context.Background(), Mc.signer, params)
 	if err != nil {
 		fmt.Println(err)
 		return []WsOrder{}, err
 	}
 	canceledWsOrders := make([]WsOrder, 0, len(canceledOrders))
+	for _, order := range canceledOrders {
+		canceledWsOrders = append(canceledWsOrders, WsOrder(order))
+	}
+	if g := Mc.ReadRiskGate(); g != nil {
+		for _, order := range canceledWsOrders {
+			g.orderCanceled(order)
+		}
+	}
 
 	return canceledWsOrders, nil
 }
 
 func (Mc *MaxClient) PlaceLimitOrder(market string, side string, price, volume float64) (WsOrder, error) {
-	/* if isEnough := Mc.checkBalanceEnoughLocal(market, side, price, volume); !isEnough {
-		return WsOrder{}, errors.New("balance is not enough for trading")
-	} */
+	return Mc.submitOrder(market, side, "limit", price, volume, "")
+}
 
-	params := make(map[string]interface{})
-	params["price"] = fmt.Sprint(price)
-	params["ord_type"] = "limit"
-	vol := fmt.Sprint(volume)
+func (Mc *MaxClient) PlacePostOnlyOrder(market string, side string, price, volume float64) (WsOrder, error) {
+	return Mc.submitOrder(market, side, "post_only", price, volume, "")
+}
 
-	order, _, err := Mc.ApiClient.PrivateApi.PostApiV2Orders(context.Background(), Mc.apiKey, Mc.apiSecret, market, side, vol, params)
-	if err != nil {
-		return WsOrder{}, err
+func (Mc *MaxClient) PlaceMarketOrder(market string, side string, volume float64) (WsOrder, error) {
+	order, err := Mc.submitOrder(market, side, "market", 0, volume, "")
+	if err != nil && !errors.Is(err, ErrRiskRejected) && !errors.Is(err, ErrCircuitOpen) {
+		return WsOrder{}, errors.New("fail to place market orders")
 	}
-
-	return WsOrder(order), nil
+	return order, err
 }
 
-func (Mc *MaxClient) PlacePostOnlyOrder(market string, side string, price, volume float64) (WsOrder, error) {
-	/* if isEnough := Mc.checkBalanceEnoughLocal(market, side, price, volume); !isEnough {
-		return WsOrder{}, errors.New("balance is not enough for trading")
-	} */
-
-	params := make(map[string]interface{})
-	params["price"] = fmt.Sprint(price)
-	params["ord_type"] = "post_only"
-	vol := fmt.Sprint(volume)
+// PlaceTaggedOrder places an order of ordType "limit", "post_only" or "market" tagged with clientOid,
+// up to 36 alphanumerics and dashes, unique per account. price is ignored for market orders.
+func (Mc *MaxClient) PlaceTaggedOrder(market, side, ordType string, price, volume float64, clientOid string) (WsOrder, error) {
+	return Mc.submitOrder(market, side, ordType, price, volume, clientOid)
+}
 
-	order, _, err := Mc.ApiClient.PrivateApi.PostApiV2Orders(context.Background(), Mc.apiKey, Mc.apiSecret, market, side, vol, params)
+// every order placement goes through here, so the risk gate sees all of them.
+func (Mc *MaxClient) submitOrder(market, side, ordType string, price, volume float64, clientOid string) (WsOrder, error) {
+	if err := Mc.checkBreaker(); err != nil {
+		return WsOrder{}, err
+	}
+	ticket, err := Mc.admitRisk(market, side, ordType, price, volume)
 	if err != nil {
+		Mc.breakerOrderRejected()
 		return WsOrder{}, err
 	}
 
-	return WsOrder(order), nil
-}
-
-func (Mc *MaxClient) PlaceMarketOrder(market string, side string, volume float64) (WsOrder, error) {
 	params := make(map[string]interface{})
-	params["ord_type"] = "market"
+	if ordType != "market" {
+		params["price"] = fmt.Sprint(price)
+	}
+	params["ord_type"] = ordType
+	if clientOid != "" {
+		params["client_oid"] = clientOid
+	}
 	vol := fmt.Sprint(volume)
-	order, _, err := Mc.ApiClient.PrivateApi.PostApiV2Orders(context.Background(), Mc.apiKey, Mc.apiSecret, market, side, vol, params)
+
+	order, _, err := Mc.ApiClient.PrivateApi.PostApiV2Orders(context.Background(), Mc.signer, market, side, vol, params)
 	if err != nil {
-		return WsOrder{}, errors.New("fail to place market orders")
+		ticket.release()
+		Mc.breakerOrderRejected()
+		return WsOrder{}, err
 	}
 
+	ticket.placed(WsOrder(order))
 	return WsOrder(order), nil
 }
 
-// for modularized arbitrage framework
+// for modularized arbitrage framework, the typed rows are in exchange.go.
 // GetBalances() ([][]string, bool)     // []string{asset, available, total}
 func (Mc *MaxClient) GetBalances() (balances [][]string, ok bool) {
-	member, _, err := Mc.ApiClient.PrivateApi.GetApiV2MembersAccounts(context.Background(), Mc.apiKey, Mc.apiSecret)
-	if err != nil {
-		return [][]string{}, false
-	}
-	Accounts := member.Accounts
-	for i := 0; i < len(Accounts); i++ {
-		currency := Accounts[i].Currency
-		available, err := decimal.NewFromString(Accounts[i].Balance)
-		if err != nil {
-			available = decimal.Zero
-		}
-		locked, err := decimal.NewFromString(Accounts[i].Locked)
-		if err != nil {
-			locked = decimal.Zero
-		}
-
-		// handle the case here.
-		balances = append(balances, []string{strings.ToUpper(currency), available.String(), (available.Add(locked)).String()})
-	}
-	return balances, true
+	return LegacyAdapter{Mc}.GetBalances()
 }
 
 // GetOpenOrders() ([][]string, bool)   // []string{oid, symbol, product, subaccount, price, qty, side, execType, UnfilledQty}
 func (Mc *MaxClient) GetOpenOrders() (openOrders [][]string, ok bool) {
-	orders, _, err := Mc.ApiClient.PrivateApi.GetApiV2Orders(context.Background(), Mc.apiKey, Mc.apiSecret, "all", nil)
-	if err != nil {
-		return [][]string{}, false
-	}
-
-	for _, v := range orders {
-		// []string{oid, symbol, product, subaccount, price, qty, side, execType, unfilledQty}
-		oid := fmt.Sprint(v.Id)
-		symbol := v.Market
-		product := "spot"
-		price := v.Price
-		qty := v.Volume
-		side := v.Side
-		execType := v.OrdType
-		unfilledQty := v.RemainingVolume
-
-		openOrder := []string{oid, symbol, product, "", price, qty, side, execType, unfilledQty}
-
-		openOrders = append(openOrders, openOrder)
-	}
-
-	return openOrders, true
+	return LegacyAdapter{Mc}.GetOpenOrders()
 }
 
 // GetTradeReports() ([][]string, bool) // []string{oid, symbol, product, subaccount, price, qty, side, execType, fee, filledQty, timestamp, isMaker}
 func (Mc *MaxClient) GetTradeReports() ([][]string, bool) {
-	Mc.TradeReportBranch.Lock()
-	trades := Mc.TradeReportBranch.TradeReports
-	Mc.TradeReportBranch.TradeReports = []Trade{}
-	Mc.TradeReportBranch.Unlock()
-
-	var tradeReports [][]string
-	for i := range trades {
-		trade := trades[i]
-		oid := fmt.Sprint(trade.Oid)
-		symbol := trade.Market
-		product := "spot"
-		subaccount := ""
-		price := trade.Price
-		qty := trade.Volume
-		side := trade.Side
-		maker := trade.Maker
-		execType := "limit"
-		if !maker {
-			execType = "market"
-		}
-		isMaker := "false"
-		if trade.Maker {
-			isMaker = "true"
-		}
-		timestamp := strconv.Itoa(int(trade.Timestamp))
-		fee := trade.Fee
-		filledQty := trade.Volume
-
-		tradeReport := []string{oid, symbol, product, subaccount, price, qty, side, execType, fee, filledQty, timestamp, isMaker}
-		tradeReports = append(tradeReports, tradeReport)
-	}
-
-	return tradeReports, len(tradeReports) != 0
+	return LegacyAdapter{Mc}.GetTradeReports()
 }
@@ -0,0 +1,150 @@
+package max_RESTfulAPI
+
+import (
+	"context"
+	"fmt"
+)
+
+// the v3 wallet scoped wrappers, alongside the v2 ones so the strategies can migrate one call at a time.
+
+// GetWalletBalance gets the balances of a wallet into a map with key denote asset.
+func (Mc *MaxClient) GetWalletBalance(wallet WalletType) (map[string]Balance, error) {
+	accounts, _, err := Mc.ApiClient.PrivateApi.GetApiV3WalletAccounts(context.Background(), Mc.signer, wallet, nil)
+	if err != nil {
+		return map[string]Balance{}, err
+	}
+
+	balances := make(map[string]Balance, len(accounts))
+	for _, account := range accounts {
+		available, _ := account.Balance.Float64()
+		locked, _ := account.Locked.Float64()
+		currency := NormalizeCurrency(account.Currency)
+		balances[currency] = Balance{
+			Name:      currency,
+			Avaliable: available,
+			Locked:    locked,
+		}
+	}
+	return balances, nil
+}
+
+// GetWalletOpenOrders gets the open orders of a market in a wallet.
+func (Mc *MaxClient) GetWalletOpenOrders(wallet WalletType, market string) ([]WalletOrder, error) {
+	orders, _, err := Mc.ApiClient.PrivateApi.GetApiV3WalletOrdersOpen(context.Background(), Mc.signer, wallet, NormalizeMarket(market), nil)
+	if err != nil {
+		return []WalletOrder{}, err
+	}
+	return orders, nil
+}
+
+// GetWalletTrades gets the trades of a market in a wallet after the trade fromId, zero for the latest ones.
+func (Mc *MaxClient) GetWalletTrades(wallet WalletType, market string, fromId int64) ([]WalletTrade, error) {
+	params := make(map[string]interface{})
+	if fromId > 0 {
+		params["from_id"] = fromId
+		params["order_by"] = "asc"
+	}
+	trades, _, err := Mc.ApiClient.PrivateApi.GetApiV3WalletTrades(context.Background(), Mc.signer, wallet, NormalizeMarket(market), params)
+	if err != nil {
+		return []WalletTrade{}, err
+	}
+	return trades, nil
+}
+
+// PlaceWalletOrder places an order in a wallet, going through the breaker like the v2 orders.
+// Only the spot orders go through the risk gate, its balances and reservations are the spot ones.
+// clientOid may be empty.
+func (Mc *MaxClient) PlaceWalletOrder(wallet WalletType, market, side, ordType string, price, volume float64, clientOid string) (WalletOrder, error) {
+	if err := Mc.checkBreaker(); err != nil {
+		return WalletOrder{}, err
+	}
+	var ticket *riskTicket
+	if wallet == WalletSpot {
+		var err error
+		if ticket, err = Mc.admitRisk(market, side, ordType, price, volume); err != nil {
+			Mc.breakerOrderRejected()
+			return WalletOrder{}, err
+		}
+	}
+
+	params := make(map[string]interface{})
+	if ordType != "market" {
+		params["price"] = fmt.Sprint(price)
+	}
+	params["ord_type"] = ordType
+	if clientOid != "" {
+		params["client_oid"] = clientOid
+	}
+
+	order, _, err := Mc.ApiClient.PrivateApi.PostApiV3WalletOrder(context.Background(), Mc.signer, wallet, NormalizeMarket(market), side, fmt.Sprint(volume), params)
+	if err != nil {
+		ticket.release()
+		Mc.breakerOrderRejected()
+		return WalletOrder{}, err
+	}
+
+	ticket.placed(order.WsOrder())
+	return order, nil
+}
+
+// CancelWalletOrder cancels an order of any wallet by id, or by clientOid if id is zero.
+func (Mc *MaxClient) CancelWalletOrder(id int64, clientOid string) (WalletOrder, error) {
+	params := make(map[string]interface{})
+	switch {
+	case id != 0:
+		params["id"] = id
+	case clientOid != "":
+		params["client_oid"] = clientOid
+	default:
+		return WalletOrder{}, fmt.Errorf("no order to cancel")
+	}
+
+	order, _, err := Mc.ApiClient.PrivateApi.DeleteApiV3Order(context.Background(), Mc.signer, params)
+	if err != nil {
+		return WalletOrder{}, err
+	}
+	if g := Mc.ReadRiskGate(); g != nil {
+		g.orderCanceled(order.WsOrder())
+	}
+	return order, nil
+}
+
+// CancelWalletOrders cancels the orders of a wallet, market and side may be empty for all of them.
+// The orders which could not be canceled are reported in the error, along with the canceled ones.
+func (Mc *MaxClient) CancelWalletOrders(wallet WalletType, market, side string) ([]WalletOrder, error) {
+	params := make(map[string]interface{})
+	if market != "" {
+		params["market"] = NormalizeMarket(market)
+	}
+	if side != "" {
+		params["side"] = side
+	}
+
+	orders, _, err := Mc.ApiClient.PrivateApi.DeleteApiV3WalletOrders(context.Background(), Mc.signer, wallet, params)
+	// the gate only holds the spot orders, and only the canceled ones are taken off.
+	if g := Mc.ReadRiskGate(); g != nil && wallet == WalletSpot {
+		for _, order := range orders {
+			g.orderCanceled(order.WsOrder())
+		}
+	}
+	return orders, err
+}
+
+// WsOrder converts the order to the v2 layout used by the rest of the client.
+func (o WalletOrder) WsOrder() WsOrder {
+	return WsOrder{
+		Id:              o.Id,
+		Side:            o.Side,
+		OrdType:         o.OrdType,
+		Price:           o.Price.String(),
+		StopPrice:       o.StopPrice.String(),
+		AvgPrice:        o.AvgPrice.String(),
+		State:           o.State,
+		Market:          o.Market,
+		CreatedAt:       o.CreatedAt / 1000,
+		Volume:          o.Volume.String(),
+		RemainingVolume: o.RemainingVolume.String(),
+		ExecutedVolume:  o.ExecutedVolume.String(),
+		TradesCount:     o.TradesCount,
+	}
+}
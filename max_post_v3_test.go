@@ -0,0 +1,73 @@
+package max_RESTfulAPI
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+// test the M-wallet orders stay out of the spot reservations of the risk gate, and failed cancels are reported
+func TestWalletOrders(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		raw, _ := base64.StdEncoding.DecodeString(r.Header.Get("X-MAX-PAYLOAD"))
+		var body map[string]interface{}
+		json.Unmarshal(raw, &body)
+		switch r.URL.Path {
+		case "/api/v3/wallet/m/order", "/api/v3/wallet/spot/order":
+			id := 1
+			if strings.Contains(r.URL.Path, "spot") {
+				id = 25
+			}
+			fmt.Fprintf(w, `{"id":%d,"market":"btcusdt","side":"buy","ord_type":"limit","price":"100","volume":"%s","remaining_volume":"%s","state":"wait"}`,
+				id, body["volume"], body["volume"])
+		case "/api/v3/wallet/spot/orders":
+			w.Write([]byte(`[{"order":{"id":25,"market":"btcusdt","side":"buy","state":"cancel"}},{"order":{"id":26,"market":"btcusdt"},"error":"order is filled"}]`))
+		default:
+			t.Errorf("unexpected %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	cfg := NewConfiguration()
+	cfg.BasePath = server.URL
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	Mc := &MaxClient{signer: NewHMACSigner("access", "secret"), logger: logger, ApiClient: NewAPIClient(cfg)}
+	Mc.SetRiskGate(newTestRiskGate(RiskConfig{CheckBalance: true}))
+
+	// worth 2000 usdt, more than the 1000 spot usdt.
+	if _, err := Mc.PlaceWalletOrder(WalletM, "btcusdt", "buy", "limit", 100, 20, ""); err != nil {
+		t.Fatal(err)
+	}
+	if usdt := Mc.ReadRiskGate().ReadBalances()["usdt"]; usdt.Avaliable != 1000 || usdt.Locked != 0 {
+		t.Fatalf("spot usdt after an M-wallet order %+v", usdt)
+	}
+	if _, err := Mc.PlaceWalletOrder(WalletSpot, "btcusdt", "buy", "limit", 100, 20, ""); err == nil {
+		t.Fatal("spot order above the balance placed")
+	}
+	if _, err := Mc.PlaceWalletOrder(WalletSpot, "btcusdt", "buy", "limit", 100, 4, ""); err != nil {
+		t.Fatal(err)
+	}
+	if usdt := Mc.ReadRiskGate().ReadBalances()["usdt"]; usdt.Avaliable != 600 || usdt.Locked != 400 {
+		t.Fatalf("spot usdt %+v", usdt)
+	}
+
+	orders, err := Mc.CancelWalletOrders(WalletSpot, "btcusdt", "")
+	if len(orders) != 1 || orders[0].Id != 25 {
+		t.Fatalf("canceled %+v", orders)
+	}
+	if err == nil || !strings.Contains(err.Error(), "order 26: order is filled") {
+		t.Fatalf("err %v", err)
+	}
+	// the spot order placed above has id 25, released by the cancel.
+	if usdt := Mc.ReadRiskGate().ReadBalances()["usdt"]; usdt.Avaliable != 1000 || usdt.Locked != 0 {
+		t.Fatalf("spot usdt after the cancel %+v", usdt)
+	}
+}
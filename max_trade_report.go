@@ -2,9 +2,6 @@ package max_RESTfulAPI
 
 import (
 	"context"
-	"crypto/hmac"
-	"crypto/sha256"
-	"encoding/hex"
 
 	"log"
 
@@ -22,115 +19,64 @@ func (Mc *MaxClient) TradeReportStream(ctx context.Context) {
 
 // trade report
 func (Mc *MaxClient) TradeReportWebsocket(ctx context.Context) {
-	duration := time.Minute * 5
-	var url string = "wss://max-stream.maicoin.com/ws"
-	Mc.wsOnErrTurn(false)
-
-	// wait 5 second, if the hand shake fail, will terminate the dail
-	dailCtx, _ := context.WithDeadline(ctx, time.Now().Add(time.Second*5))
-	conn, _, err := websocket.DefaultDialer.DialContext(dailCtx, url, nil)
-	if err != nil {
-		log.Println("❌ trade report dial:", err)
-		Mc.wsOnErrTurn(true)
-		defer Mc.TradeReportWebsocket(ctx)
-		time.Sleep(1 * time.Second)
-		return
-	}
-
-	Mc.setConn(conn)
-
-	subMsg, err := TradeReportSubscribeMessage(Mc.apiKey, Mc.apiSecret)
-	if err != nil {
-		log.Println(errors.New("❌ fail to construct subscribtion message"))
-		Mc.wsOnErrTurn(true)
+	engine := newWsEngine("trade report", wsConfig{PingInterval: time.Minute, ReadTimeout: 5 * time.Minute}, Mc.logger, Mc.WsClient.health)
+	engine.subscribe = func() ([][]byte, error) {
+		subMsg, err := TradeReportSubscribeMessage(Mc.signer, Mc.ApiClient.Nonce)
+		if err != nil {
+			return nil, errors.New("❌ fail to construct subscribtion message")
+		}
+		return [][]byte{subMsg}, nil
 	}
-
-	if !Mc.isWsOnErr() {
-		Mc.wsWriteMsg(websocket.TextMessage, subMsg)
+	engine.handle = Mc.handleTradeReportMsg
+	engine.onConnect = func() {
+		Mc.wsOnErrTurn(false)
+		Mc.WsClient.engineMutex.Lock()
+		Mc.WsClient.Conn = engine.conn()
+		Mc.WsClient.engineMutex.Unlock()
 	}
-
-	// pint it
-	go func() {
-		for {
-			if Mc.isWsOnErr() {
-				break
-			}
-			time.Sleep(time.Minute * 2)
-			if err := Mc.wsWriteMsg(websocket.PingMessage, []byte("ping")); err == nil {
-				Mc.WsClient.Conn.SetReadDeadline(time.Now().Add(duration))
-			}
-		}
-	}()
-
-	// mainloop
-mainloop:
-	for {
-		// if there is something wrong that the WS should be reconnected.
-		if Mc.isWsOnErr() {
-			break
-		}
-
-		select {
-		case <-ctx.Done():
-			Mc.wsOnErrTurn(false)
-			Mc.ShutDown()
-			return
-		default:
-			if Mc.WsClient.Conn == nil {
-				Mc.wsOnErrTurn(true)
-				break mainloop
-			}
-
-			_, msg, err := Mc.WsClient.Conn.ReadMessage()
-			if err != nil {
-				//log.Println("❌ trade report read:", err, string(msg), msgtype)
-				Mc.wsOnErrTurn(true)
-				time.Sleep(time.Millisecond * 500)
-				break mainloop
-			}
-			Mc.WsClient.Conn.SetReadDeadline(time.Now().Add(duration))
-
-			errh := Mc.handleTradeReportMsg(msg)
-			if errh != nil {
-				log.Println("❌ trade report handle:", errh)
-				Mc.wsOnErrTurn(true)
-				break mainloop
-			}
-		} // end select
-		time.Sleep(time.Millisecond)
-	} // end for
-
-	Mc.WsClient.Conn.Close()
-
-	// if it is manual work.
-	if !Mc.isWsOnErr() {
-		return
+	engine.onDisconnect = func(err error) {
+		Mc.wsOnErrTurn(true)
+		Mc.WsClient.engineMutex.Lock()
+		Mc.WsClient.Conn = nil
+		Mc.WsClient.engineMutex.Unlock()
+
+		// keep the tracked trades, so the snapshot after reconnecting only reports the missed ones.
+		Mc.WsClient.TmpBranch.Lock()
+		Mc.WsClient.TmpBranch.Trades = Mc.ReadTrades()
+		Mc.WsClient.TmpBranch.Unlock()
 	}
 
-	Mc.WsClient.TmpBranch.Lock()
-	Mc.WsClient.TmpBranch.Trades = Mc.ReadTrades()
-	Mc.WsClient.TmpBranch.Unlock()
+	Mc.WsClient.engineMutex.Lock()
+	Mc.WsClient.engine = engine
+	Mc.WsClient.engineMutex.Unlock()
 
-	time.Sleep(time.Millisecond * 500)
+	engine.run(ctx)
 
-	Mc.TradeReportWebsocket(ctx)
+	Mc.wsOnErrTurn(false)
+	Mc.ShutDown()
 }
 
 // provide private subscribtion message.
-func TradeReportSubscribeMessage(apikey, apisecret string) ([]byte, error) {
+func TradeReportSubscribeMessage(signer Signer, nonces *NonceSource) ([]byte, error) {
+	return privateAuthMessage(signer, nonces, "trade") //"filters": ["order", "trade"] // ignore account update
+}
+
+// privateAuthMessage authenticates the private channel, receiving the events of the filters only.
+func privateAuthMessage(signer Signer, nonces *NonceSource, filters ...string) ([]byte, error) {
 	// making signature
-	h := hmac.New(sha256.New, []byte(apisecret))
-	nonce := time.Now().UnixMilli()               // millisecond.
-	h.Write([]byte(strconv.FormatInt(nonce, 10))) // int64 to string.
-	signature := hex.EncodeToString(h.Sum(nil))
+	nonce := nonces.Next() // millisecond.
+	signature, err := signer.Sign([]byte(strconv.FormatInt(nonce, 10)))
+	if err != nil {
+		return nil, err
+	}
 
 	// prepare authentication message.
 	param := make(map[string]interface{})
 	param["action"] = "auth"
-	param["apiKey"] = apikey
+	param["apiKey"] = signer.AccessKey()
 	param["nonce"] = nonce
 	param["signature"] = signature
-	param["filters"] = []string{"trade"} //"filters": ["order", "trade"] // ignore account update
+	param["filters"] = filters
 	param["id"] = "User"
 
 	req, err := json.Marshal(param)
@@ -159,6 +105,7 @@ func (Mc *MaxClient) handleTradeReportMsg(msg []byte) error {
 	switch event {
 	case "authenticated":
 		log.Println("✅ MAX trade report websocket connected")
+		Mc.WsClient.health.connected()
 	case "trade_snapshot":
 		err2 = Mc.parseTradeReportSnapshotMsg(msgMap)
 	case "trade_update":
@@ -179,6 +126,12 @@ func (Mc *MaxClient) parseTradeReportSnapshotMsg(msgMap map[string]interface{})
 	json.Unmarshal(jsonbody, &newTrades)
 	Mc.trackingTradeReports(newTrades)
 
+	Mc.TradeListenersBranch.RLock()
+	snapshotListeners := Mc.TradeListenersBranch.snapshotListeners
+	Mc.TradeListenersBranch.RUnlock()
+	for _, l := range snapshotListeners {
+		l.fn(newTrades)
+	}
 	return nil
 }
 
@@ -233,31 +186,119 @@ func (Mc *MaxClient) tradeReportsArrived(trades []Trade) {
 	Mc.TradeReportBranch.Lock()
 	Mc.TradeReportBranch.TradeReports = append(Mc.TradeReportBranch.TradeReports, trades...)
 	Mc.TradeReportBranch.Unlock()
+	if g := Mc.ReadRiskGate(); g != nil {
+		g.tradesArrived(trades)
+	}
+	if cb := Mc.ReadCircuitBreaker(); cb != nil {
+		cb.tradesArrived(trades)
+	}
 	Mc.TradesArrived(trades)
+
+	Mc.TradeListenersBranch.RLock()
+	listeners := Mc.TradeListenersBranch.listeners
+	Mc.TradeListenersBranch.RUnlock()
+	for _, trade := range trades {
+		for _, l := range listeners {
+			l.fn(trade)
+		}
+	}
+}
+
+// tradeListener is a callback of OnTradeReport, the id finds it back to unsubscribe.
+type tradeListener struct {
+	id int64
+	fn func(Trade)
+}
+
+// withoutListener copies listeners without the one of id, readers may still hold the old slice.
+func withoutListener(listeners []tradeListener, id int64) []tradeListener {
+	kept := make([]tradeListener, 0, len(listeners))
+	for _, l := range listeners {
+		if l.id != id {
+			kept = append(kept, l)
+		}
+	}
+	return kept
+}
+
+// OnTradeReport registers a callback run for every private trade report, in the order received,
+// without taking it from GetTradeReports or TakeUnhedgeTrades.
+// It runs on the websocket goroutine, so it should return quickly. The returned func unsubscribes it.
+func (Mc *MaxClient) OnTradeReport(fn func(Trade)) (unsubscribe func()) {
+	Mc.TradeListenersBranch.Lock()
+	defer Mc.TradeListenersBranch.Unlock()
+	Mc.TradeListenersBranch.nextId++
+	id := Mc.TradeListenersBranch.nextId
+	Mc.TradeListenersBranch.listeners = append(Mc.TradeListenersBranch.listeners, tradeListener{id: id, fn: fn})
+	return func() {
+		Mc.TradeListenersBranch.Lock()
+		defer Mc.TradeListenersBranch.Unlock()
+		Mc.TradeListenersBranch.listeners = withoutListener(Mc.TradeListenersBranch.listeners, id)
+	}
+}
+
+// OnTradeSnapshot registers a callback run with the recent trades sent on every (re)connection
+// of the private websocket, after the missed ones were reported. The returned func unsubscribes it.
+func (Mc *MaxClient) OnTradeSnapshot(fn func([]Trade)) (unsubscribe func()) {
+	Mc.TradeListenersBranch.Lock()
+	defer Mc.TradeListenersBranch.Unlock()
+	Mc.TradeListenersBranch.nextId++
+	id := Mc.TradeListenersBranch.nextId
+	Mc.TradeListenersBranch.snapshotListeners = append(Mc.TradeListenersBranch.snapshotListeners, snapshotListener{id: id, fn: fn})
+	return func() {
+		Mc.TradeListenersBranch.Lock()
+		defer Mc.TradeListenersBranch.Unlock()
+		// copied, parseTradeReportSnapshotMsg may still range over the old slice.
+		listeners := Mc.TradeListenersBranch.snapshotListeners
+		kept := make([]snapshotListener, 0, len(listeners))
+		for _, l := range listeners {
+			if l.id != id {
+				kept = append(kept, l)
+			}
+		}
+		Mc.TradeListenersBranch.snapshotListeners = kept
+	}
+}
+
+type snapshotListener struct {
+	id int64
+	fn func([]Trade)
 }
 
 func (Mc *MaxClient) wsOnErrTurn(b bool) {
 	Mc.WsClient.onErrMutex.Lock()
 	defer Mc.WsClient.onErrMutex.Unlock()
+	if b && !Mc.WsClient.OnErr {
+		Mc.WsClient.downSince = time.Now()
+	}
 	Mc.WsClient.OnErr = b
 }
 
+// WsConn is the current connection of the private websocket, nil while disconnected.
+// It is replaced on every reconnect, so it should not be kept.
+func (Mc *MaxClient) WsConn() *websocket.Conn {
+	Mc.WsClient.engineMutex.RLock()
+	engine := Mc.WsClient.engine
+	Mc.WsClient.engineMutex.RUnlock()
+	if engine == nil {
+		return nil
+	}
+	return engine.conn()
+}
+
+// how long the private websocket has been down, zero if it is up.
+func (Mc *MaxClient) wsDownFor() time.Duration {
+	Mc.WsClient.onErrMutex.RLock()
+	defer Mc.WsClient.onErrMutex.RUnlock()
+	if !Mc.WsClient.OnErr {
+		return 0
+	}
+	return time.Since(Mc.WsClient.downSince)
+}
+
 func (Mc *MaxClient) isWsOnErr() (onErr bool) {
 	Mc.WsClient.onErrMutex.RLock()
 	defer Mc.WsClient.onErrMutex.RUnlock()
 	onErr = Mc.WsClient.OnErr
 	return
 }
-
-func (Mc *MaxClient) setConn(conn *websocket.Conn) {
-	Mc.WsClient.connMutex.Lock()
-	defer Mc.WsClient.connMutex.Unlock()
-	Mc.WsClient.Conn = conn
-}
-
-func (Mc *MaxClient) wsWriteMsg(msgType int, data []byte) error {
-	Mc.WsClient.connMutex.Lock()
-	defer Mc.WsClient.connMutex.Unlock()
-	err := Mc.WsClient.Conn.WriteMessage(msgType, data)
-	return err
-}
@@ -0,0 +1,300 @@
+package max_RESTfulAPI
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// MemoryExchange is an in-memory Exchange for tests. Orders rest until Fill or a cancel,
+// balances only change through SetBalance.
+type MemoryExchange struct {
+	stateBranch struct {
+		markets  []Market
+		balances map[string]BalanceRow
+		orders   map[int64]WsOrder
+		placed   []WsOrder
+		// last state of the orders no longer open.
+		closed     map[int64]WsOrder
+		clientOids map[string]int64
+		reports    []Trade
+		orderId    int64
+		tradeId    int64
+		sync.Mutex
+	}
+
+	listenersBranch struct {
+		listeners []tradeListener
+		nextId    int64
+		sync.RWMutex
+	}
+}
+
+func NewMemoryExchange(markets []Market) *MemoryExchange {
+	m := &MemoryExchange{}
+	m.stateBranch.markets = normalizeMarkets(append([]Market(nil), markets...))
+	m.stateBranch.balances = make(map[string]BalanceRow)
+	m.stateBranch.orders = make(map[int64]WsOrder)
+	m.stateBranch.closed = make(map[int64]WsOrder)
+	m.stateBranch.clientOids = make(map[string]int64)
+	return m
+}
+
+func (m *MemoryExchange) SetBalance(asset string, available, locked decimal.Decimal) {
+	m.stateBranch.Lock()
+	defer m.stateBranch.Unlock()
+	asset = NormalizeCurrency(asset)
+	m.stateBranch.balances[asset] = BalanceRow{Asset: asset, Available: available, Total: available.Add(locked)}
+}
+
+func (m *MemoryExchange) BalanceRows() ([]BalanceRow, error) {
+	m.stateBranch.Lock()
+	defer m.stateBranch.Unlock()
+	rows := make([]BalanceRow, 0, len(m.stateBranch.balances))
+	for _, row := range m.stateBranch.balances {
+		rows = append(rows, row)
+	}
+	sort.Slice(rows, func(i, j int) bool { return rows[i].Asset < rows[j].Asset })
+	return rows, nil
+}
+
+func (m *MemoryExchange) OpenOrderRows() ([]OpenOrderRow, error) {
+	m.stateBranch.Lock()
+	defer m.stateBranch.Unlock()
+	orders := make([]WsOrder, 0, len(m.stateBranch.orders))
+	for _, order := range m.stateBranch.orders {
+		orders = append(orders, order)
+	}
+	sort.Slice(orders, func(i, j int) bool { return orders[i].Id < orders[j].Id })
+	rows := make([]OpenOrderRow, 0, len(orders))
+	for _, order := range orders {
+		rows = append(rows, openOrderRow(order))
+	}
+	return rows, nil
+}
+
+func (m *MemoryExchange) TradeReportRows() ([]TradeReportRow, error) {
+	m.stateBranch.Lock()
+	trades := m.stateBranch.reports
+	m.stateBranch.reports = nil
+	m.stateBranch.Unlock()
+
+	rows := make([]TradeReportRow, 0, len(trades))
+	for _, trade := range trades {
+		rows = append(rows, tradeReportRow(trade))
+	}
+	return rows, nil
+}
+
+func (m *MemoryExchange) PlaceLimitOrder(market string, side string, price, volume float64) (WsOrder, error) {
+	return m.place(market, side, "limit", price, volume)
+}
+
+func (m *MemoryExchange) PlacePostOnlyOrder(market string, side string, price, volume float64) (WsOrder, error) {
+	return m.place(market, side, "post_only", price, volume)
+}
+
+// PlaceMarketOrder records the order as done, Fill it to report its trades.
+func (m *MemoryExchange) PlaceMarketOrder(market string, side string, volume float64) (WsOrder, error) {
+	return m.place(market, side, "market", 0, volume)
+}
+
+// PlaceTaggedOrder places an order of ordType "limit", "post_only" or "market", GetOrder finds it by clientOid.
+func (m *MemoryExchange) PlaceTaggedOrder(market, side, ordType string, price, volume float64, clientOid string) (WsOrder, error) {
+	order, err := m.place(market, side, ordType, price, volume)
+	if err == nil && clientOid != "" {
+		m.stateBranch.Lock()
+		m.stateBranch.clientOids[clientOid] = order.Id
+		m.stateBranch.Unlock()
+	}
+	return order, err
+}
+
+func (m *MemoryExchange) place(market, side, ordType string, price, volume float64) (WsOrder, error) {
+	market = NormalizeMarket(market)
+	m.stateBranch.Lock()
+	defer m.stateBranch.Unlock()
+	known := false
+	for _, mk := range m.stateBranch.markets {
+		known = known || mk.Id == market
+	}
+	if !known {
+		return WsOrder{}, fmt.Errorf("%w: %s", ErrUnknownMarket, market)
+	}
+
+	m.stateBranch.orderId++
+	order := WsOrder{
+		Id:              m.stateBranch.orderId,
+		Side:            strings.ToLower(side),
+		OrdType:         ordType,
+		Market:          market,
+		CreatedAt:       time.Now().UnixMilli(),
+		Volume:          decimal.NewFromFloat(volume).String(),
+		RemainingVolume: decimal.NewFromFloat(volume).String(),
+		ExecutedVolume:  "0",
+		State:           "wait",
+	}
+	if ordType != "market" {
+		order.Price = decimal.NewFromFloat(price).String()
+		m.stateBranch.orders[order.Id] = order
+	} else {
+		order.State = "done"
+		m.stateBranch.closed[order.Id] = order
+	}
+	m.stateBranch.placed = append(m.stateBranch.placed, order)
+	return order, nil
+}
+
+// OnTradeReport registers a callback run by Fill for every trade, the returned func unsubscribes it.
+func (m *MemoryExchange) OnTradeReport(fn func(Trade)) (unsubscribe func()) {
+	m.listenersBranch.Lock()
+	defer m.listenersBranch.Unlock()
+	m.listenersBranch.nextId++
+	id := m.listenersBranch.nextId
+	m.listenersBranch.listeners = append(m.listenersBranch.listeners, tradeListener{id: id, fn: fn})
+	return func() {
+		m.listenersBranch.Lock()
+		defer m.listenersBranch.Unlock()
+		m.listenersBranch.listeners = withoutListener(m.listenersBranch.listeners, id)
+	}
+}
+
+// Fill reports a trade of an order placed on m, and removes the order once fully filled.
+func (m *MemoryExchange) Fill(id int64, price, volume, fee decimal.Decimal, maker bool) (Trade, error) {
+	trade, err := m.fill(id, price, volume, fee, maker)
+	if err != nil {
+		return Trade{}, err
+	}
+	m.listenersBranch.RLock()
+	listeners := m.listenersBranch.listeners
+	m.listenersBranch.RUnlock()
+	for _, l := range listeners {
+		l.fn(trade)
+	}
+	return trade, nil
+}
+
+func (m *MemoryExchange) fill(id int64, price, volume, fee decimal.Decimal, maker bool) (Trade, error) {
+	m.stateBranch.Lock()
+	defer m.stateBranch.Unlock()
+
+	order, ok := m.stateBranch.closed[id]
+	if open, isOpen := m.stateBranch.orders[id]; isOpen {
+		order, ok = open, true
+	}
+	if !ok {
+		return Trade{}, errors.New("no order found")
+	}
+
+	remaining := decimalOrZero(order.RemainingVolume).Sub(volume)
+	order.RemainingVolume = remaining.String()
+	order.ExecutedVolume = decimalOrZero(order.ExecutedVolume).Add(volume).String()
+	order.TradesCount++
+	if remaining.IsPositive() && order.OrdType != "market" {
+		m.stateBranch.orders[id] = order
+	} else {
+		order.State = "done"
+		delete(m.stateBranch.orders, id)
+		m.stateBranch.closed[id] = order
+	}
+
+	// MAX charges buys in base and sells in quote.
+	base, quote, _ := splitMarket(m.stateBranch.markets, order.Market)
+	feeCurrency := quote
+	if order.Side == "buy" {
+		feeCurrency = base
+	}
+
+	m.stateBranch.tradeId++
+	trade := Trade{
+		Id:          m.stateBranch.tradeId,
+		Oid:         id,
+		Price:       price.String(),
+		Volume:      volume.String(),
+		Market:      order.Market,
+		Timestamp:   time.Now().UnixMilli(),
+		Side:        order.Side,
+		Fee:         fee.String(),
+		FeeCurrency: feeCurrency,
+		Maker:       maker,
+	}
+	m.stateBranch.reports = append(m.stateBranch.reports, trade)
+	return trade, nil
+}
+
+// Placed returns every order placed so far, in order.
+func (m *MemoryExchange) Placed() []WsOrder {
+	m.stateBranch.Lock()
+	defer m.stateBranch.Unlock()
+	return append([]WsOrder(nil), m.stateBranch.placed...)
+}
+
+// CancelOrder cancels an order by id, or by client oid when id is nil.
+func (m *MemoryExchange) CancelOrder(market string, id, clientId interface{}) (WsOrder, error) {
+	m.stateBranch.Lock()
+	defer m.stateBranch.Unlock()
+	oid, ok := id.(int64)
+	if !ok {
+		clientOid, isString := clientId.(string)
+		if !isString {
+			return WsOrder{}, errors.New("no order found")
+		}
+		if oid, ok = m.stateBranch.clientOids[clientOid]; !ok {
+			return WsOrder{}, errors.New("fail to cancel order" + clientOid)
+		}
+	}
+	order, ok := m.stateBranch.orders[oid]
+	if !ok {
+		return WsOrder{}, errors.New("fail to cancel order" + fmt.Sprint(oid))
+	}
+	delete(m.stateBranch.orders, oid)
+	order.State = "cancel"
+	m.stateBranch.closed[oid] = order
+	return order, nil
+}
+
+// GetOrder gets an order by id, or by client oid when id is nil.
+func (m *MemoryExchange) GetOrder(market string, id, clientId interface{}) (WsOrder, error) {
+	m.stateBranch.Lock()
+	defer m.stateBranch.Unlock()
+	oid, ok := id.(int64)
+	if !ok {
+		if clientOid, isString := clientId.(string); isString {
+			oid, ok = m.stateBranch.clientOids[clientOid]
+		}
+	}
+	if !ok {
+		return WsOrder{}, ErrOrderNotFound
+	}
+	if order, isOpen := m.stateBranch.orders[oid]; isOpen {
+		return order, nil
+	}
+	if order, isClosed := m.stateBranch.closed[oid]; isClosed {
+		return order, nil
+	}
+	return WsOrder{}, ErrOrderNotFound
+}
+
+func (m *MemoryExchange) CancelAllOrders() ([]WsOrder, error) {
+	m.stateBranch.Lock()
+	defer m.stateBranch.Unlock()
+	canceled := make([]WsOrder, 0, len(m.stateBranch.orders))
+	for id, order := range m.stateBranch.orders {
+		order.State = "cancel"
+		canceled = append(canceled, order)
+		delete(m.stateBranch.orders, id)
+		m.stateBranch.closed[id] = order
+	}
+	return canceled, nil
+}
+
+func (m *MemoryExchange) ReadMarkets() []Market {
+	m.stateBranch.Lock()
+	defer m.stateBranch.Unlock()
+	return m.stateBranch.markets
+}
@@ -0,0 +1,147 @@
+package max_RESTfulAPI
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// NonceSource hands out the nonces of signed requests in milliseconds.
+// They are strictly increasing across goroutines and follow the MAX server clock once synced.
+type NonceSource struct {
+	last int64
+	// server time minus local time, in milliseconds.
+	offset int64
+
+	syncBranch struct {
+		lastSync time.Time
+		// change of the offset between the last two syncs.
+		drift time.Duration
+		// error bound of the offset of the last two syncs.
+		uncertainty, prevUncertainty time.Duration
+		sync.RWMutex
+	}
+}
+
+func NewNonceSource() *NonceSource {
+	return &NonceSource{}
+}
+
+// Next returns a nonce greater than every nonce returned before.
+func (n *NonceSource) Next() int64 {
+	now := time.Now().UnixMilli() + atomic.LoadInt64(&n.offset)
+	for {
+		last := atomic.LoadInt64(&n.last)
+		next := now
+		if next <= last {
+			next = last + 1
+		}
+		if atomic.CompareAndSwapInt64(&n.last, last, next) {
+			return next
+		}
+	}
+}
+
+// Offset is how far the MAX server clock is ahead of the local one.
+func (n *NonceSource) Offset() time.Duration {
+	return time.Duration(atomic.LoadInt64(&n.offset)) * time.Millisecond
+}
+
+// Uncertainty is the error bound of Offset, the offset is only known within ±Uncertainty.
+// /api/v2/timestamp has a one second resolution, so the bound is 500ms plus half of the round trip.
+func (n *NonceSource) Uncertainty() time.Duration {
+	n.syncBranch.RLock()
+	defer n.syncBranch.RUnlock()
+	return n.syncBranch.uncertainty
+}
+
+// Drift is how much the offset moved between the last two syncs, i.e. how fast the local clock drifts.
+// Moves within the Uncertainty of both syncs are measurement noise, see Drifting.
+func (n *NonceSource) Drift() time.Duration {
+	n.syncBranch.RLock()
+	defer n.syncBranch.RUnlock()
+	return n.syncBranch.drift
+}
+
+// Drifting tells if the offset moved beyond the error bounds of the last two syncs,
+// so the local clock did drift and not only the server second was rounded differently.
+func (n *NonceSource) Drifting() bool {
+	n.syncBranch.RLock()
+	defer n.syncBranch.RUnlock()
+	drift := n.syncBranch.drift
+	if drift < 0 {
+		drift = -drift
+	}
+	return drift > n.syncBranch.uncertainty+n.syncBranch.prevUncertainty
+}
+
+// LastSync is the time of the last successful sync, zero if never synced.
+func (n *NonceSource) LastSync() time.Time {
+	n.syncBranch.RLock()
+	defer n.syncBranch.RUnlock()
+	return n.syncBranch.lastSync
+}
+
+// Sync measures the offset to the MAX server time.
+func (n *NonceSource) Sync(ctx context.Context, api *APIClient) error {
+	sent := time.Now()
+	server, _, err := api.PublicApi.GetApiV2Timestamp(ctx)
+	if err != nil {
+		return err
+	}
+	received := time.Now()
+
+	// the server time is in seconds, take the middle of that second against the middle of the round trip.
+	// the server read its clock somewhere in the round trip and truncated it to the second,
+	// so the offset is off by up to 500ms plus half of the round trip.
+	rtt := received.Sub(sent)
+	local := sent.Add(rtt / 2).UnixMilli()
+	offset := server*1000 + 500 - local
+	n.setOffset(offset, 500*time.Millisecond+rtt/2)
+	return nil
+}
+
+func (n *NonceSource) setOffset(offset int64, uncertainty time.Duration) {
+	prev := atomic.SwapInt64(&n.offset, offset)
+
+	n.syncBranch.Lock()
+	defer n.syncBranch.Unlock()
+	if !n.syncBranch.lastSync.IsZero() {
+		n.syncBranch.drift = time.Duration(offset-prev) * time.Millisecond
+	}
+	n.syncBranch.prevUncertainty = n.syncBranch.uncertainty
+	n.syncBranch.uncertainty = uncertainty
+	n.syncBranch.lastSync = time.Now()
+}
+
+// StartSync syncs to the server time now and then every interval until ctx is done.
+func (n *NonceSource) StartSync(ctx context.Context, api *APIClient, interval time.Duration, logger *logrus.Logger) {
+	doSync := func() {
+		if err := n.Sync(ctx, api); err != nil {
+			logger.Warn("fail to sync server time: ", err)
+			return
+		}
+		if n.Drifting() {
+			logger.Warnf("local clock drifted %v since the last sync, offset %v ±%v", n.Drift(), n.Offset(), n.Uncertainty())
+			return
+		}
+		logger.Debugf("server time offset %v ±%v, drift %v", n.Offset(), n.Uncertainty(), n.Drift())
+	}
+	doSync()
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				doSync()
+			}
+		}
+	}()
+}
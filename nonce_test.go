@@ -0,0 +1,65 @@
+package max_RESTfulAPI
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// test nonces stay unique and increasing when requested concurrently
+func TestNonceSourceConcurrent(t *testing.T) {
+	n := NewNonceSource()
+	n.setOffset(-5000, 0)
+
+	const workers, perWorker = 8, 1000
+	results := make([][]int64, workers)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < perWorker; i++ {
+				results[w] = append(results[w], n.Next())
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	seen := make(map[int64]bool, workers*perWorker)
+	for _, nonces := range results {
+		for i, nonce := range nonces {
+			if seen[nonce] {
+				t.Fatalf("duplicated nonce %d", nonce)
+			}
+			seen[nonce] = true
+			if i > 0 && nonce <= nonces[i-1] {
+				t.Fatalf("nonce went backwards: %d after %d", nonce, nonces[i-1])
+			}
+		}
+	}
+
+	// moving the clock back must not move the nonces back.
+	last := n.Next()
+	n.setOffset(-60000, 0)
+	if next := n.Next(); next <= last {
+		t.Fatalf("nonce went backwards after sync: %d after %d", next, last)
+	}
+}
+
+// test offset moves within the one second resolution of the server time are not reported as drift
+func TestNonceSourceDrifting(t *testing.T) {
+	n := NewNonceSource()
+	n.setOffset(1200, 550*time.Millisecond)
+	if n.Drifting() {
+		t.Fatal("drifting after the first sync")
+	}
+	// rounded to the other side of the server second.
+	n.setOffset(300, 520*time.Millisecond)
+	if n.Drift() != -900*time.Millisecond || n.Drifting() {
+		t.Fatalf("drift %v, drifting %v", n.Drift(), n.Drifting())
+	}
+	n.setOffset(1400, 510*time.Millisecond)
+	if !n.Drifting() || n.Uncertainty() != 510*time.Millisecond {
+		t.Fatalf("drift %v, uncertainty %v, drifting %v", n.Drift(), n.Uncertainty(), n.Drifting())
+	}
+}
@@ -38,6 +38,19 @@ func (o *OrderBookKeeper) handleSnapshot(bids, asks [][]decimal.Decimal) error {
 	return nil
 }
 
+// SeedFromDepth sets the orderbook from a REST depth snapshot.
+func (o *OrderBookKeeper) SeedFromDepth(depth Depth) error {
+	return o.handleSnapshot(depthLevels(depth.Bids), depthLevels(depth.Asks))
+}
+
+func depthLevels(levels []DepthLevel) [][]decimal.Decimal {
+	book := make([][]decimal.Decimal, 0, len(levels))
+	for _, level := range levels {
+		book = append(book, []decimal.Decimal{level.Price, level.Volume})
+	}
+	return book
+}
+
 // handleUpdate, update orderbook
 func (o *OrderBookKeeper) handleUpdate(bids, asks [][]decimal.Decimal) {
 	o.Lock()
@@ -138,3 +151,15 @@ func (ob *OrderbookBranch) GetBids() (bids [][]decimal.Decimal, ok bool) {
 
 	return bids, true
 }
+
+// MidPrice returns the mid of best bid and best ask.
+func (ob *OrderbookBranch) MidPrice() (mid decimal.Decimal, ok bool) {
+	ob.keeper.RLock()
+	defer ob.keeper.RUnlock()
+
+	if len(ob.keeper.bids) == 0 || len(ob.keeper.asks) == 0 {
+		return decimal.Zero, false
+	}
+	mid = ob.keeper.bids[0][0].Add(ob.keeper.asks[0][0]).Div(decimal.NewFromInt(2))
+	return mid, true
+}
@@ -0,0 +1,40 @@
+package max_RESTfulAPI
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+// test seeding the book from a REST depth and comparing it back
+func TestOrderbookSeedFromDepth(t *testing.T) {
+	var depth Depth
+	body := `{"timestamp":1672531200,"asks":[["31.2","5"],["31.1","2"]],"bids":[["31.0","1"],["30.9","4"]]}`
+	if err := json.Unmarshal([]byte(body), &depth); err != nil {
+		t.Fatal(err)
+	}
+
+	var o OrderbookBranch
+	if err := o.keeper.SeedFromDepth(depth); err != nil {
+		t.Fatal(err)
+	}
+	asks, ok := o.GetAsks()
+	if !ok || !asks[0][0].Equal(decimal.RequireFromString("31.1")) {
+		t.Fatalf("best ask %v", asks)
+	}
+	bids, ok := o.GetBids()
+	if !ok || !bids[0][0].Equal(decimal.RequireFromString("31")) {
+		t.Fatalf("best bid %v", bids)
+	}
+
+	tolerance := decimal.NewFromFloat(0.005)
+	if reason, ok := o.checkDepth(depth, tolerance); !ok {
+		t.Fatalf("unexpected drift: %s", reason)
+	}
+
+	depth.Asks[1].Price = decimal.RequireFromString("32")
+	depth.Asks[0], depth.Asks[1] = depth.Asks[1], depth.Asks[0]
+	if _, ok := o.checkDepth(depth, tolerance); ok {
+		t.Fatal("drift not detected")
+	}
+}
@@ -0,0 +1,660 @@
+package max_RESTfulAPI
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+var (
+	ErrInsufficientBalance = errors.New("insufficient balance")
+	// a post only order that would take liquidity is rejected, as MAX does.
+	ErrPostOnlyCross = errors.New("post only order would cross the book")
+)
+
+type PaperConfig struct {
+	// starting balances by currency.
+	Balances map[string]decimal.Decimal
+	// maker/taker rates, default to the VIP 0 schedule. Fees are charged in the received currency.
+	Fees *FeeModel
+	// how often resting orders are checked against the books, default to 200 milliseconds.
+	MatchInterval time.Duration
+}
+
+// PaperClient simulates the order methods of MaxClient against the live local orderbooks and
+// public trades, with a virtual balance sheet. Fills go through the same trade report path as
+// live trading, so TakeUnhedgeTrades, GetTradeReports, the risk gate and the circuit breaker see them.
+// Methods not overridden here are the ones of the embedded client, which has no api key.
+type PaperClient struct {
+	*MaxClient
+	Config PaperConfig
+
+	books  BookPrices
+	trades *TradeStreamBranch
+
+	paperBranch struct {
+		available map[string]decimal.Decimal
+		locked    map[string]decimal.Decimal
+		orders    map[int64]*paperOrder
+		// every order placed and the ids of the tagged ones, for GetOrder.
+		history    map[int64]*paperOrder
+		clientOids map[string]int64
+		// volume already taken from the book levels, by market, book side and price.
+		consumed map[string]*paperLevel
+		orderId  int64
+		tradeId  int64
+		sync.Mutex
+	}
+}
+
+// paperLevel is the volume taken from a book level, valid while the level keeps its size.
+type paperLevel struct {
+	size decimal.Decimal
+	used decimal.Decimal
+}
+
+type paperOrder struct {
+	order       WsOrder
+	base, quote string
+	price       decimal.Decimal
+	remaining   decimal.Decimal
+	executed    decimal.Decimal
+	// quote for buy orders, base for sell orders, still locked by the order.
+	locked   decimal.Decimal
+	notional decimal.Decimal
+}
+
+// NewPaperClient simulates trading with the markets of Mc, matching against books and trades.
+// Resting orders fill from the public trades, or when trades is nil, from the book levels crossing them.
+// Either way a book level is taken once, until its size changes.
+func NewPaperClient(Mc *MaxClient, books BookPrices, trades *TradeStreamBranch, cfg PaperConfig) *PaperClient {
+	if cfg.Fees == nil {
+		cfg.Fees = NewFeeModel("usdt", books)
+	}
+	if cfg.MatchInterval <= 0 {
+		cfg.MatchInterval = 200 * time.Millisecond
+	}
+
+	sim := &MaxClient{ApiClient: Mc.ApiClient, logger: Mc.logger}
+	sim.MarketsBranch.Markets = Mc.ReadMarkets()
+	sim.setCurrencies(append([]Currency(nil), Mc.ReadCurrencies()...))
+
+	p := &PaperClient{MaxClient: sim, Config: cfg, books: books, trades: trades}
+	p.paperBranch.available = make(map[string]decimal.Decimal)
+	p.paperBranch.locked = make(map[string]decimal.Decimal)
+	p.paperBranch.orders = make(map[int64]*paperOrder)
+	p.paperBranch.history = make(map[int64]*paperOrder)
+	p.paperBranch.clientOids = make(map[string]int64)
+	p.paperBranch.consumed = make(map[string]*paperLevel)
+	for currency, amount := range cfg.Balances {
+		p.paperBranch.available[NormalizeCurrency(currency)] = amount
+	}
+	return p
+}
+
+// Start matches the resting orders against the public trades, or the books without trades, until ctx is done.
+// Matching against both would fill the same liquidity twice.
+func (p *PaperClient) Start(ctx context.Context) {
+	if p.trades != nil {
+		unsubscribe := p.trades.OnTrade(func(trade PublicTrade) {
+			if ctx.Err() == nil {
+				p.publicTradeArrived(trade)
+			}
+		})
+		go func() {
+			<-ctx.Done()
+			unsubscribe()
+		}()
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(p.Config.MatchInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				p.matchBooks()
+			}
+		}
+	}()
+}
+
+func (p *PaperClient) PlaceLimitOrder(market string, side string, price, volume float64) (WsOrder, error) {
+	return p.submitOrder(market, side, "limit", price, volume)
+}
+
+func (p *PaperClient) PlacePostOnlyOrder(market string, side string, price, volume float64) (WsOrder, error) {
+	return p.submitOrder(market, side, "post_only", price, volume)
+}
+
+func (p *PaperClient) PlaceMarketOrder(market string, side string, volume float64) (WsOrder, error) {
+	return p.submitOrder(market, side, "market", 0, volume)
+}
+
+// PlaceTaggedOrder places an order of ordType "limit", "post_only" or "market", GetOrder finds it by clientOid.
+func (p *PaperClient) PlaceTaggedOrder(market, side, ordType string, price, volume float64, clientOid string) (WsOrder, error) {
+	return p.submitTaggedOrder(market, side, ordType, price, volume, clientOid)
+}
+
+func (p *PaperClient) submitOrder(market, side, ordType string, price, volume float64) (WsOrder, error) {
+	return p.submitTaggedOrder(market, side, ordType, price, volume, "")
+}
+
+// submitTaggedOrder gates the order like MaxClient.submitOrder, then takes what it crosses and rests the rest.
+func (p *PaperClient) submitTaggedOrder(market, side, ordType string, price, volume float64, clientOid string) (WsOrder, error) {
+	if err := p.checkBreaker(); err != nil {
+		return WsOrder{}, err
+	}
+	ticket, err := p.admitRisk(market, side, ordType, price, volume)
+	if err != nil {
+		p.breakerOrderRejected()
+		return WsOrder{}, err
+	}
+
+	order, trades, err := p.match(NormalizeMarket(market), strings.ToLower(side), ordType, decimal.NewFromFloat(price), decimal.NewFromFloat(volume), clientOid)
+	if err != nil {
+		ticket.release()
+		p.breakerOrderRejected()
+		return WsOrder{}, err
+	}
+
+	ticket.placed(order)
+	p.fillsArrived(trades)
+	return order, nil
+}
+
+func (p *PaperClient) match(market, side, ordType string, price, volume decimal.Decimal, clientOid string) (WsOrder, []Trade, error) {
+	base, quote, ok := splitMarket(p.ReadMarkets(), market)
+	if !ok {
+		return WsOrder{}, nil, fmt.Errorf("%w: %s", ErrUnknownMarket, market)
+	}
+	if side != "buy" && side != "sell" {
+		return WsOrder{}, nil, fmt.Errorf("unknown side %q", side)
+	}
+	if !volume.IsPositive() || (ordType != "market" && !price.IsPositive()) {
+		return WsOrder{}, nil, fmt.Errorf("invalid price %s or volume %s", price, volume)
+	}
+
+	p.paperBranch.Lock()
+	defer p.paperBranch.Unlock()
+
+	levels := p.depleted(market, side, p.crossable(market, side, ordType, price))
+	if ordType == "post_only" && len(levels) > 0 {
+		return WsOrder{}, nil, ErrPostOnlyCross
+	}
+	if ordType == "market" && len(levels) == 0 {
+		return WsOrder{}, nil, fmt.Errorf("no liquidity to fill market order on %s", market)
+	}
+
+	o := &paperOrder{base: base, quote: quote, price: price, remaining: volume, executed: decimal.Zero, notional: decimal.Zero}
+	// market buys lock what walking the book costs, the rest lock the worst case.
+	switch {
+	case side == "sell":
+		o.locked = volume
+	case ordType == "market":
+		o.locked = walkCost(levels, volume)
+	default:
+		o.locked = price.Mul(volume)
+	}
+	lockCurrency := quote
+	if side == "sell" {
+		lockCurrency = base
+	}
+
+	if p.paperBranch.available[lockCurrency].LessThan(o.locked) {
+		return WsOrder{}, nil, fmt.Errorf("%w: %s %s needed, %s available", ErrInsufficientBalance, o.locked, lockCurrency, p.paperBranch.available[lockCurrency])
+	}
+	p.paperBranch.available[lockCurrency] = p.paperBranch.available[lockCurrency].Sub(o.locked)
+	p.paperBranch.locked[lockCurrency] = p.paperBranch.locked[lockCurrency].Add(o.locked)
+
+	p.paperBranch.orderId++
+	o.order = WsOrder{
+		Id:        p.paperBranch.orderId,
+		Side:      side,
+		OrdType:   ordType,
+		Market:    market,
+		CreatedAt: time.Now().UnixMilli(),
+		Volume:    volume.String(),
+		State:     "wait",
+	}
+	if ordType != "market" {
+		o.order.Price = price.String()
+	}
+	p.paperBranch.history[o.order.Id] = o
+	if clientOid != "" {
+		p.paperBranch.clientOids[clientOid] = o.order.Id
+	}
+
+	var trades []Trade
+	for _, level := range levels {
+		if !o.remaining.IsPositive() {
+			break
+		}
+		volume := decimal.Min(level[1], o.remaining)
+		p.consume(market, side, level[0], volume)
+		trades = append(trades, p.fill(o, level[0], volume, false))
+	}
+
+	if ordType == "market" || !o.remaining.IsPositive() {
+		p.close(o, "done")
+	} else {
+		p.paperBranch.orders[o.order.Id] = o
+	}
+	return o.snapshot(), trades, nil
+}
+
+// crossable returns the opposite side levels an order would take, best first.
+func (p *PaperClient) crossable(market, side, ordType string, price decimal.Decimal) [][]decimal.Decimal {
+	ob, ok := p.books[market]
+	if !ok || ob == nil {
+		return nil
+	}
+	var levels [][]decimal.Decimal
+	if side == "buy" {
+		levels, _ = ob.GetAsks()
+	} else {
+		levels, _ = ob.GetBids()
+	}
+
+	var crossed [][]decimal.Decimal
+	for _, level := range levels {
+		if ordType != "market" && ((side == "buy" && level[0].GreaterThan(price)) || (side == "sell" && level[0].LessThan(price))) {
+			break
+		}
+		crossed = append(crossed, []decimal.Decimal{level[0], level[1]})
+	}
+	return crossed
+}
+
+// depleted takes off the levels a taker on side would cross the volume already filled from them,
+// with the lock held. A level whose size changed since is taken as new.
+func (p *PaperClient) depleted(market, side string, levels [][]decimal.Decimal) [][]decimal.Decimal {
+	var left [][]decimal.Decimal
+	for _, level := range levels {
+		consumed, ok := p.paperBranch.consumed[levelKey(market, side, level[0])]
+		if ok && consumed.size.Equal(level[1]) {
+			level = []decimal.Decimal{level[0], level[1].Sub(consumed.used)}
+		}
+		if level[1].IsPositive() {
+			left = append(left, level)
+		}
+	}
+	return left
+}
+
+// consume records volume taken from the level at price by a taker on side, with the lock held.
+func (p *PaperClient) consume(market, side string, price, volume decimal.Decimal) {
+	key := levelKey(market, side, price)
+	size, ok := p.levelSize(market, side, price)
+	if !ok {
+		delete(p.paperBranch.consumed, key)
+		return
+	}
+	consumed, ok := p.paperBranch.consumed[key]
+	if !ok || !consumed.size.Equal(size) {
+		consumed = &paperLevel{size: size, used: decimal.Zero}
+		p.paperBranch.consumed[key] = consumed
+	}
+	consumed.used = consumed.used.Add(volume)
+}
+
+// levelSize is the size of the level at price on the book side a taker on side takes.
+func (p *PaperClient) levelSize(market, side string, price decimal.Decimal) (decimal.Decimal, bool) {
+	for _, level := range p.crossable(market, side, "limit", price) {
+		if level[0].Equal(price) {
+			return level[1], true
+		}
+	}
+	return decimal.Zero, false
+}
+
+// pruneConsumed forgets the levels gone from the book or resized, with the lock held.
+func (p *PaperClient) pruneConsumed() {
+	for key, consumed := range p.paperBranch.consumed {
+		market, side, price := splitLevelKey(key)
+		if size, ok := p.levelSize(market, side, price); !ok || !size.Equal(consumed.size) {
+			delete(p.paperBranch.consumed, key)
+		}
+	}
+}
+
+func levelKey(market, side string, price decimal.Decimal) string {
+	return market + "/" + side + "/" + price.String()
+}
+
+func splitLevelKey(key string) (market, side string, price decimal.Decimal) {
+	parts := strings.SplitN(key, "/", 3)
+	price, _ = decimal.NewFromString(parts[2])
+	return parts[0], parts[1], price
+}
+
+func walkCost(levels [][]decimal.Decimal, volume decimal.Decimal) decimal.Decimal {
+	cost := decimal.Zero
+	for _, level := range levels {
+		if !volume.IsPositive() {
+			break
+		}
+		v := decimal.Min(level[1], volume)
+		cost = cost.Add(level[0].Mul(v))
+		volume = volume.Sub(v)
+	}
+	return cost
+}
+
+// fill settles a fill of o with the lock held and returns its trade report.
+func (p *PaperClient) fill(o *paperOrder, price, volume decimal.Decimal, maker bool) Trade {
+	rate := p.Config.Fees.EffectiveRate(maker)
+	notional := price.Mul(volume)
+
+	var fee decimal.Decimal
+	var feeCurrency string
+	if o.order.Side == "buy" {
+		// a limit buy filled below its price gets the difference back.
+		release := notional
+		if o.order.OrdType != "market" {
+			release = o.price.Mul(volume)
+		}
+		release = decimal.Min(release, o.locked)
+		o.locked = o.locked.Sub(release)
+		p.paperBranch.locked[o.quote] = p.paperBranch.locked[o.quote].Sub(release)
+		p.paperBranch.available[o.quote] = p.paperBranch.available[o.quote].Add(release).Sub(notional)
+
+		fee, feeCurrency = volume.Mul(rate), o.base
+		p.paperBranch.available[o.base] = p.paperBranch.available[o.base].Add(volume).Sub(fee)
+	} else {
+		o.locked = o.locked.Sub(volume)
+		p.paperBranch.locked[o.base] = p.paperBranch.locked[o.base].Sub(volume)
+
+		fee, feeCurrency = notional.Mul(rate), o.quote
+		p.paperBranch.available[o.quote] = p.paperBranch.available[o.quote].Add(notional).Sub(fee)
+	}
+
+	o.remaining = o.remaining.Sub(volume)
+	o.executed = o.executed.Add(volume)
+	o.notional = o.notional.Add(notional)
+	o.order.TradesCount++
+
+	p.paperBranch.tradeId++
+	return Trade{
+		Id:          p.paperBranch.tradeId,
+		Oid:         o.order.Id,
+		Price:       price.String(),
+		Volume:      volume.String(),
+		Market:      o.order.Market,
+		Timestamp:   time.Now().UnixMilli(),
+		Side:        o.order.Side,
+		Fee:         fee.String(),
+		FeeCurrency: feeCurrency,
+		Maker:       maker,
+	}
+}
+
+// close releases what o still locks, with the lock held.
+func (p *PaperClient) close(o *paperOrder, state string) {
+	currency := o.quote
+	if o.order.Side == "sell" {
+		currency = o.base
+	}
+	p.paperBranch.locked[currency] = p.paperBranch.locked[currency].Sub(o.locked)
+	p.paperBranch.available[currency] = p.paperBranch.available[currency].Add(o.locked)
+	o.locked = decimal.Zero
+	o.order.State = state
+	delete(p.paperBranch.orders, o.order.Id)
+}
+
+func (o *paperOrder) snapshot() WsOrder {
+	order := o.order
+	order.RemainingVolume = o.remaining.String()
+	order.ExecutedVolume = o.executed.String()
+	if o.executed.IsPositive() {
+		order.AvgPrice = o.notional.Div(o.executed).String()
+	}
+	return order
+}
+
+// publicTradeArrived fills the resting orders the trade went through, as maker at their own price.
+// The queue position is ignored, a trade at the order price fills it.
+func (p *PaperClient) publicTradeArrived(trade PublicTrade) {
+	market := NormalizeMarket(trade.Market)
+	left := trade.Volume
+
+	p.paperBranch.Lock()
+	var trades []Trade
+	for _, o := range p.restingOrders(market) {
+		if !left.IsPositive() {
+			break
+		}
+		switch {
+		case o.order.Side == "buy" && trade.Side != TakerSideBuy && trade.Price.LessThanOrEqual(o.price):
+		case o.order.Side == "sell" && trade.Side != TakerSideSell && trade.Price.GreaterThanOrEqual(o.price):
+		default:
+			continue
+		}
+		volume := decimal.Min(left, o.remaining)
+		left = left.Sub(volume)
+		trades = append(trades, p.fill(o, o.price, volume, true))
+		if !o.remaining.IsPositive() {
+			p.close(o, "done")
+		}
+	}
+	p.paperBranch.Unlock()
+
+	p.fillsArrived(trades)
+}
+
+// matchBooks fills the resting orders the books moved through, taking each level volume once.
+func (p *PaperClient) matchBooks() {
+	p.paperBranch.Lock()
+	p.pruneConsumed()
+	var trades []Trade
+	for market := range p.books {
+		for _, o := range p.restingOrders(market) {
+			// the levels that trade through the price of o.
+			for _, level := range p.depleted(market, o.order.Side, p.crossable(market, o.order.Side, "limit", o.price)) {
+				if !o.remaining.IsPositive() {
+					break
+				}
+				volume := decimal.Min(level[1], o.remaining)
+				p.consume(market, o.order.Side, level[0], volume)
+				trades = append(trades, p.fill(o, o.price, volume, true))
+			}
+			if !o.remaining.IsPositive() {
+				p.close(o, "done")
+			}
+		}
+	}
+	p.paperBranch.Unlock()
+
+	p.fillsArrived(trades)
+}
+
+// restingOrders of a market by price priority then time, with the lock held.
+func (p *PaperClient) restingOrders(market string) []*paperOrder {
+	var orders []*paperOrder
+	for _, o := range p.paperBranch.orders {
+		if o.order.Market == market {
+			orders = append(orders, o)
+		}
+	}
+	sort.Slice(orders, func(i, j int) bool {
+		a, b := orders[i], orders[j]
+		if !a.price.Equal(b.price) && a.order.Side == b.order.Side {
+			if a.order.Side == "buy" {
+				return a.price.GreaterThan(b.price)
+			}
+			return a.price.LessThan(b.price)
+		}
+		return a.order.Id < b.order.Id
+	})
+	return orders
+}
+
+func (p *PaperClient) fillsArrived(trades []Trade) {
+	if len(trades) == 0 {
+		return
+	}
+	for _, trade := range trades {
+		p.logger.Infof("paper fill %s %s %s@%s, fee %s %s", trade.Market, trade.Side, trade.Volume, trade.Price, trade.Fee, trade.FeeCurrency)
+	}
+	p.tradeReportsArrived(trades)
+}
+
+// CancelOrder cancels an order by id, or by client oid when id is nil.
+func (p *PaperClient) CancelOrder(market string, id, clientId interface{}) (WsOrder, error) {
+	p.paperBranch.Lock()
+	oid, ok := id.(int64)
+	if !ok {
+		if clientOid, isString := clientId.(string); isString {
+			oid, ok = p.paperBranch.clientOids[clientOid]
+			if !ok {
+				p.paperBranch.Unlock()
+				return WsOrder{}, errors.New("fail to cancel order" + clientOid)
+			}
+		}
+	}
+	if !ok {
+		p.paperBranch.Unlock()
+		return WsOrder{}, errors.New("no order found")
+	}
+
+	o, ok := p.paperBranch.orders[oid]
+	if !ok {
+		p.paperBranch.Unlock()
+		return WsOrder{}, errors.New("fail to cancel order" + fmt.Sprint(oid))
+	}
+	p.close(o, "cancel")
+	order := o.snapshot()
+	p.paperBranch.Unlock()
+
+	if g := p.ReadRiskGate(); g != nil {
+		g.orderCanceled(order)
+	}
+	return order, nil
+}
+
+// GetOrder gets an order by id, or by client oid when id is nil.
+func (p *PaperClient) GetOrder(market string, id, clientId interface{}) (WsOrder, error) {
+	p.paperBranch.Lock()
+	defer p.paperBranch.Unlock()
+	oid, ok := id.(int64)
+	if !ok {
+		if clientOid, isString := clientId.(string); isString {
+			oid, ok = p.paperBranch.clientOids[clientOid]
+		}
+	}
+	o, found := p.paperBranch.history[oid]
+	if !ok || !found {
+		return WsOrder{}, ErrOrderNotFound
+	}
+	return o.snapshot(), nil
+}
+
+/*
+"side" (string) set tp cancel only sell (asks) or buy (bids) orders
+"market" (string) specify market like btctwd / ethbtc
+both params can be set as nil.
+*/
+func (p *PaperClient) CancelOrders(market, side interface{}) ([]WsOrder, error) {
+	p.paperBranch.Lock()
+	var canceled []WsOrder
+	for _, o := range p.paperBranch.orders {
+		if market != nil && o.order.Market != NormalizeMarket(market.(string)) {
+			continue
+		}
+		if side != nil && o.order.Side != strings.ToLower(side.(string)) {
+			continue
+		}
+		p.close(o, "cancel")
+		canceled = append(canceled, o.snapshot())
+	}
+	p.paperBranch.Unlock()
+
+	if g := p.ReadRiskGate(); g != nil {
+		for _, order := range canceled {
+			g.orderCanceled(order)
+		}
+	}
+	return canceled, nil
+}
+
+func (p *PaperClient) CancelAllOrders() ([]WsOrder, error) {
+	return p.CancelOrders(nil, nil)
+}
+
+func (p *PaperClient) GetBalance() (map[string]Balance, error) {
+	p.paperBranch.Lock()
+	defer p.paperBranch.Unlock()
+	balances := make(map[string]Balance)
+	for _, currency := range p.currencies() {
+		available, _ := p.paperBranch.available[currency].Float64()
+		locked, _ := p.paperBranch.locked[currency].Float64()
+		balances[currency] = Balance{Name: currency, Avaliable: available, Locked: locked}
+	}
+	return balances, nil
+}
+
+func (p *PaperClient) BalanceRows() ([]BalanceRow, error) {
+	p.paperBranch.Lock()
+	defer p.paperBranch.Unlock()
+	rows := make([]BalanceRow, 0, len(p.paperBranch.available))
+	for _, currency := range p.currencies() {
+		available, locked := p.paperBranch.available[currency], p.paperBranch.locked[currency]
+		rows = append(rows, BalanceRow{Asset: currency, Available: available, Total: available.Add(locked)})
+	}
+	return rows, nil
+}
+
+// GetBalances() ([][]string, bool)     // []string{asset, available, total}
+func (p *PaperClient) GetBalances() (balances [][]string, ok bool) {
+	return LegacyAdapter{p}.GetBalances()
+}
+
+// currencies of the balance sheet, sorted, with the lock held.
+func (p *PaperClient) currencies() []string {
+	seen := make(map[string]struct{})
+	for currency := range p.paperBranch.available {
+		seen[currency] = struct{}{}
+	}
+	for currency := range p.paperBranch.locked {
+		seen[currency] = struct{}{}
+	}
+	currencies := make([]string, 0, len(seen))
+	for currency := range seen {
+		currencies = append(currencies, currency)
+	}
+	sort.Strings(currencies)
+	return currencies
+}
+
+func (p *PaperClient) OpenOrderRows() ([]OpenOrderRow, error) {
+	p.paperBranch.Lock()
+	defer p.paperBranch.Unlock()
+	ids := make([]int64, 0, len(p.paperBranch.orders))
+	for id := range p.paperBranch.orders {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	rows := make([]OpenOrderRow, 0, len(ids))
+	for _, id := range ids {
+		rows = append(rows, openOrderRow(p.paperBranch.orders[id].snapshot()))
+	}
+	return rows, nil
+}
+
+// GetOpenOrders() ([][]string, bool)   // []string{oid, symbol, product, subaccount, price, qty, side, execType, UnfilledQty}
+func (p *PaperClient) GetOpenOrders() (openOrders [][]string, ok bool) {
+	return LegacyAdapter{p}.GetOpenOrders()
+}
+
+// GetTradeReports() ([][]string, bool) // []string{oid, symbol, product, subaccount, price, qty, side, execType, fee, filledQty, timestamp, isMaker}
+func (p *PaperClient) GetTradeReports() ([][]string, bool) {
+	return LegacyAdapter{p}.GetTradeReports()
+}
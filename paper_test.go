@@ -0,0 +1,179 @@
+package max_RESTfulAPI
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/sirupsen/logrus"
+)
+
+func TestPaperClientMatching(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	Mc := &MaxClient{logger: logger}
+	Mc.MarketsBranch.Markets = []Market{{Id: "btcusdt", BaseUnit: "btc", QuoteUnit: "usdt"}}
+
+	var book OrderbookBranch
+	var depth Depth
+	body := `{"asks":[["101","1"],["102","1"],["103","2"]],"bids":[["99","1"]]}`
+	if err := json.Unmarshal([]byte(body), &depth); err != nil {
+		t.Fatal(err)
+	}
+	if err := book.keeper.SeedFromDepth(depth); err != nil {
+		t.Fatal(err)
+	}
+
+	fees := NewFeeModel("usdt", nil)
+	fees.Schedule = []FeeTier{{Level: 0, Maker: decimal.Zero, Taker: decimal.RequireFromString("0.001")}}
+	p := NewPaperClient(Mc, BookPrices{"btcusdt": &book}, nil, PaperConfig{
+		Balances: map[string]decimal.Decimal{"USDT": decimal.NewFromInt(1000)},
+		Fees:     fees,
+	})
+
+	if _, err := p.PlacePostOnlyOrder("btcusdt", "buy", 101, 1); !errors.Is(err, ErrPostOnlyCross) {
+		t.Fatalf("post only crossing: %v", err)
+	}
+
+	// takes 1@101 and 1@102, rests 1@102.
+	order, err := p.PlaceLimitOrder("BTC/USDT", "buy", 102, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if order.ExecutedVolume != "2" || order.RemainingVolume != "1" || order.AvgPrice != "101.5" {
+		t.Fatalf("order %+v", order)
+	}
+	trades := p.TakeUnhedgeTrades()
+	if len(trades) != 2 || trades[0].Maker || trades[0].Fee != "0.001" || trades[0].FeeCurrency != "btc" {
+		t.Fatalf("trades %+v", trades)
+	}
+
+	// a public sell through the resting order fills it as maker.
+	p.publicTradeArrived(PublicTrade{Market: "btcusdt", Price: decimal.NewFromInt(101), Volume: decimal.NewFromInt(5), Side: TakerSideSell, Time: time.Now()})
+	if trades := p.TakeUnhedgeTrades(); len(trades) != 1 || !trades[0].Maker || trades[0].Price != "102" {
+		t.Fatalf("maker trades %+v", trades)
+	}
+
+	balances, _ := p.GetBalance()
+	// 1000 - 101 - 102 - 102
+	if balances["usdt"].Avaliable != 695 || balances["usdt"].Locked != 0 {
+		t.Errorf("usdt %+v", balances["usdt"])
+	}
+	if balances["btc"].Avaliable != 2.998 {
+		t.Errorf("btc %+v", balances["btc"])
+	}
+
+	if _, err := p.PlaceLimitOrder("btcusdt", "sell", 110, 5); !errors.Is(err, ErrInsufficientBalance) {
+		t.Fatalf("oversell: %v", err)
+	}
+	order, err = p.PlaceLimitOrder("btcusdt", "sell", 110, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.CancelOrder("btcusdt", order.Id, nil); err != nil {
+		t.Fatal(err)
+	}
+	if balances, _ := p.GetBalance(); balances["btc"].Locked != 0 || balances["btc"].Avaliable != 2.998 {
+		t.Errorf("btc after cancel %+v", balances["btc"])
+	}
+}
+
+// test a resting order against a crossed book that stays still only takes the crossed volume once
+func TestPaperClientStaticCrossedBook(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	Mc := &MaxClient{logger: logger}
+	Mc.MarketsBranch.Markets = []Market{{Id: "btcusdt", BaseUnit: "btc", QuoteUnit: "usdt"}}
+
+	var book OrderbookBranch
+	seed := func(body string) {
+		var depth Depth
+		if err := json.Unmarshal([]byte(body), &depth); err != nil {
+			t.Fatal(err)
+		}
+		if err := book.keeper.SeedFromDepth(depth); err != nil {
+			t.Fatal(err)
+		}
+	}
+	seed(`{"asks":[["103","1"]],"bids":[["99","1"]]}`)
+
+	p := NewPaperClient(Mc, BookPrices{"btcusdt": &book}, nil, PaperConfig{
+		Balances: map[string]decimal.Decimal{"usdt": decimal.NewFromInt(1000)},
+	})
+	order, err := p.PlaceLimitOrder("btcusdt", "buy", 102, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	seed(`{"asks":[["101","1"],["102","0.5"]],"bids":[["99","1"]]}`)
+	for i := 0; i < 3; i++ {
+		p.matchBooks()
+	}
+	if got, _ := p.GetOrder("btcusdt", order.Id, nil); got.ExecutedVolume != "1.5" {
+		t.Fatalf("static book filled %s", got.ExecutedVolume)
+	}
+
+	// a resized level is new liquidity, the untouched one is still taken.
+	seed(`{"asks":[["101","2"],["102","0.5"]],"bids":[["99","1"]]}`)
+	p.matchBooks()
+	p.matchBooks()
+	if got, _ := p.GetOrder("btcusdt", order.Id, nil); got.ExecutedVolume != "3.5" {
+		t.Fatalf("resized book filled %s", got.ExecutedVolume)
+	}
+
+	// a taker order only gets what the resting order left.
+	if _, err := p.PlaceMarketOrder("btcusdt", "buy", 1); err == nil {
+		t.Fatal("market order took consumed liquidity")
+	}
+}
+
+// test orders cancel by client oid, and the trade subscription of Start ends with its context
+func TestPaperClientCancel(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	Mc := &MaxClient{logger: logger}
+	Mc.MarketsBranch.Markets = []Market{{Id: "btcusdt", BaseUnit: "btc", QuoteUnit: "usdt"}}
+
+	trades := newTradeStreamBranch([]string{"btcusdt"}, TradeStreamConfig{}, logger)
+	p := NewPaperClient(Mc, BookPrices{}, trades, PaperConfig{
+		Balances: map[string]decimal.Decimal{"usdt": decimal.NewFromInt(1000)},
+	})
+	if _, err := p.PlaceTaggedOrder("btcusdt", "buy", "limit", 100, 2, "algo-1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.CancelOrder("btcusdt", nil, "algo-2"); err == nil {
+		t.Fatal("canceled an unknown client oid")
+	}
+	order, err := p.CancelOrder("btcusdt", nil, "algo-1")
+	if err != nil || order.State != "cancel" {
+		t.Fatalf("order %+v, err %v", order, err)
+	}
+	if balances, _ := p.GetBalance(); balances["usdt"].Locked != 0 || balances["usdt"].Avaliable != 1000 {
+		t.Errorf("usdt after cancel %+v", balances["usdt"])
+	}
+	if _, err := p.CancelOrder("btcusdt", nil, nil); err == nil {
+		t.Fatal("canceled without an id")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	p.Start(ctx)
+	count := func() int {
+		trades.listenersBranch.Lock()
+		defer trades.listenersBranch.Unlock()
+		return len(trades.listenersBranch.listeners)
+	}
+	if n := count(); n != 1 {
+		t.Fatalf("%d listeners", n)
+	}
+	cancel()
+	deadline := time.Now().Add(2 * time.Second)
+	for count() != 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("listener kept after the context is done")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
@@ -0,0 +1,140 @@
+package max_RESTfulAPI
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// PriceSource provides a reference price of a market, e.g. the mid of the local orderbook.
+type PriceSource interface {
+	MidPrice(market string) (decimal.Decimal, bool)
+}
+
+// currencies used as an intermediate leg when there is no direct market between two currencies.
+var bridgeCurrencies = []string{"usdt", "twd", "btc"}
+
+// BookPrices prices markets by the mid of the local orderbooks, keyed by market.
+type BookPrices map[string]*OrderbookBranch
+
+func (b BookPrices) MidPrice(market string) (decimal.Decimal, bool) {
+	ob, ok := b[NormalizeMarket(market)]
+	if !ok || ob == nil {
+		return decimal.Zero, false
+	}
+	return ob.MidPrice()
+}
+
+// PriceSources tries every source in order and returns the first price found.
+type PriceSources []PriceSource
+
+func (p PriceSources) MidPrice(market string) (decimal.Decimal, bool) {
+	for _, source := range p {
+		if source == nil {
+			continue
+		}
+		if price, ok := source.MidPrice(market); ok {
+			return price, true
+		}
+	}
+	return decimal.Zero, false
+}
+
+// TickerPrices prices markets by the REST ticker, cached for ttl.
+type TickerPrices struct {
+	api *APIClient
+	ttl time.Duration
+
+	cacheBranch struct {
+		prices map[string]cachedPrice
+		sync.RWMutex
+	}
+}
+
+type cachedPrice struct {
+	price decimal.Decimal
+	at    time.Time
+}
+
+func NewTickerPrices(api *APIClient, ttl time.Duration) *TickerPrices {
+	t := &TickerPrices{api: api, ttl: ttl}
+	t.cacheBranch.prices = make(map[string]cachedPrice)
+	return t
+}
+
+func (t *TickerPrices) MidPrice(market string) (decimal.Decimal, bool) {
+	market = NormalizeMarket(market)
+
+	t.cacheBranch.RLock()
+	cached, ok := t.cacheBranch.prices[market]
+	t.cacheBranch.RUnlock()
+	if ok && time.Since(cached.at) < t.ttl {
+		return cached.price, !cached.price.IsZero()
+	}
+
+	ticker, _, err := t.api.PublicApi.GetApiV2TickersMarket(context.Background(), market)
+	price := decimal.Zero
+	if err == nil {
+		price = tickerMid(ticker)
+	}
+
+	// unknown markets are cached as zero too, so the routing won't hammer the api.
+	t.cacheBranch.Lock()
+	t.cacheBranch.prices[market] = cachedPrice{price: price, at: time.Now()}
+	t.cacheBranch.Unlock()
+
+	return price, !price.IsZero()
+}
+
+func tickerMid(ticker Ticker) decimal.Decimal {
+	if ticker.Buy.IsPositive() && ticker.Sell.IsPositive() {
+		return ticker.Buy.Add(ticker.Sell).Div(decimal.NewFromInt(2))
+	}
+	return ticker.Last
+}
+
+// directRate returns how many $to one unit of $from is worth, using the from+to or to+from market.
+func directRate(prices PriceSource, from, to string) (decimal.Decimal, bool) {
+	if price, ok := prices.MidPrice(from + to); ok && price.IsPositive() {
+		return price, true
+	}
+	if price, ok := prices.MidPrice(to + from); ok && price.IsPositive() {
+		return decimal.NewFromInt(1).Div(price), true
+	}
+	return decimal.Zero, false
+}
+
+// convertAmount converts $amount of currency $from into currency $to,
+// going through one of the bridge currencies if there is no direct market.
+func convertAmount(prices PriceSource, amount decimal.Decimal, from, to string) (decimal.Decimal, bool) {
+	from, to = NormalizeCurrency(from), NormalizeCurrency(to)
+	if from == to || amount.IsZero() {
+		return amount, true
+	}
+	if prices == nil {
+		return decimal.Zero, false
+	}
+
+	if rate, ok := directRate(prices, from, to); ok {
+		return amount.Mul(rate), true
+	}
+
+	for _, bridge := range bridgeCurrencies {
+		if bridge == from || bridge == to {
+			continue
+		}
+		first, ok := directRate(prices, from, bridge)
+		if !ok {
+			continue
+		}
+		second, ok := directRate(prices, bridge, to)
+		if !ok {
+			continue
+		}
+		return amount.Mul(first).Mul(second), true
+	}
+
+	return decimal.Zero, false
+}
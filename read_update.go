@@ -8,6 +8,12 @@ func (Mc *MaxClient) ReadBaseOrderUnit() string {
 	return BaseOrderUnit
 }
 
+func (Mc *MaxClient) UpdateBaseOrderUnit(unit string) {
+	Mc.BaseOrderUnitBranch.Lock()
+	defer Mc.BaseOrderUnitBranch.Unlock()
+	Mc.BaseOrderUnitBranch.BaseOrderUnit = NormalizeCurrency(unit)
+}
+
 func (Mc *MaxClient) ReadExchangeInfo() ExchangeInfo {
 	Mc.ExchangeInfoBranch.RLock()
 	E := Mc.ExchangeInfoBranch.ExInfo
@@ -21,6 +27,12 @@ func (Mc *MaxClient) ReadMarkets() []Market {
 	return Mc.MarketsBranch.Markets
 }
 
+func (Mc *MaxClient) ReadCurrencies() []Currency {
+	Mc.CurrenciesBranch.RLock()
+	defer Mc.CurrenciesBranch.RUnlock()
+	return Mc.CurrenciesBranch.Currencies
+}
+
 func (Mc *MaxClient) ReadTrades() []Trade {
 	Mc.TradeBranch.RLock()
 	defer Mc.TradeBranch.RUnlock()
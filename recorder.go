@@ -0,0 +1,161 @@
+package max_RESTfulAPI
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/sirupsen/logrus"
+)
+
+// FrameTap sees every raw frame read by any websocket of the package, source is the stream name,
+// e.g. "orderbook btctwd" or "trade report". It runs on the websocket goroutines.
+type FrameTap func(source string, at time.Time, frame []byte)
+
+var frameTapBranch struct {
+	tap FrameTap
+	sync.RWMutex
+}
+
+// SetFrameTap installs the tap of the raw frames, e.g. FrameRecorder.Record, nil removes it.
+func SetFrameTap(tap FrameTap) {
+	frameTapBranch.Lock()
+	defer frameTapBranch.Unlock()
+	frameTapBranch.tap = tap
+}
+
+func tapFrame(source string, frame []byte) {
+	frameTapBranch.RLock()
+	tap := frameTapBranch.tap
+	frameTapBranch.RUnlock()
+	if tap != nil {
+		tap(source, time.Now(), frame)
+	}
+}
+
+// FrameRecord is one line of the recorded files.
+type FrameRecord struct {
+	// receive time
+	At     time.Time           `json:"at"`
+	Source string              `json:"source"`
+	Frame  jsoniter.RawMessage `json:"frame"`
+}
+
+// FrameRecorder writes frames as gzipped JSON lines, one file per day named frames-2006-01-02.jsonl.gz.
+// A file of the same day is appended to after a restart, as another gzip member.
+type FrameRecorder struct {
+	Dir string
+	// day boundary of the rotation, default to UTC.
+	Location *time.Location
+
+	logger *logrus.Logger
+
+	fileBranch struct {
+		day       string
+		file      *os.File
+		gz        *gzip.Writer
+		buf       *bufio.Writer
+		lastFlush time.Time
+		sync.Mutex
+	}
+}
+
+func NewFrameRecorder(dir string, logger *logrus.Logger) (*FrameRecorder, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("fail to create record dir: %w", err)
+	}
+	return &FrameRecorder{Dir: dir, Location: time.UTC, logger: logger}, nil
+}
+
+// Record appends a frame, it matches FrameTap. Write errors are logged, recording must not break trading.
+func (r *FrameRecorder) Record(source string, at time.Time, frame []byte) {
+	line, err := json.Marshal(FrameRecord{At: at, Source: source, Frame: append(jsoniter.RawMessage(nil), frame...)})
+	if err != nil {
+		// not a json frame, keep it as a string.
+		line, err = json.Marshal(map[string]interface{}{"at": at, "source": source, "frame": string(frame)})
+		if err != nil {
+			return
+		}
+	}
+
+	r.fileBranch.Lock()
+	defer r.fileBranch.Unlock()
+	if err := r.rotate(at); err != nil {
+		r.logger.Warn("fail to open record file: ", err)
+		return
+	}
+	r.fileBranch.buf.Write(line)
+	r.fileBranch.buf.WriteByte('\n')
+
+	// bound what a crash loses to about a second of frames.
+	if time.Since(r.fileBranch.lastFlush) >= time.Second {
+		if err := r.flush(); err != nil {
+			r.logger.Warn("fail to flush record file: ", err)
+		}
+	}
+}
+
+// rotate opens the file of the day of at, with the lock held.
+func (r *FrameRecorder) rotate(at time.Time) error {
+	loc := r.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+	day := at.In(loc).Format("2006-01-02")
+	if r.fileBranch.file != nil && r.fileBranch.day == day {
+		return nil
+	}
+	if err := r.closeFile(); err != nil {
+		r.logger.Warn("fail to close record file: ", err)
+	}
+
+	path := filepath.Join(r.Dir, "frames-"+day+".jsonl.gz")
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	r.fileBranch.day = day
+	r.fileBranch.file = file
+	r.fileBranch.gz = gzip.NewWriter(file)
+	r.fileBranch.buf = bufio.NewWriter(r.fileBranch.gz)
+	r.fileBranch.lastFlush = time.Now()
+	return nil
+}
+
+func (r *FrameRecorder) flush() error {
+	r.fileBranch.lastFlush = time.Now()
+	if r.fileBranch.file == nil {
+		return nil
+	}
+	if err := r.fileBranch.buf.Flush(); err != nil {
+		return err
+	}
+	return r.fileBranch.gz.Flush()
+}
+
+func (r *FrameRecorder) closeFile() error {
+	if r.fileBranch.file == nil {
+		return nil
+	}
+	err := r.flush()
+	if cerr := r.fileBranch.gz.Close(); err == nil {
+		err = cerr
+	}
+	if cerr := r.fileBranch.file.Close(); err == nil {
+		err = cerr
+	}
+	r.fileBranch.file, r.fileBranch.gz, r.fileBranch.buf = nil, nil, nil
+	return err
+}
+
+// Close flushes and closes the current file, a later Record opens it again.
+func (r *FrameRecorder) Close() error {
+	r.fileBranch.Lock()
+	defer r.fileBranch.Unlock()
+	return r.closeFile()
+}
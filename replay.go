@@ -0,0 +1,198 @@
+package max_RESTfulAPI
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+type ReplayConfig struct {
+	// 1 replays at the recorded speed, 10 ten times faster, 0 as fast as possible.
+	Speed float64
+	// frames received outside of [From, To) are skipped, zero for unbounded.
+	From time.Time
+	To   time.Time
+}
+
+// Replay feeds recorded frames back through the websocket handlers of the package, in the order received,
+// with a virtual clock at the receive time of the current frame.
+type Replay struct {
+	Config ReplayConfig
+	Files  []string
+
+	logger *logrus.Logger
+
+	handlersBranch struct {
+		handlers []replayHandler
+		sync.RWMutex
+	}
+
+	clockBranch struct {
+		now time.Time
+		sync.RWMutex
+	}
+}
+
+type replayHandler struct {
+	match  func(source string, channel, market string) bool
+	handle func(msg []byte) error
+}
+
+// NewReplay replays the record files, sorted by name, which is by day for the FrameRecorder files.
+func NewReplay(files []string, cfg ReplayConfig, logger *logrus.Logger) *Replay {
+	files = append([]string(nil), files...)
+	sort.Strings(files)
+	return &Replay{Config: cfg, Files: files, logger: logger}
+}
+
+// Now is the virtual clock, the receive time of the frame being replayed.
+func (r *Replay) Now() time.Time {
+	r.clockBranch.RLock()
+	defer r.clockBranch.RUnlock()
+	return r.clockBranch.now
+}
+
+// Handle feeds the frames of a source, the stream name the frames were recorded under, to handle.
+func (r *Replay) Handle(source string, handle func(msg []byte) error) {
+	r.addHandler(replayHandler{
+		match:  func(s, _, _ string) bool { return s == source },
+		handle: handle,
+	})
+}
+
+func (r *Replay) addHandler(h replayHandler) {
+	r.handlersBranch.Lock()
+	defer r.handlersBranch.Unlock()
+	r.handlersBranch.handlers = append(r.handlersBranch.handlers, h)
+}
+
+// Orderbook rebuilds the local orderbook of a market from the recorded book frames.
+func (r *Replay) Orderbook(market string) *OrderbookBranch {
+	var o OrderbookBranch
+	o.Market = NormalizeMarket(market)
+	o.logger = r.logger
+	o.health = newHealthTracker("replay orderbook " + o.Market)
+	o.now = r.Now
+	r.addHandler(replayHandler{
+		match:  func(_, channel, m string) bool { return channel == "book" && m == o.Market },
+		handle: o.handleMaxBookSocketMsg,
+	})
+	return &o
+}
+
+// TradeStream rebuilds the public trades of markets from the recorded trade frames.
+func (r *Replay) TradeStream(markets []string, cfg TradeStreamConfig) *TradeStreamBranch {
+	o := newTradeStreamBranch(markets, cfg, r.logger)
+	cancel := context.CancelFunc(func() {})
+	o.cancel = &cancel
+	r.addHandler(replayHandler{
+		match:  func(_, channel, m string) bool { return channel == "trade" && o.subscribed(m) },
+		handle: o.handleMaxTradeSocketMsg,
+	})
+	return o
+}
+
+// TradeReports feeds the recorded private trade reports to Mc, as its private websocket would.
+func (r *Replay) TradeReports(Mc *MaxClient) {
+	if Mc.WsClient.health == nil {
+		Mc.WsClient.health = newHealthTracker("replay trade report")
+	}
+	r.Handle("trade report", Mc.handleTradeReportMsg)
+}
+
+// Run replays every file until the end or ctx is done.
+func (r *Replay) Run(ctx context.Context) error {
+	var last time.Time
+	for _, path := range r.Files {
+		if err := r.replayFile(ctx, path, &last); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *Replay) replayFile(ctx context.Context, path string, last *time.Time) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("fail to open record file: %w", err)
+	}
+	defer file.Close()
+
+	var reader *bufio.Reader
+	if strings.HasSuffix(path, ".gz") {
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			return fmt.Errorf("fail to read record file %s: %w", path, err)
+		}
+		defer gz.Close()
+		reader = bufio.NewReader(gz)
+	} else {
+		reader = bufio.NewReader(file)
+	}
+
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		var record FrameRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			r.logger.Warnf("skip a bad record of %s: %v", path, err)
+			continue
+		}
+		if (!r.Config.From.IsZero() && record.At.Before(r.Config.From)) || (!r.Config.To.IsZero() && !record.At.Before(r.Config.To)) {
+			continue
+		}
+
+		if r.Config.Speed > 0 && !last.IsZero() && record.At.After(*last) {
+			wait := time.Duration(float64(record.At.Sub(*last)) / r.Config.Speed)
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(wait):
+			}
+		}
+		*last = record.At
+
+		r.clockBranch.Lock()
+		r.clockBranch.now = record.At
+		r.clockBranch.Unlock()
+
+		r.dispatch(record)
+	}
+	// a file cut by a crash ends with a truncated gzip member, keep what was read.
+	if err := scanner.Err(); err != nil {
+		r.logger.Warnf("stop reading %s: %v", path, err)
+	}
+	return nil
+}
+
+func (r *Replay) dispatch(record FrameRecord) {
+	var head struct {
+		Channel string `json:"c"`
+		Market  string `json:"M"`
+	}
+	json.Unmarshal(record.Frame, &head)
+
+	r.handlersBranch.RLock()
+	handlers := r.handlersBranch.handlers
+	r.handlersBranch.RUnlock()
+	for _, h := range handlers {
+		if !h.match(record.Source, head.Channel, head.Market) {
+			continue
+		}
+		// live, an error drops the connection and the next snapshot resyncs, which the record holds too.
+		if err := h.handle(record.Frame); err != nil {
+			r.logger.Debugf("replay %s frame at %s: %v", record.Source, record.At.Format(time.RFC3339Nano), err)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package max_RESTfulAPI
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/sirupsen/logrus"
+)
+
+// test recording frames to disk and rebuilding the book and trades from them
+func TestRecordAndReplay(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	dir := t.TempDir()
+	r, err := NewFrameRecorder(dir, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Date(2026, 1, 1, 23, 59, 59, 0, time.UTC)
+	ms := start.UnixMilli()
+	frames := []string{
+		fmt.Sprintf(`{"c":"book","e":"snapshot","M":"btcusdt","a":[["101","1"]],"b":[["99","2"]],"T":%d}`, ms),
+		fmt.Sprintf(`{"c":"trade","e":"update","M":"btcusdt","t":[{"i":7,"p":"100","v":"0.5","T":%d,"tr":"up"}],"T":%d}`, ms+500, ms+500),
+		// after midnight, in the next file.
+		fmt.Sprintf(`{"c":"book","e":"update","M":"btcusdt","a":[["100.5","3"]],"b":[],"T":%d}`, ms+1500),
+	}
+	for i, frame := range frames {
+		r.Record("orderbook btcusdt", start.Add(time.Duration(i)*750*time.Millisecond), []byte(frame))
+	}
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+	files, _ := filepath.Glob(filepath.Join(dir, "*.jsonl.gz"))
+	if len(files) != 2 {
+		t.Fatalf("files %v", files)
+	}
+
+	replay := NewReplay(files, ReplayConfig{}, logger)
+	book := replay.Orderbook("btcusdt")
+	trades := replay.TradeStream([]string{"btcusdt"}, TradeStreamConfig{})
+	if err := replay.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	asks, ok := book.GetAsks()
+	if !ok || len(asks) != 2 || !asks[0][0].Equal(decimal.RequireFromString("100.5")) {
+		t.Fatalf("asks %v", asks)
+	}
+	if got := trades.GetTrades(); len(got) != 1 || got[0].ID != 7 || got[0].Side != TakerSideBuy {
+		t.Fatalf("trades %+v", got)
+	}
+	if !replay.Now().Equal(start.Add(1500 * time.Millisecond)) {
+		t.Errorf("clock %s", replay.Now())
+	}
+}
@@ -0,0 +1,588 @@
+package max_RESTfulAPI
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/sirupsen/logrus"
+)
+
+// RiskRule names the pre-trade check that rejected an order.
+type RiskRule string
+
+const (
+	RiskRuleMaxNotional   RiskRule = "max_notional"
+	RiskRuleMaxPosition   RiskRule = "max_position"
+	RiskRulePriceBand     RiskRule = "price_band"
+	RiskRuleMaxOpenOrders RiskRule = "max_open_orders"
+	RiskRuleFatFinger     RiskRule = "fat_finger"
+	RiskRuleBalance       RiskRule = "balance"
+	RiskRuleNoPrice       RiskRule = "no_price"
+)
+
+// ErrRiskRejected is matched by errors.Is for every RiskError.
+var ErrRiskRejected = errors.New("order rejected by risk check")
+
+// RiskError is returned by the order placement functions when the risk gate rejects an order.
+type RiskError struct {
+	Rule   RiskRule
+	Market string
+	Reason string
+}
+
+func (e *RiskError) Error() string {
+	return fmt.Sprintf("risk check %s rejected %s order: %s", e.Rule, e.Market, e.Reason)
+}
+
+func (e *RiskError) Is(target error) bool {
+	return target == ErrRiskRejected
+}
+
+// RiskConfig holds the pre-trade limits, a zero value disables the corresponding check.
+type RiskConfig struct {
+	// max notional of one order, in NotionalCurrency.
+	MaxOrderNotional decimal.Decimal
+	NotionalCurrency string
+
+	// max total holding per asset once the order and the open buys fill, key is the lowercase currency.
+	// Only buys are checked, a spot sell can't raise a holding.
+	MaxPosition map[string]decimal.Decimal
+
+	// max distance of the limit price from the local book mid, e.g. 0.02 for 2%.
+	PriceBand decimal.Decimal
+	// reject orders when there is no mid price to check against.
+	RequireMid bool
+
+	MaxOpenOrdersPerMarket int
+
+	// fat-finger guard: max volume per order by market, and max jump from the last accepted price.
+	MaxOrderVolume map[string]decimal.Decimal
+	FatFingerJump  decimal.Decimal
+
+	// reject orders the available balance can't pay for: the notional in quote for a buy, the volume for a sell.
+	// Fees are left out, MAX takes them from the received currency or from MAX.
+	CheckBalance bool
+}
+
+// RiskDecision is one audited pre-trade decision.
+type RiskDecision struct {
+	Time     time.Time
+	Market   string
+	Side     string
+	OrdType  string
+	Price    float64
+	Volume   float64
+	Accepted bool
+	Err      *RiskError
+}
+
+// RiskGate runs the pre-trade checks every MaxClient order placement goes through.
+type RiskGate struct {
+	Config RiskConfig
+	Prices PriceSource
+	// called with every decision besides the log entry.
+	AuditFunc func(RiskDecision)
+
+	logger *logrus.Logger
+
+	stateBranch struct {
+		balances   map[string]Balance
+		openOrders map[string]int
+		lastPrice  map[string]decimal.Decimal
+		markets    []Market
+		// orders holding a reservation, by id.
+		orders map[int64]*riskOrder
+		// volume of fills that came before the placement returned, by order id.
+		early map[int64]decimal.Decimal
+		// reservations of the admitted orders still being placed.
+		pending map[*riskOrder]*riskTicket
+		sync.RWMutex
+	}
+}
+
+// riskTicket is the reservation an admitted order holds while it is placed, settled by placed or release.
+type riskTicket struct {
+	gate *RiskGate
+	ro   *riskOrder
+	// what is still held, SetBalances and SetOpenOrders start over without it.
+	reserved, counted bool
+}
+
+// riskOrder is the part of an order the gate reserved balance for.
+type riskOrder struct {
+	market    string
+	side      string
+	price     decimal.Decimal
+	remaining decimal.Decimal
+	// counted by MaxOpenOrdersPerMarket.
+	resting bool
+}
+
+func NewRiskGate(config RiskConfig, prices PriceSource, logger *logrus.Logger) *RiskGate {
+	g := &RiskGate{Config: config, Prices: prices, logger: logger}
+	g.stateBranch.balances = make(map[string]Balance)
+	g.stateBranch.openOrders = make(map[string]int)
+	g.stateBranch.lastPrice = make(map[string]decimal.Decimal)
+	g.stateBranch.orders = make(map[int64]*riskOrder)
+	g.stateBranch.early = make(map[int64]decimal.Decimal)
+	g.stateBranch.pending = make(map[*riskOrder]*riskTicket)
+	return g
+}
+
+// SetRiskGate makes every order placement of the client go through the gate, nil removes it.
+func (Mc *MaxClient) SetRiskGate(g *RiskGate) {
+	Mc.RiskBranch.Lock()
+	defer Mc.RiskBranch.Unlock()
+	Mc.RiskBranch.Gate = g
+	if g != nil {
+		g.SetMarkets(Mc.ReadMarkets())
+	}
+}
+
+func (Mc *MaxClient) ReadRiskGate() *RiskGate {
+	Mc.RiskBranch.RLock()
+	defer Mc.RiskBranch.RUnlock()
+	return Mc.RiskBranch.Gate
+}
+
+// RefreshRiskState reloads balances and open orders of the gate from the api.
+func (Mc *MaxClient) RefreshRiskState() error {
+	g := Mc.ReadRiskGate()
+	if g == nil {
+		return nil
+	}
+	balances, err := Mc.GetBalance()
+	if err != nil {
+		return err
+	}
+	g.SetBalances(balances)
+
+	orders, err := Mc.GetAllOrders()
+	if err != nil {
+		return err
+	}
+	g.SetOpenOrders(orders)
+	return nil
+}
+
+func (g *RiskGate) SetMarkets(markets []Market) {
+	g.stateBranch.Lock()
+	defer g.stateBranch.Unlock()
+	g.stateBranch.markets = markets
+}
+
+func (g *RiskGate) SetBalances(balances map[string]Balance) {
+	g.stateBranch.Lock()
+	defer g.stateBranch.Unlock()
+	g.stateBranch.balances = make(map[string]Balance, len(balances))
+	for currency, balance := range balances {
+		g.stateBranch.balances[NormalizeCurrency(currency)] = balance
+	}
+	for _, t := range g.stateBranch.pending {
+		t.reserved = false
+	}
+}
+
+// SetOpenOrders replaces the tracked open orders, their reservation is part of the locked balances.
+func (g *RiskGate) SetOpenOrders(orders map[int64]WsOrder) {
+	g.stateBranch.Lock()
+	defer g.stateBranch.Unlock()
+	g.stateBranch.openOrders = make(map[string]int)
+	g.stateBranch.orders = make(map[int64]*riskOrder)
+	g.stateBranch.early = make(map[int64]decimal.Decimal)
+	for _, t := range g.stateBranch.pending {
+		t.counted = false
+	}
+	for _, order := range orders {
+		g.stateBranch.openOrders[order.Market]++
+		price, _ := decimal.NewFromString(order.Price)
+		remaining, err := decimal.NewFromString(order.RemainingVolume)
+		if err != nil {
+			remaining, _ = decimal.NewFromString(order.Volume)
+		}
+		g.stateBranch.orders[order.Id] = &riskOrder{
+			market:    order.Market,
+			side:      order.Side,
+			price:     price,
+			remaining: remaining,
+			resting:   true,
+		}
+	}
+}
+
+func (g *RiskGate) ReadBalances() map[string]Balance {
+	g.stateBranch.RLock()
+	defer g.stateBranch.RUnlock()
+	balances := make(map[string]Balance, len(g.stateBranch.balances))
+	for k, v := range g.stateBranch.balances {
+		balances[k] = v
+	}
+	return balances
+}
+
+// Check runs every configured check on an order, price is ignored for market orders.
+// It reserves nothing, the order placements of the client are admitted atomically with their reservation.
+func (g *RiskGate) Check(market, side, ordType string, price, volume float64) error {
+	_, err := g.admit(market, side, ordType, price, volume, false)
+	return err
+}
+
+// admit checks an order like Check, and with reserve set holds its balance and open order slot
+// in the same critical section, so concurrent placements can't pass against the same state.
+func (g *RiskGate) admit(market, side, ordType string, price, volume float64, reserve bool) (*riskTicket, error) {
+	market, side = NormalizeMarket(market), strings.ToLower(side)
+	ticket, rerr := g.check(market, side, ordType, price, volume, reserve)
+	g.audit(RiskDecision{
+		Time:     time.Now(),
+		Market:   market,
+		Side:     side,
+		OrdType:  ordType,
+		Price:    price,
+		Volume:   volume,
+		Accepted: rerr == nil,
+		Err:      rerr,
+	})
+	if rerr != nil {
+		return nil, rerr
+	}
+	return ticket, nil
+}
+
+func (g *RiskGate) check(market, side, ordType string, price, volume float64, reserve bool) (*riskTicket, *RiskError) {
+	cfg := g.Config
+	reject := func(rule RiskRule, format string, a ...interface{}) *RiskError {
+		return &RiskError{Rule: rule, Market: market, Reason: fmt.Sprintf(format, a...)}
+	}
+
+	g.stateBranch.RLock()
+	base, quote, ok := splitMarket(g.stateBranch.markets, market)
+	g.stateBranch.RUnlock()
+	if !ok {
+		return nil, reject(RiskRuleNoPrice, "unknown market")
+	}
+
+	v := decimal.NewFromFloat(volume)
+	mid, hasMid := decimal.Zero, false
+	if g.Prices != nil {
+		mid, hasMid = g.Prices.MidPrice(market)
+	}
+
+	p := decimal.NewFromFloat(price)
+	if ordType == "market" {
+		if !hasMid {
+			if cfg.RequireMid || cfg.CheckBalance || cfg.MaxOrderNotional.IsPositive() {
+				return nil, reject(RiskRuleNoPrice, "no mid price to value the market order")
+			}
+		}
+		p = mid
+	}
+
+	if maxVolume, ok := cfg.MaxOrderVolume[market]; ok && v.GreaterThan(maxVolume) {
+		return nil, reject(RiskRuleFatFinger, "volume %s above %s", v, maxVolume)
+	}
+	if cfg.FatFingerJump.IsPositive() && ordType != "market" {
+		g.stateBranch.RLock()
+		last, ok := g.stateBranch.lastPrice[market]
+		g.stateBranch.RUnlock()
+		if ok && last.IsPositive() && p.Sub(last).Abs().Div(last).GreaterThan(cfg.FatFingerJump) {
+			return nil, reject(RiskRuleFatFinger, "price %s jumps too far from last %s", p, last)
+		}
+	}
+
+	if cfg.PriceBand.IsPositive() && ordType != "market" {
+		switch {
+		case hasMid && mid.IsPositive():
+			if p.Sub(mid).Abs().Div(mid).GreaterThan(cfg.PriceBand) {
+				return nil, reject(RiskRulePriceBand, "price %s out of band around mid %s", p, mid)
+			}
+		case cfg.RequireMid:
+			return nil, reject(RiskRuleNoPrice, "no mid price for price band")
+		}
+	}
+
+	notional := p.Mul(v)
+	if cfg.MaxOrderNotional.IsPositive() {
+		currency := cfg.NotionalCurrency
+		if currency == "" {
+			currency = quote
+		}
+		converted, ok := convertAmount(g.Prices, notional, quote, currency)
+		if !ok {
+			return nil, reject(RiskRuleNoPrice, "no price route from %s to %s", quote, currency)
+		}
+		if converted.GreaterThan(cfg.MaxOrderNotional) {
+			return nil, reject(RiskRuleMaxNotional, "notional %s %s above %s", converted, currency, cfg.MaxOrderNotional)
+		}
+	}
+
+	if reserve {
+		g.stateBranch.Lock()
+		defer g.stateBranch.Unlock()
+	} else {
+		g.stateBranch.RLock()
+		defer g.stateBranch.RUnlock()
+	}
+
+	if cfg.MaxOpenOrdersPerMarket > 0 && ordType != "market" && g.stateBranch.openOrders[market] >= cfg.MaxOpenOrdersPerMarket {
+		return nil, reject(RiskRuleMaxOpenOrders, "%d open orders", g.stateBranch.openOrders[market])
+	}
+
+	if limit, ok := cfg.MaxPosition[base]; ok && side == "buy" {
+		holding := g.stateBranch.balances[base]
+		after := decimal.NewFromFloat(holding.Avaliable + holding.Locked).Add(g.openBuys(base)).Add(v)
+		if after.GreaterThan(limit) {
+			return nil, reject(RiskRuleMaxPosition, "%s position %s above %s", base, after, limit)
+		}
+	}
+
+	if cfg.CheckBalance {
+		if side == "buy" {
+			available := decimal.NewFromFloat(g.stateBranch.balances[quote].Avaliable)
+			if available.LessThan(notional) {
+				return nil, reject(RiskRuleBalance, "%s available %s, need %s", quote, available, notional)
+			}
+		} else {
+			available := decimal.NewFromFloat(g.stateBranch.balances[base].Avaliable)
+			if available.LessThan(v) {
+				return nil, reject(RiskRuleBalance, "%s available %s, need %s", base, available, v)
+			}
+		}
+	}
+
+	if !reserve {
+		return nil, nil
+	}
+	t := &riskTicket{gate: g, ro: &riskOrder{market: market, side: side, price: p, remaining: v, resting: ordType != "market"}, reserved: true, counted: true}
+	g.reserve(t.ro, v, true)
+	if t.ro.resting {
+		g.stateBranch.openOrders[market]++
+	}
+	g.stateBranch.pending[t.ro] = t
+	return t, nil
+}
+
+// openBuys is the base volume the open and pending buys of base may still receive, with the lock held.
+func (g *RiskGate) openBuys(base string) decimal.Decimal {
+	total := decimal.Zero
+	add := func(ro *riskOrder) {
+		if ro.side != "buy" && ro.side != "bid" {
+			return
+		}
+		if b, _, ok := splitMarket(g.stateBranch.markets, ro.market); ok && b == base {
+			total = total.Add(ro.remaining)
+		}
+	}
+	for _, ro := range g.stateBranch.orders {
+		add(ro)
+	}
+	for ro := range g.stateBranch.pending {
+		add(ro)
+	}
+	return total
+}
+
+// settle gives back what the ticket still holds, with the lock held.
+func (t *riskTicket) settle() {
+	g := t.gate
+	delete(g.stateBranch.pending, t.ro)
+	if t.reserved {
+		g.reserve(t.ro, t.ro.remaining, false)
+	}
+	if t.counted && t.ro.resting && g.stateBranch.openOrders[t.ro.market] > 0 {
+		g.stateBranch.openOrders[t.ro.market]--
+	}
+	t.reserved, t.counted = false, false
+}
+
+// placed swaps the reservation of the admission for the one of the placed order. A nil ticket does nothing.
+func (t *riskTicket) placed(order WsOrder) {
+	if t == nil {
+		return
+	}
+	t.gate.stateBranch.Lock()
+	defer t.gate.stateBranch.Unlock()
+	t.settle()
+	t.gate.orderPlacedLocked(order)
+}
+
+// release gives back the reservation of an order whose placement failed. A nil ticket does nothing.
+func (t *riskTicket) release() {
+	if t == nil {
+		return
+	}
+	t.gate.stateBranch.Lock()
+	defer t.gate.stateBranch.Unlock()
+	t.settle()
+}
+
+// orderPlaced reserves the balance of an accepted order until it fills or is canceled.
+func (g *RiskGate) orderPlaced(order WsOrder) {
+	g.stateBranch.Lock()
+	defer g.stateBranch.Unlock()
+	g.orderPlacedLocked(order)
+}
+
+func (g *RiskGate) orderPlacedLocked(order WsOrder) {
+	resting := order.OrdType != "market"
+	price, err := decimal.NewFromString(order.Price)
+	if err == nil && price.IsPositive() {
+		g.stateBranch.lastPrice[order.Market] = price
+	} else {
+		price = decimal.Zero
+	}
+
+	volume, err := decimal.NewFromString(order.Volume)
+	if err != nil {
+		if resting {
+			g.stateBranch.openOrders[order.Market]++
+		}
+		return
+	}
+	// the early fills were settled from the available balance already.
+	volume = volume.Sub(g.stateBranch.early[order.Id])
+	delete(g.stateBranch.early, order.Id)
+	if !volume.IsPositive() {
+		return
+	}
+	if resting {
+		g.stateBranch.openOrders[order.Market]++
+	}
+	ro := &riskOrder{market: order.Market, side: order.Side, price: price, remaining: volume, resting: resting}
+	g.stateBranch.orders[order.Id] = ro
+	g.reserve(ro, volume, true)
+}
+
+// reserve moves the balance of volume of ro between available and locked, with the lock held.
+func (g *RiskGate) reserve(ro *riskOrder, volume decimal.Decimal, lock bool) {
+	base, quote, ok := splitMarket(g.stateBranch.markets, ro.market)
+	if !ok {
+		return
+	}
+	currency, amount := base, volume
+	if ro.side == "buy" || ro.side == "bid" {
+		currency, amount = quote, volume.Mul(ro.price)
+	}
+	reserved, _ := amount.Float64()
+	if !lock {
+		reserved = -reserved
+	}
+	balance := g.stateBranch.balances[currency]
+	balance.Avaliable -= reserved
+	balance.Locked += reserved
+	g.stateBranch.balances[currency] = balance
+}
+
+// tradesArrived settles fills at their own price net of fees, releasing the order once fully filled.
+func (g *RiskGate) tradesArrived(trades []Trade) {
+	g.stateBranch.Lock()
+	defer g.stateBranch.Unlock()
+
+	for _, trade := range trades {
+		base, quote, ok := splitMarket(g.stateBranch.markets, trade.Market)
+		if !ok {
+			continue
+		}
+		price, errP := decimal.NewFromString(trade.Price)
+		volume, errV := decimal.NewFromString(trade.Volume)
+		if errP != nil || errV != nil {
+			continue
+		}
+
+		ro, tracked := g.stateBranch.orders[trade.Oid]
+		if tracked {
+			// give back the reservation of the filled volume, then pay from available.
+			g.reserve(ro, decimal.Min(volume, ro.remaining), false)
+			ro.remaining = ro.remaining.Sub(volume)
+			if !ro.remaining.IsPositive() {
+				delete(g.stateBranch.orders, trade.Oid)
+				if ro.resting && g.stateBranch.openOrders[ro.market] > 0 {
+					g.stateBranch.openOrders[ro.market]--
+				}
+			}
+		} else if trade.Oid != 0 {
+			g.stateBranch.early[trade.Oid] = g.stateBranch.early[trade.Oid].Add(volume)
+		}
+
+		v, _ := volume.Float64()
+		notional, _ := price.Mul(volume).Float64()
+		baseBalance, quoteBalance := g.stateBranch.balances[base], g.stateBranch.balances[quote]
+		if trade.Side == "buy" || trade.Side == "bid" {
+			quoteBalance.Avaliable -= notional
+			baseBalance.Avaliable += v
+		} else {
+			baseBalance.Avaliable -= v
+			quoteBalance.Avaliable += notional
+		}
+		g.stateBranch.balances[base], g.stateBranch.balances[quote] = baseBalance, quoteBalance
+
+		if fee, err := decimal.NewFromString(trade.Fee); err == nil && trade.FeeCurrency != "" {
+			currency := NormalizeCurrency(trade.FeeCurrency)
+			balance := g.stateBranch.balances[currency]
+			f, _ := fee.Float64()
+			balance.Avaliable -= f
+			g.stateBranch.balances[currency] = balance
+		}
+	}
+}
+
+// orderCanceled releases what is left of the reservation of a canceled order.
+func (g *RiskGate) orderCanceled(order WsOrder) {
+	g.stateBranch.Lock()
+	defer g.stateBranch.Unlock()
+	ro, ok := g.stateBranch.orders[order.Id]
+	if !ok {
+		return
+	}
+	delete(g.stateBranch.orders, order.Id)
+	g.reserve(ro, ro.remaining, false)
+	if ro.resting && g.stateBranch.openOrders[ro.market] > 0 {
+		g.stateBranch.openOrders[ro.market]--
+	}
+}
+
+func (g *RiskGate) allOrdersCanceled() {
+	g.stateBranch.Lock()
+	defer g.stateBranch.Unlock()
+	for _, ro := range g.stateBranch.orders {
+		g.reserve(ro, ro.remaining, false)
+	}
+	g.stateBranch.orders = make(map[int64]*riskOrder)
+	g.stateBranch.openOrders = make(map[string]int)
+	for _, t := range g.stateBranch.pending {
+		t.counted = false
+	}
+}
+
+func (g *RiskGate) audit(d RiskDecision) {
+	if g.logger != nil {
+		entry := g.logger.WithFields(logrus.Fields{
+			"market":   d.Market,
+			"side":     d.Side,
+			"ord_type": d.OrdType,
+			"price":    d.Price,
+			"volume":   d.Volume,
+		})
+		if d.Accepted {
+			entry.Info("risk check accepted")
+		} else {
+			entry.WithField("rule", d.Err.Rule).Warn("risk check rejected: ", d.Err.Reason)
+		}
+	}
+	if g.AuditFunc != nil {
+		g.AuditFunc(d)
+	}
+}
+
+// admitRisk checks an order against the risk gate and reserves for it, a nil ticket without a gate.
+// The ticket must be settled with placed or release once the placement returns.
+func (Mc *MaxClient) admitRisk(market, side, ordType string, price, volume float64) (*riskTicket, error) {
+	g := Mc.ReadRiskGate()
+	if g == nil {
+		return nil, nil
+	}
+	return g.admit(market, side, ordType, price, volume, true)
+}
@@ -0,0 +1,207 @@
+package max_RESTfulAPI
+
+import (
+	"errors"
+	"io"
+	"sync"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/sirupsen/logrus"
+)
+
+type fixedPrices map[string]decimal.Decimal
+
+func (p fixedPrices) MidPrice(market string) (decimal.Decimal, bool) {
+	price, ok := p[market]
+	return price, ok
+}
+
+func newTestRiskGate(cfg RiskConfig) *RiskGate {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	g := NewRiskGate(cfg, fixedPrices{"btcusdt": decimal.NewFromInt(100)}, logger)
+	g.SetMarkets([]Market{{Id: "btcusdt", BaseUnit: "btc", QuoteUnit: "usdt"}})
+	g.SetBalances(map[string]Balance{"USDT": {Avaliable: 1000}, "BTC": {Avaliable: 10}})
+	return g
+}
+
+func expectRule(t *testing.T, err error, rule RiskRule) {
+	t.Helper()
+	var rerr *RiskError
+	if !errors.As(err, &rerr) || rerr.Rule != rule || !errors.Is(err, ErrRiskRejected) {
+		t.Fatalf("want %s rejection, got %v", rule, err)
+	}
+}
+
+func TestRiskMaxNotional(t *testing.T) {
+	g := newTestRiskGate(RiskConfig{MaxOrderNotional: decimal.NewFromInt(500)})
+	if err := g.Check("btcusdt", "buy", "limit", 100, 5); err != nil {
+		t.Fatal(err)
+	}
+	expectRule(t, g.Check("btcusdt", "buy", "limit", 100, 5.1), RiskRuleMaxNotional)
+	// market orders are valued at the mid.
+	expectRule(t, g.Check("btcusdt", "sell", "market", 0, 6), RiskRuleMaxNotional)
+}
+
+func TestRiskMaxPosition(t *testing.T) {
+	g := newTestRiskGate(RiskConfig{MaxPosition: map[string]decimal.Decimal{"btc": decimal.NewFromInt(12)}})
+	if err := g.Check("btcusdt", "buy", "limit", 100, 2); err != nil {
+		t.Fatal(err)
+	}
+	expectRule(t, g.Check("btcusdt", "buy", "limit", 100, 3), RiskRuleMaxPosition)
+	if err := g.Check("btcusdt", "sell", "limit", 100, 3); err != nil {
+		t.Fatal(err)
+	}
+
+	// the open buys count toward the position.
+	g.orderPlaced(WsOrder{Id: 1, Market: "btcusdt", Side: "buy", OrdType: "limit", Price: "100", Volume: "1.5"})
+	expectRule(t, g.Check("btcusdt", "buy", "limit", 100, 1), RiskRuleMaxPosition)
+}
+
+func TestRiskPriceBand(t *testing.T) {
+	g := newTestRiskGate(RiskConfig{PriceBand: decimal.RequireFromString("0.02")})
+	if err := g.Check("btcusdt", "buy", "limit", 98, 1); err != nil {
+		t.Fatal(err)
+	}
+	expectRule(t, g.Check("btcusdt", "sell", "limit", 103, 1), RiskRulePriceBand)
+}
+
+func TestRiskNoPrice(t *testing.T) {
+	g := newTestRiskGate(RiskConfig{PriceBand: decimal.RequireFromString("0.02"), RequireMid: true})
+	g.SetMarkets([]Market{{Id: "btcusdt", BaseUnit: "btc", QuoteUnit: "usdt"}, {Id: "ethusdt", BaseUnit: "eth", QuoteUnit: "usdt"}})
+	expectRule(t, g.Check("ethusdt", "buy", "limit", 100, 1), RiskRuleNoPrice)
+	expectRule(t, g.Check("dogeusdt", "buy", "limit", 100, 1), RiskRuleNoPrice)
+}
+
+func TestRiskFatFinger(t *testing.T) {
+	g := newTestRiskGate(RiskConfig{
+		MaxOrderVolume: map[string]decimal.Decimal{"btcusdt": decimal.NewFromInt(3)},
+		FatFingerJump:  decimal.RequireFromString("0.1"),
+	})
+	expectRule(t, g.Check("btcusdt", "buy", "limit", 100, 4), RiskRuleFatFinger)
+	g.orderPlaced(WsOrder{Id: 1, Market: "btcusdt", Side: "buy", OrdType: "limit", Price: "100", Volume: "1"})
+	if err := g.Check("btcusdt", "buy", "limit", 109, 1); err != nil {
+		t.Fatal(err)
+	}
+	expectRule(t, g.Check("btcusdt", "buy", "limit", 111, 1), RiskRuleFatFinger)
+}
+
+func TestRiskMaxOpenOrders(t *testing.T) {
+	g := newTestRiskGate(RiskConfig{MaxOpenOrdersPerMarket: 2})
+	g.orderPlaced(WsOrder{Id: 1, Market: "btcusdt", Side: "buy", OrdType: "limit", Price: "100", Volume: "1"})
+	g.orderPlaced(WsOrder{Id: 2, Market: "btcusdt", Side: "sell", OrdType: "limit", Price: "101", Volume: "1"})
+	expectRule(t, g.Check("btcusdt", "buy", "limit", 99, 1), RiskRuleMaxOpenOrders)
+	if err := g.Check("btcusdt", "buy", "market", 0, 1); err != nil {
+		t.Fatalf("market orders don't rest: %v", err)
+	}
+
+	// a partial fill keeps the order open, the full fill releases it.
+	g.tradesArrived([]Trade{{Oid: 1, Market: "btcusdt", Side: "buy", Price: "100", Volume: "0.4"}})
+	expectRule(t, g.Check("btcusdt", "buy", "limit", 99, 1), RiskRuleMaxOpenOrders)
+	g.tradesArrived([]Trade{{Oid: 1, Market: "btcusdt", Side: "buy", Price: "100", Volume: "0.6"}})
+	if err := g.Check("btcusdt", "buy", "limit", 99, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	// so does a cancel, only once.
+	g.orderPlaced(WsOrder{Id: 3, Market: "btcusdt", Side: "buy", OrdType: "limit", Price: "99", Volume: "1"})
+	expectRule(t, g.Check("btcusdt", "buy", "limit", 99, 1), RiskRuleMaxOpenOrders)
+	g.orderCanceled(WsOrder{Id: 3, Market: "btcusdt"})
+	g.orderCanceled(WsOrder{Id: 3, Market: "btcusdt"})
+	g.orderCanceled(WsOrder{Id: 1, Market: "btcusdt"})
+	g.orderPlaced(WsOrder{Id: 4, Market: "btcusdt", Side: "buy", OrdType: "limit", Price: "99", Volume: "1"})
+	expectRule(t, g.Check("btcusdt", "buy", "limit", 99, 1), RiskRuleMaxOpenOrders)
+}
+
+func TestRiskBalance(t *testing.T) {
+	g := newTestRiskGate(RiskConfig{CheckBalance: true})
+	expectRule(t, g.Check("btcusdt", "buy", "limit", 100, 11), RiskRuleBalance)
+	expectRule(t, g.Check("btcusdt", "sell", "limit", 100, 11), RiskRuleBalance)
+
+	// 1000 usdt reserved at the limit price of 100.
+	g.orderPlaced(WsOrder{Id: 1, Market: "btcusdt", Side: "buy", OrdType: "limit", Price: "100", Volume: "10"})
+	expectRule(t, g.Check("btcusdt", "buy", "limit", 100, 0.1), RiskRuleBalance)
+
+	// 4 fill at 90 with the fee in base, 6 left reserved.
+	g.tradesArrived([]Trade{{Oid: 1, Market: "btcusdt", Side: "buy", Price: "90", Volume: "4", Fee: "0.004", FeeCurrency: "btc"}})
+	balances := g.ReadBalances()
+	if usdt := balances["usdt"]; usdt.Avaliable != 40 || usdt.Locked != 600 {
+		t.Fatalf("usdt %+v", usdt)
+	}
+	if btc := balances["btc"]; btc.Avaliable != 13.996 {
+		t.Fatalf("btc %+v", btc)
+	}
+
+	// canceling gives the rest of the reservation back.
+	g.orderCanceled(WsOrder{Id: 1, Market: "btcusdt"})
+	if usdt := g.ReadBalances()["usdt"]; usdt.Avaliable != 640 || usdt.Locked != 0 {
+		t.Fatalf("usdt %+v", usdt)
+	}
+
+	// a sell filled before the placement returned only reserves what is left.
+	g.tradesArrived([]Trade{{Oid: 2, Market: "btcusdt", Side: "sell", Price: "110", Volume: "1", Fee: "0.11", FeeCurrency: "usdt"}})
+	g.orderPlaced(WsOrder{Id: 2, Market: "btcusdt", Side: "sell", OrdType: "limit", Price: "105", Volume: "3"})
+	balances = g.ReadBalances()
+	if usdt := balances["usdt"]; usdt.Avaliable != 749.89 {
+		t.Fatalf("usdt %+v", usdt)
+	}
+	if btc := balances["btc"]; btc.Avaliable != 10.996 || btc.Locked != 2 {
+		t.Fatalf("btc %+v", btc)
+	}
+}
+
+// test concurrent admissions reserve atomically, and a failed placement gives its reservation back
+func TestRiskAdmit(t *testing.T) {
+	g := newTestRiskGate(RiskConfig{CheckBalance: true})
+
+	var wg sync.WaitGroup
+	tickets := make(chan *riskTicket, 8)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// 600 usdt each out of 1000.
+			if ticket, err := g.admit("btcusdt", "buy", "limit", 100, 6, true); err == nil {
+				tickets <- ticket
+			}
+		}()
+	}
+	wg.Wait()
+	close(tickets)
+	if len(tickets) != 1 {
+		t.Fatalf("%d admitted", len(tickets))
+	}
+	ticket := <-tickets
+	if usdt := g.ReadBalances()["usdt"]; usdt.Avaliable != 400 || usdt.Locked != 600 {
+		t.Fatalf("usdt %+v", usdt)
+	}
+
+	ticket.release()
+	ticket.release()
+	if usdt := g.ReadBalances()["usdt"]; usdt.Avaliable != 1000 || usdt.Locked != 0 {
+		t.Fatalf("usdt after the release %+v", usdt)
+	}
+
+	// the placed order takes over the reservation of the admission.
+	ticket, err := g.admit("btcusdt", "buy", "limit", 100, 6, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ticket.placed(WsOrder{Id: 1, Market: "btcusdt", Side: "buy", OrdType: "limit", Price: "100", Volume: "6"})
+	if usdt := g.ReadBalances()["usdt"]; usdt.Avaliable != 400 || usdt.Locked != 600 {
+		t.Fatalf("usdt after the placement %+v", usdt)
+	}
+	g.orderCanceled(WsOrder{Id: 1, Market: "btcusdt"})
+	if usdt := g.ReadBalances()["usdt"]; usdt.Avaliable != 1000 || usdt.Locked != 0 {
+		t.Fatalf("usdt after the cancel %+v", usdt)
+	}
+
+	// a dry run reserves nothing.
+	if err := g.Check("btcusdt", "buy", "limit", 100, 6); err != nil {
+		t.Fatal(err)
+	}
+	if usdt := g.ReadBalances()["usdt"]; usdt.Locked != 0 {
+		t.Fatalf("usdt after a check %+v", usdt)
+	}
+}
@@ -0,0 +1,195 @@
+package max_RESTfulAPI
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"time"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// Signer signs the requests of an api key, both the REST payloads and the websocket auth.
+// Implementations may keep the secret outside of the trading process.
+type Signer interface {
+	AccessKey() string
+	// Sign returns the hex HMAC-SHA256 of payload with the api secret.
+	Sign(payload []byte) (string, error)
+}
+
+// HMACSigner keeps the api secret in memory, the default signer.
+type HMACSigner struct {
+	accessKey string
+	secret    []byte
+}
+
+func NewHMACSigner(accessKey, secret string) *HMACSigner {
+	return &HMACSigner{accessKey: accessKey, secret: []byte(secret)}
+}
+
+func (s *HMACSigner) AccessKey() string {
+	return s.accessKey
+}
+
+func (s *HMACSigner) Sign(payload []byte) (string, error) {
+	h := hmac.New(sha256.New, s.secret)
+	h.Write(payload)
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// UnixSocketSigner asks a local signing daemon for the signatures, the secret never enters the process.
+// One JSON line {"access_key", "payload"} is written per request and one JSON line {"signature", "error"} is read back.
+type UnixSocketSigner struct {
+	Path    string
+	Timeout time.Duration
+
+	accessKey string
+}
+
+type socketSignRequest struct {
+	AccessKey string `json:"access_key"`
+	Payload   string `json:"payload"`
+}
+
+type socketSignResponse struct {
+	Signature string `json:"signature"`
+	Error     string `json:"error"`
+}
+
+func NewUnixSocketSigner(path, accessKey string) *UnixSocketSigner {
+	return &UnixSocketSigner{Path: path, Timeout: 3 * time.Second, accessKey: accessKey}
+}
+
+func (s *UnixSocketSigner) AccessKey() string {
+	return s.accessKey
+}
+
+func (s *UnixSocketSigner) Sign(payload []byte) (string, error) {
+	conn, err := net.DialTimeout("unix", s.Path, s.Timeout)
+	if err != nil {
+		return "", fmt.Errorf("fail to reach signing daemon: %w", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(s.Timeout))
+
+	req, err := json.Marshal(socketSignRequest{AccessKey: s.accessKey, Payload: string(payload)})
+	if err != nil {
+		return "", err
+	}
+	if _, err := conn.Write(append(req, '\n')); err != nil {
+		return "", fmt.Errorf("fail to send to signing daemon: %w", err)
+	}
+
+	line, err := bufio.NewReader(conn).ReadBytes('\n')
+	if err != nil {
+		return "", fmt.Errorf("fail to read from signing daemon: %w", err)
+	}
+	var resp socketSignResponse
+	if err := json.Unmarshal(line, &resp); err != nil {
+		return "", fmt.Errorf("invalid signing daemon response: %w", err)
+	}
+	if resp.Error != "" {
+		return "", errors.New("signing daemon: " + resp.Error)
+	}
+	if resp.Signature == "" {
+		return "", errors.New("signing daemon returned no signature")
+	}
+	return resp.Signature, nil
+}
+
+// keyFile is the layout of an encrypted key file, the secret is sealed by AES-256-GCM
+// with a key derived from the passphrase by PBKDF2-HMAC-SHA256.
+type keyFile struct {
+	AccessKey  string `json:"access_key"`
+	Iterations int    `json:"iterations"`
+	Salt       string `json:"salt"`
+	Nonce      string `json:"nonce"`
+	Ciphertext string `json:"ciphertext"`
+}
+
+const keyFileIterations = 200000
+
+// LoadEncryptedFileSigner decrypts a key file written by WriteEncryptedKeyFile.
+func LoadEncryptedFileSigner(path string, passphrase []byte) (*HMACSigner, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var f keyFile
+	if err := json.Unmarshal(raw, &f); err != nil {
+		return nil, fmt.Errorf("invalid key file: %w", err)
+	}
+	salt, err := base64.StdEncoding.DecodeString(f.Salt)
+	if err != nil {
+		return nil, fmt.Errorf("invalid key file salt: %w", err)
+	}
+	nonce, err := base64.StdEncoding.DecodeString(f.Nonce)
+	if err != nil {
+		return nil, fmt.Errorf("invalid key file nonce: %w", err)
+	}
+	ciphertext, err := base64.StdEncoding.DecodeString(f.Ciphertext)
+	if err != nil {
+		return nil, fmt.Errorf("invalid key file ciphertext: %w", err)
+	}
+
+	gcm, err := keyFileCipher(passphrase, salt, f.Iterations)
+	if err != nil {
+		return nil, err
+	}
+	if len(nonce) != gcm.NonceSize() {
+		return nil, errors.New("invalid key file nonce size")
+	}
+	secret, err := gcm.Open(nil, nonce, ciphertext, []byte(f.AccessKey))
+	if err != nil {
+		return nil, errors.New("fail to decrypt key file, wrong passphrase?")
+	}
+	return &HMACSigner{accessKey: f.AccessKey, secret: secret}, nil
+}
+
+// WriteEncryptedKeyFile seals the api secret into a key file readable only by the owner.
+func WriteEncryptedKeyFile(path, accessKey, secret string, passphrase []byte) error {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return err
+	}
+	gcm, err := keyFileCipher(passphrase, salt, keyFileIterations)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+
+	raw, err := json.Marshal(keyFile{
+		AccessKey:  accessKey,
+		Iterations: keyFileIterations,
+		Salt:       base64.StdEncoding.EncodeToString(salt),
+		Nonce:      base64.StdEncoding.EncodeToString(nonce),
+		Ciphertext: base64.StdEncoding.EncodeToString(gcm.Seal(nil, nonce, []byte(secret), []byte(accessKey))),
+	})
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, raw, 0600)
+}
+
+func keyFileCipher(passphrase, salt []byte, iterations int) (cipher.AEAD, error) {
+	if iterations <= 0 {
+		return nil, errors.New("invalid key file iterations")
+	}
+	block, err := aes.NewCipher(pbkdf2.Key(passphrase, salt, iterations, 32, sha256.New))
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
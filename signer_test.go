@@ -0,0 +1,100 @@
+package max_RESTfulAPI
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/hex"
+	"net"
+	"path/filepath"
+	"testing"
+)
+
+// test an encrypted key file signs like the in-memory secret
+func TestEncryptedFileSigner(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "max.key")
+	if err := WriteEncryptedKeyFile(path, "access", "secret", []byte("passphrase")); err != nil {
+		t.Fatal(err)
+	}
+
+	signer, err := LoadEncryptedFileSigner(path, []byte("passphrase"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if signer.AccessKey() != "access" {
+		t.Fatalf("access key %s", signer.AccessKey())
+	}
+
+	// the PBKDF2-HMAC-SHA256 vector of RFC 7914, key files written before keep decrypting.
+	aead, err := keyFileCipher([]byte("password"), []byte("salt"), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, _ := hex.DecodeString("120fb6cffcf8b32c43e7225256c4f837a86548c92ccc35480805987cb70be17b")
+	block, _ := aes.NewCipher(key)
+	gcm, _ := cipher.NewGCM(block)
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := aead.Open(nil, nonce, gcm.Seal(nil, nonce, []byte("secret"), nil), nil); err != nil {
+		t.Fatalf("derived key changed: %v", err)
+	}
+
+	sig, _ := NewHMACSigner("access", "secret").Sign([]byte("payload"))
+	got, err := signer.Sign([]byte("payload"))
+	if err != nil || got != sig {
+		t.Fatalf("signature %s, want %s, err %v", got, sig, err)
+	}
+
+	if _, err := LoadEncryptedFileSigner(path, []byte("wrong")); err == nil {
+		t.Fatal("decrypted with a wrong passphrase")
+	}
+}
+
+// test the unix socket signer round-trips through a signing daemon
+func TestUnixSocketSigner(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sign.sock")
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	daemon := NewHMACSigner("access", "secret")
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			line, err := bufio.NewReader(conn).ReadBytes('\n')
+			if err != nil {
+				t.Error(err)
+				conn.Close()
+				continue
+			}
+			var req socketSignRequest
+			var resp socketSignResponse
+			if err := json.Unmarshal(line, &req); err != nil {
+				resp.Error = err.Error()
+			} else if req.AccessKey != daemon.AccessKey() {
+				resp.Error = "unknown access key " + req.AccessKey
+			} else {
+				resp.Signature, _ = daemon.Sign([]byte(req.Payload))
+			}
+			raw, _ := json.Marshal(resp)
+			conn.Write(append(raw, '\n'))
+			conn.Close()
+		}
+	}()
+
+	want, _ := daemon.Sign([]byte("payload"))
+	got, err := NewUnixSocketSigner(path, "access").Sign([]byte("payload"))
+	if err != nil || got != want {
+		t.Fatalf("signature %s, want %s, err %v", got, want, err)
+	}
+
+	if _, err := NewUnixSocketSigner(path, "other").Sign([]byte("payload")); err == nil || err.Error() != "signing daemon: unknown access key other" {
+		t.Fatalf("err %v", err)
+	}
+	if _, err := NewUnixSocketSigner(filepath.Join(t.TempDir(), "none.sock"), "access").Sign([]byte("payload")); err == nil {
+		t.Fatal("signed without a daemon")
+	}
+}
@@ -4,14 +4,14 @@ import (
 	"context"
 	"github.com/sirupsen/logrus"
 	"sync"
+	"time"
 
 	"github.com/gorilla/websocket"
 	"github.com/shopspring/decimal"
 )
 
 type MaxClient struct {
-	apiKey    string
-	apiSecret string
+	signer Signer
 
 	logger        *logrus.Logger
 	cancelFunc    *context.CancelFunc
@@ -26,6 +26,24 @@ type MaxClient struct {
 		sync.RWMutex
 	}
 
+	// pre-trade risk checks
+	RiskBranch struct {
+		Gate *RiskGate
+		sync.RWMutex
+	}
+
+	// kill switch
+	BreakerBranch struct {
+		Breaker *CircuitBreaker
+		sync.RWMutex
+	}
+
+	// components in the aggregate health report
+	HealthBranch struct {
+		components []HealthReporter
+		sync.RWMutex
+	}
+
 	// exchange information
 	ExchangeInfoBranch struct {
 		ExInfo ExchangeInfo
@@ -35,9 +53,15 @@ type MaxClient struct {
 	// web socket client
 	WsClient struct {
 		OnErr      bool
+		downSince  time.Time
 		onErrMutex sync.RWMutex
-		Conn       *websocket.Conn
-		connMutex  sync.Mutex
+		health     *healthTracker
+
+		engine      *wsEngine
+		engineMutex sync.RWMutex
+
+		// Deprecated: use WsConn. Set while connected, the engine replaces it on every reconnect.
+		Conn *websocket.Conn
 
 		LastUpdatedIdBranch struct {
 			LastUpdatedId decimal.Decimal
@@ -55,6 +79,14 @@ type MaxClient struct {
 		sync.RWMutex
 	}
 
+	// callbacks of OnTradeReport and OnTradeSnapshot
+	TradeListenersBranch struct {
+		listeners         []tradeListener
+		snapshotListeners []snapshotListener
+		nextId            int64
+		sync.RWMutex
+	}
+
 	// api client
 	ApiClient *APIClient
 
@@ -69,6 +101,12 @@ type MaxClient struct {
 		Markets []Market
 		sync.RWMutex
 	}
+
+	CurrenciesBranch struct {
+		Currencies []Currency
+		byId       map[string]Currency
+		sync.RWMutex
+	}
 }
 
 type ExchangeInfo struct {
@@ -0,0 +1,326 @@
+package max_RESTfulAPI
+
+import (
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// TapeStats are the rolling statistics of the public prints within one window.
+type TapeStats struct {
+	Market string
+	Window time.Duration
+	Time   time.Time
+
+	VWAP       decimal.Decimal
+	Volume     decimal.Decimal
+	BuyVolume  decimal.Decimal
+	SellVolume decimal.Decimal
+	Count      int
+	// trades per second over the window.
+	Rate         float64
+	LargestPrint PublicTrade
+	// square root of the summed squared log returns between consecutive prints, not annualized.
+	RealizedVol float64
+}
+
+// Imbalance is (buy - sell) / total aggressor volume, within [-1, 1].
+func (s TapeStats) Imbalance() decimal.Decimal {
+	total := s.BuyVolume.Add(s.SellVolume)
+	if total.IsZero() {
+		return decimal.Zero
+	}
+	return s.BuyVolume.Sub(s.SellVolume).Div(total)
+}
+
+// TapeAnalytics keeps rolling-window statistics of the public trade stream of one market.
+// Every window of Windows keeps running sums, updated as trades enter and leave it.
+type TapeAnalytics struct {
+	Market  string
+	Windows []time.Duration
+	// called after every trade with the stats of every window.
+	OnUpdate func([]TapeStats)
+
+	tradesBranch struct {
+		trades []PublicTrade
+		// sequence number of trades[0].
+		base    int
+		windows map[time.Duration]*tapeWindow
+		sync.RWMutex
+	}
+}
+
+// tapeWindow holds the running sums of the trades in [start, end) of one window.
+type tapeWindow struct {
+	window     time.Duration
+	start, end int
+	// time the window was last moved to, older trades have left it.
+	until time.Time
+
+	volume, notional, buyVolume, sellVolume decimal.Decimal
+	sumSquares                              float64
+	// sequence numbers of decreasing volumes, the largest print first.
+	largest []int
+}
+
+func NewTapeAnalytics(market string, windows []time.Duration, onUpdate func([]TapeStats)) *TapeAnalytics {
+	return &TapeAnalytics{
+		Market:   strings.ToLower(market),
+		Windows:  windows,
+		OnUpdate: onUpdate,
+	}
+}
+
+// Attach feeds the analytics from a trade stream.
+func (a *TapeAnalytics) Attach(stream *TradeStreamBranch) {
+	stream.OnTrade(a.AddTrade)
+}
+
+func (a *TapeAnalytics) AddTrade(trade PublicTrade) {
+	if trade.Market != a.Market {
+		return
+	}
+
+	a.tradesBranch.Lock()
+	a.tradesBranch.trades = append(a.tradesBranch.trades, trade)
+	for _, w := range a.windows() {
+		for w.end < a.tradesBranch.base+len(a.tradesBranch.trades) {
+			a.enter(w)
+		}
+		a.advance(w, trade.Time)
+	}
+	a.prune()
+	a.tradesBranch.Unlock()
+
+	if a.OnUpdate != nil {
+		a.OnUpdate(a.SnapshotAt(trade.Time))
+	}
+}
+
+// windows returns the running window of every configured window, seeding the new ones from the buffer.
+func (a *TapeAnalytics) windows() []*tapeWindow {
+	if a.tradesBranch.windows == nil {
+		a.tradesBranch.windows = make(map[time.Duration]*tapeWindow)
+	}
+	windows := make([]*tapeWindow, 0, len(a.Windows))
+	for _, window := range a.Windows {
+		w, ok := a.tradesBranch.windows[window]
+		if !ok {
+			base := a.tradesBranch.base
+			w = &tapeWindow{window: window, start: base, end: base}
+			w.reset()
+			a.tradesBranch.windows[window] = w
+		}
+		windows = append(windows, w)
+	}
+	// forget the windows taken out of Windows, they would hold the buffer.
+	for window, w := range a.tradesBranch.windows {
+		if !containsWindow(windows, w) {
+			delete(a.tradesBranch.windows, window)
+		}
+	}
+	return windows
+}
+
+func containsWindow(windows []*tapeWindow, w *tapeWindow) bool {
+	for _, v := range windows {
+		if v == w {
+			return true
+		}
+	}
+	return false
+}
+
+func (w *tapeWindow) reset() {
+	w.volume = decimal.Zero
+	w.notional = decimal.Zero
+	w.buyVolume = decimal.Zero
+	w.sellVolume = decimal.Zero
+	w.sumSquares = 0
+	w.largest = w.largest[:0]
+}
+
+func (a *TapeAnalytics) trade(seq int) PublicTrade {
+	return a.tradesBranch.trades[seq-a.tradesBranch.base]
+}
+
+// logReturn is the squared log return between two consecutive prints, zero without a price.
+func logReturn(prev, next PublicTrade) float64 {
+	p, _ := prev.Price.Float64()
+	q, _ := next.Price.Float64()
+	if p <= 0 || q <= 0 {
+		return 0
+	}
+	r := math.Log(q / p)
+	return r * r
+}
+
+// enter adds the next trade of the buffer to the window.
+func (a *TapeAnalytics) enter(w *tapeWindow) {
+	trade := a.trade(w.end)
+	w.volume = w.volume.Add(trade.Volume)
+	w.notional = w.notional.Add(trade.Price.Mul(trade.Volume))
+	switch trade.Side {
+	case TakerSideBuy:
+		w.buyVolume = w.buyVolume.Add(trade.Volume)
+	case TakerSideSell:
+		w.sellVolume = w.sellVolume.Add(trade.Volume)
+	}
+	if w.end > w.start {
+		w.sumSquares += logReturn(a.trade(w.end-1), trade)
+	}
+	// keep the earlier print on a tie.
+	for len(w.largest) > 0 && a.trade(w.largest[len(w.largest)-1]).Volume.LessThan(trade.Volume) {
+		w.largest = w.largest[:len(w.largest)-1]
+	}
+	w.largest = append(w.largest, w.end)
+	w.end++
+}
+
+// advance moves the window to end at $now, taking out the trades older than the window.
+func (a *TapeAnalytics) advance(w *tapeWindow, now time.Time) {
+	if now.After(w.until) {
+		w.until = now
+	}
+	for w.start < w.end && w.until.Sub(a.trade(w.start).Time) > w.window {
+		trade := a.trade(w.start)
+		w.volume = w.volume.Sub(trade.Volume)
+		w.notional = w.notional.Sub(trade.Price.Mul(trade.Volume))
+		switch trade.Side {
+		case TakerSideBuy:
+			w.buyVolume = w.buyVolume.Sub(trade.Volume)
+		case TakerSideSell:
+			w.sellVolume = w.sellVolume.Sub(trade.Volume)
+		}
+		if w.start+1 < w.end {
+			w.sumSquares -= logReturn(trade, a.trade(w.start+1))
+		}
+		if len(w.largest) > 0 && w.largest[0] == w.start {
+			w.largest = w.largest[1:]
+		}
+		w.start++
+	}
+	if w.end-w.start < 2 {
+		// drop the rounding left by the subtractions.
+		w.sumSquares = 0
+	}
+	if w.start == w.end {
+		w.reset()
+	}
+}
+
+// prune drops the trades every window has left.
+func (a *TapeAnalytics) prune() {
+	first := a.tradesBranch.base + len(a.tradesBranch.trades)
+	for _, w := range a.tradesBranch.windows {
+		if w.start < first {
+			first = w.start
+		}
+	}
+	if i := first - a.tradesBranch.base; i > 0 {
+		// the dropped trades are released when append grows the slice again.
+		a.tradesBranch.trades = a.tradesBranch.trades[i:]
+		a.tradesBranch.base = first
+	}
+}
+
+// Stats returns the statistics of one window ending now.
+func (a *TapeAnalytics) Stats(window time.Duration) TapeStats {
+	return a.StatsAt(window, time.Now())
+}
+
+// Snapshot returns the statistics of every window ending now.
+func (a *TapeAnalytics) Snapshot() []TapeStats {
+	return a.SnapshotAt(time.Now())
+}
+
+func (a *TapeAnalytics) SnapshotAt(now time.Time) []TapeStats {
+	stats := make([]TapeStats, 0, len(a.Windows))
+	for _, w := range a.Windows {
+		stats = append(stats, a.StatsAt(w, now))
+	}
+	return stats
+}
+
+// StatsAt returns the statistics of the window ending at $now.
+// A configured window ending at or after its last move is read from its running sums,
+// an other window or an earlier end is computed from the buffered trades.
+func (a *TapeAnalytics) StatsAt(window time.Duration, now time.Time) TapeStats {
+	stats := TapeStats{
+		Market:     a.Market,
+		Window:     window,
+		Time:       now,
+		VWAP:       decimal.Zero,
+		Volume:     decimal.Zero,
+		BuyVolume:  decimal.Zero,
+		SellVolume: decimal.Zero,
+	}
+
+	a.tradesBranch.Lock()
+	defer a.tradesBranch.Unlock()
+
+	w, ok := a.tradesBranch.windows[window]
+	if !ok || now.Before(w.until) {
+		a.scanStats(&stats)
+		return stats
+	}
+	a.advance(w, now)
+
+	stats.Count = w.end - w.start
+	stats.Volume = w.volume
+	stats.BuyVolume = w.buyVolume
+	stats.SellVolume = w.sellVolume
+	if len(w.largest) > 0 {
+		stats.LargestPrint = a.trade(w.largest[0])
+	}
+	if stats.Volume.IsPositive() {
+		stats.VWAP = w.notional.Div(stats.Volume)
+	}
+	if window > 0 {
+		stats.Rate = float64(stats.Count) / window.Seconds()
+	}
+	stats.RealizedVol = math.Sqrt(math.Max(w.sumSquares, 0))
+	return stats
+}
+
+// scanStats computes the stats from every buffered trade within the window.
+func (a *TapeAnalytics) scanStats(stats *TapeStats) {
+	notional := decimal.Zero
+	var sumSquares float64
+	var prev float64
+	for _, trade := range a.tradesBranch.trades {
+		if stats.Time.Sub(trade.Time) > stats.Window || trade.Time.After(stats.Time) {
+			continue
+		}
+		stats.Count++
+		stats.Volume = stats.Volume.Add(trade.Volume)
+		notional = notional.Add(trade.Price.Mul(trade.Volume))
+		switch trade.Side {
+		case TakerSideBuy:
+			stats.BuyVolume = stats.BuyVolume.Add(trade.Volume)
+		case TakerSideSell:
+			stats.SellVolume = stats.SellVolume.Add(trade.Volume)
+		}
+		if trade.Volume.GreaterThan(stats.LargestPrint.Volume) {
+			stats.LargestPrint = trade
+		}
+
+		price, _ := trade.Price.Float64()
+		if prev > 0 && price > 0 {
+			r := math.Log(price / prev)
+			sumSquares += r * r
+		}
+		prev = price
+	}
+
+	if stats.Volume.IsPositive() {
+		stats.VWAP = notional.Div(stats.Volume)
+	}
+	if stats.Window > 0 {
+		stats.Rate = float64(stats.Count) / stats.Window.Seconds()
+	}
+	stats.RealizedVol = math.Sqrt(sumSquares)
+}
@@ -0,0 +1,103 @@
+package max_RESTfulAPI
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+func tapeTrade(id int64, at time.Time, price, volume int64, side TakerSide) PublicTrade {
+	return PublicTrade{ID: id, Market: "btcusdt", Price: decimal.NewFromInt(price), Volume: decimal.NewFromInt(volume), Time: at, Side: side}
+}
+
+// test the rolling vwap and imbalance of every window and the eviction of old trades
+func TestTapeAnalytics(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	var updates [][]TapeStats
+	a := NewTapeAnalytics("BTCUSDT", []time.Duration{time.Second, time.Minute}, func(stats []TapeStats) {
+		updates = append(updates, stats)
+	})
+
+	a.AddTrade(tapeTrade(1, start, 100, 2, TakerSideBuy))
+	a.AddTrade(tapeTrade(2, start.Add(500*time.Millisecond), 110, 3, TakerSideSell))
+	a.AddTrade(PublicTrade{ID: 3, Market: "ethusdt", Price: decimal.NewFromInt(1), Volume: decimal.NewFromInt(1), Time: start})
+	if len(updates) != 2 {
+		t.Fatalf("%d updates", len(updates))
+	}
+	second := updates[1][0]
+	// (100*2 + 110*3) / 5
+	if !second.VWAP.Equal(decimal.NewFromInt(106)) || second.Count != 2 || second.LargestPrint.ID != 2 {
+		t.Fatalf("stats %+v", second)
+	}
+	// (2 - 3) / 5
+	if !second.Imbalance().Equal(decimal.RequireFromString("-0.2")) {
+		t.Fatalf("imbalance %s", second.Imbalance())
+	}
+	if want := math.Abs(math.Log(110.0 / 100)); math.Abs(second.RealizedVol-want) > 1e-12 {
+		t.Fatalf("realized vol %v, want %v", second.RealizedVol, want)
+	}
+
+	// trade 1 leaves the one second window only.
+	a.AddTrade(tapeTrade(4, start.Add(1200*time.Millisecond), 120, 1, TakerSideBuy))
+	stats := updates[2]
+	if s := stats[0]; s.Count != 2 || !s.Volume.Equal(decimal.NewFromInt(4)) || !s.BuyVolume.Equal(decimal.NewFromInt(1)) || !s.VWAP.Equal(decimal.RequireFromString("112.5")) {
+		t.Fatalf("one second %+v", s)
+	}
+	if s := stats[1]; s.Count != 3 || !s.VWAP.Equal(decimal.RequireFromString("108.3333333333333333")) || !s.Imbalance().IsZero() {
+		t.Fatalf("one minute %+v", s)
+	}
+
+	// every trade left the one second window, a later end keeps moving it.
+	if s := a.StatsAt(time.Second, start.Add(3*time.Second)); s.Count != 0 || !s.VWAP.IsZero() || s.LargestPrint.ID != 0 || s.RealizedVol != 0 {
+		t.Fatalf("empty window %+v", s)
+	}
+	// an earlier end and an unconfigured window are computed from the buffer.
+	if s := a.StatsAt(time.Second, start.Add(1100*time.Millisecond)); s.Count != 1 || !s.VWAP.Equal(decimal.NewFromInt(110)) {
+		t.Fatalf("earlier end %+v", s)
+	}
+	if s := a.StatsAt(300*time.Millisecond, start.Add(1200*time.Millisecond)); s.Count != 1 || s.LargestPrint.ID != 4 {
+		t.Fatalf("unconfigured window %+v", s)
+	}
+
+	// the buffer only keeps what the longest window holds.
+	a.AddTrade(tapeTrade(5, start.Add(2*time.Minute), 100, 1, TakerSideSell))
+	if n := len(a.tradesBranch.trades); n != 1 {
+		t.Fatalf("%d trades buffered", n)
+	}
+	if s := a.StatsAt(time.Minute, start.Add(2*time.Minute)); s.Count != 1 || s.RealizedVol != 0 || !s.Imbalance().Equal(decimal.NewFromInt(-1)) {
+		t.Fatalf("one minute %+v", s)
+	}
+}
+
+// test the running sums match a full recompute of the window
+func TestTapeAnalyticsRunningSums(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	a := NewTapeAnalytics("btcusdt", []time.Duration{5 * time.Second}, nil)
+	for i := int64(0); i < 200; i++ {
+		side := TakerSideBuy
+		if i%3 == 0 {
+			side = TakerSideSell
+		}
+		at := start.Add(time.Duration(i*i%700) * time.Millisecond * 10)
+		if i > 0 {
+			// keep the prints in order.
+			if prev := a.tradesBranch.trades[len(a.tradesBranch.trades)-1].Time; at.Before(prev) {
+				at = prev.Add(300 * time.Millisecond)
+			}
+		}
+		a.AddTrade(tapeTrade(i, at, 100+i%7, 1+i%5, side))
+
+		now := at.Add(time.Duration(i%4) * time.Second)
+		want := TapeStats{Market: a.Market, Window: 5 * time.Second, Time: now, VWAP: decimal.Zero, Volume: decimal.Zero, BuyVolume: decimal.Zero, SellVolume: decimal.Zero}
+		a.tradesBranch.Lock()
+		a.scanStats(&want)
+		a.tradesBranch.Unlock()
+		got := a.StatsAt(5*time.Second, now)
+		if got.Count != want.Count || !got.VWAP.Equal(want.VWAP) || !got.BuyVolume.Equal(want.BuyVolume) ||
+			!got.SellVolume.Equal(want.SellVolume) || got.LargestPrint.ID != want.LargestPrint.ID || math.Abs(got.RealizedVol-want.RealizedVol) > 1e-9 {
+			t.Fatalf("trade %d: got %+v, want %+v", i, got, want)
+		}
+	}
+}
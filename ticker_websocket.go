@@ -0,0 +1,170 @@
+package max_RESTfulAPI
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/sirupsen/logrus"
+)
+
+// TickerStreamBranch keeps a live ticker cache of markets from the ticker channel.
+type TickerStreamBranch struct {
+	cancel  context.CancelFunc
+	engine  *wsEngine
+	Markets []string
+
+	tickersBranch struct {
+		tickers map[string]Ticker
+		sync.RWMutex
+	}
+
+	logger *logrus.Logger
+	health *healthTracker
+}
+
+type tickerMsg struct {
+	Channel string `json:"c"`
+	Event   string `json:"e"`
+	Market  string `json:"M"`
+	Ticker  struct {
+		Open   decimal.Decimal `json:"O"`
+		High   decimal.Decimal `json:"H"`
+		Low    decimal.Decimal `json:"L"`
+		Close  decimal.Decimal `json:"C"`
+		Volume decimal.Decimal `json:"v"`
+		// volume in quote, declared so it doesn't land in Volume, json keys match case-insensitively.
+		QuoteVolume decimal.Decimal `json:"V"`
+	} `json:"tk"`
+	Timestamp int64 `json:"T"`
+}
+
+// TickerStream keeps the tickers of every market of the client.
+func (Mc *MaxClient) TickerStream(ctx context.Context) *TickerStreamBranch {
+	markets := Mc.ReadMarkets()
+	symbols := make([]string, 0, len(markets))
+	for _, m := range markets {
+		symbols = append(symbols, m.Id)
+	}
+	o := SpotTickerStream(ctx, symbols, Mc.logger)
+	if err := o.Seed(ctx, Mc.ApiClient); err != nil {
+		Mc.logger.Warn("fail to seed tickers: ", err)
+	}
+	return o
+}
+
+func SpotTickerStream(ctx context.Context, symbols []string, logger *logrus.Logger) *TickerStreamBranch {
+	var o TickerStreamBranch
+	for _, symbol := range symbols {
+		o.Markets = append(o.Markets, NormalizeMarket(symbol))
+	}
+	o.tickersBranch.tickers = make(map[string]Ticker)
+	o.logger = logger
+	o.health = newHealthTracker("ticker stream")
+
+	ctx, o.cancel = context.WithCancel(ctx)
+	o.engine = newWsEngine("ticker stream", wsConfig{}, logger, o.health)
+	o.engine.subscribe = func() ([][]byte, error) {
+		var args []map[string]interface{}
+		for _, market := range o.Markets {
+			args = append(args, map[string]interface{}{"channel": "ticker", "market": market})
+		}
+		subMsg, err := json.Marshal(map[string]interface{}{"action": "sub", "subscriptions": args})
+		if err != nil {
+			return nil, err
+		}
+		return [][]byte{subMsg}, nil
+	}
+	o.engine.handle = o.handleTickerSocketMsg
+
+	go o.engine.run(ctx)
+	return &o
+}
+
+// Seed fills the cache from the REST tickers, which also carry best bid and ask.
+func (o *TickerStreamBranch) Seed(ctx context.Context, api *APIClient) error {
+	tickers, _, err := api.PublicApi.GetApiV2Tickers(ctx)
+	if err != nil {
+		return err
+	}
+	o.tickersBranch.Lock()
+	defer o.tickersBranch.Unlock()
+	for market, ticker := range tickers {
+		if _, ok := o.tickersBranch.tickers[market]; !ok {
+			o.tickersBranch.tickers[market] = ticker
+		}
+	}
+	return nil
+}
+
+func (o *TickerStreamBranch) handleTickerSocketMsg(msg []byte) error {
+	var t tickerMsg
+	if err := json.Unmarshal(msg, &t); err != nil {
+		return errors.New("fail to unmarshal message")
+	}
+
+	switch t.Event {
+	case "subscribed":
+		o.health.connected()
+	case "snapshot", "update":
+		if t.Channel != "ticker" {
+			return errors.New("wrong channel")
+		}
+		o.tickersBranch.Lock()
+		market := NormalizeMarket(t.Market)
+		ticker := o.tickersBranch.tickers[market]
+		// the channel has no best bid and ask, drop the seeded ones once they go stale.
+		if t.Timestamp/1000-ticker.At > int64(time.Minute/time.Second) {
+			ticker.Buy, ticker.Sell = decimal.Zero, decimal.Zero
+		}
+		ticker.At = t.Timestamp / 1000
+		ticker.Open = t.Ticker.Open
+		ticker.High = t.Ticker.High
+		ticker.Low = t.Ticker.Low
+		ticker.Last = t.Ticker.Close
+		ticker.Vol = t.Ticker.Volume
+		o.tickersBranch.tickers[market] = ticker
+		o.tickersBranch.Unlock()
+	}
+	return nil
+}
+
+// Get returns the cached ticker of a market.
+func (o *TickerStreamBranch) Get(market string) (Ticker, bool) {
+	o.tickersBranch.RLock()
+	defer o.tickersBranch.RUnlock()
+	ticker, ok := o.tickersBranch.tickers[NormalizeMarket(market)]
+	return ticker, ok
+}
+
+// All returns a copy of the whole cache.
+func (o *TickerStreamBranch) All() map[string]Ticker {
+	o.tickersBranch.RLock()
+	defer o.tickersBranch.RUnlock()
+	tickers := make(map[string]Ticker, len(o.tickersBranch.tickers))
+	for market, ticker := range o.tickersBranch.tickers {
+		tickers[market] = ticker
+	}
+	return tickers
+}
+
+// MidPrice makes the cache usable as a PriceSource.
+func (o *TickerStreamBranch) MidPrice(market string) (decimal.Decimal, bool) {
+	ticker, ok := o.Get(market)
+	if !ok {
+		return decimal.Zero, false
+	}
+	mid := tickerMid(ticker)
+	return mid, mid.IsPositive()
+}
+
+func (o *TickerStreamBranch) Close() {
+	o.cancel()
+}
+
+// Health of the ticker websocket.
+func (o *TickerStreamBranch) Health() Health {
+	return o.health.snapshot()
+}
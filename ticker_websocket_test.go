@@ -0,0 +1,85 @@
+package max_RESTfulAPI
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/sirupsen/logrus"
+)
+
+func tickerFrame(event, market string, close string, ms int64) []byte {
+	return []byte(fmt.Sprintf(`{"c":"ticker","e":"%s","M":"%s","tk":{"M":"%s","O":"95","H":"105","L":"90","C":"%s","v":"12.5","V":"1250"},"T":%d}`,
+		event, market, market, close, ms))
+}
+
+// test the ticker frames update the cache, keeping the seeded best bid and ask while they are fresh
+func TestTickerStream(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	o := &TickerStreamBranch{Markets: []string{"btcusdt"}, logger: logger, health: newHealthTracker("ticker stream")}
+	o.tickersBranch.tickers = make(map[string]Ticker)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"btcusdt":{"at":1700000000,"buy":"99","sell":"101","last":"100"},"ethusdt":{"at":1700000000,"last":"5"}}`))
+	}))
+	defer server.Close()
+	cfg := NewConfiguration()
+	cfg.BasePath = server.URL
+	if err := o.Seed(context.Background(), NewAPIClient(cfg)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := o.handleTickerSocketMsg([]byte(`{"c":"ticker","e":"subscribed","s":[{"channel":"ticker","market":"btcusdt"}],"i":"","T":1700000000000}`)); err != nil {
+		t.Fatal(err)
+	}
+	if h := o.Health(); h.State != ConnStateConnected {
+		t.Fatalf("health %+v", h)
+	}
+
+	if err := o.handleTickerSocketMsg(tickerFrame("snapshot", "btcusdt", "100.5", 1700000030000)); err != nil {
+		t.Fatal(err)
+	}
+	ticker, ok := o.Get("BTCUSDT")
+	if !ok || ticker.At != 1700000030 || !ticker.Last.Equal(decimal.RequireFromString("100.5")) || !ticker.Open.Equal(decimal.NewFromInt(95)) ||
+		!ticker.High.Equal(decimal.NewFromInt(105)) || !ticker.Low.Equal(decimal.NewFromInt(90)) || !ticker.Vol.Equal(decimal.RequireFromString("12.5")) {
+		t.Fatalf("ticker %+v", ticker)
+	}
+	// the seeded bid and ask are 30 seconds old, still used for the mid.
+	if mid, ok := o.MidPrice("btcusdt"); !ok || !mid.Equal(decimal.NewFromInt(100)) {
+		t.Fatalf("mid %s", mid)
+	}
+
+	// more than a minute after the last ticker they are dropped, the mid falls back to the last price.
+	if err := o.handleTickerSocketMsg(tickerFrame("update", "btcusdt", "102", 1700000100000)); err != nil {
+		t.Fatal(err)
+	}
+	if ticker, _ := o.Get("btcusdt"); !ticker.Buy.IsZero() || !ticker.Sell.IsZero() {
+		t.Fatalf("stale bid and ask kept %+v", ticker)
+	}
+	if mid, ok := o.MidPrice("btcusdt"); !ok || !mid.Equal(decimal.NewFromInt(102)) {
+		t.Fatalf("mid %s", mid)
+	}
+
+	// a market seeded only from the REST tickers.
+	if mid, ok := o.MidPrice("ethusdt"); !ok || !mid.Equal(decimal.NewFromInt(5)) {
+		t.Fatalf("eth mid %s", mid)
+	}
+	if _, ok := o.MidPrice("dogeusdt"); ok {
+		t.Fatal("mid of an unknown market")
+	}
+	if len(o.All()) != 2 {
+		t.Fatalf("tickers %v", o.All())
+	}
+
+	if err := o.handleTickerSocketMsg([]byte(`{"c":"book","e":"update","M":"btcusdt"}`)); err == nil {
+		t.Fatal("accepted a frame of another channel")
+	}
+	if err := o.handleTickerSocketMsg([]byte(`{"c":"ticker",`)); err == nil {
+		t.Fatal("accepted a truncated frame")
+	}
+}
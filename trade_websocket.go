@@ -6,44 +6,148 @@ import (
 	"fmt"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
-	"github.com/gorilla/websocket"
+	"github.com/shopspring/decimal"
 	"github.com/sirupsen/logrus"
 )
 
+// TakerSide is the aggressor side of a public trade.
+type TakerSide string
+
+const (
+	TakerSideUnknown TakerSide = ""
+	TakerSideBuy     TakerSide = "buy"
+	TakerSideSell    TakerSide = "sell"
+)
+
+// takerSideFromTrend maps the `tr` field of MAX trades, "up" means the trade lifted the ask.
+func takerSideFromTrend(tr string) TakerSide {
+	switch tr {
+	case "up":
+		return TakerSideBuy
+	case "down":
+		return TakerSideSell
+	default:
+		return TakerSideUnknown
+	}
+}
+
+// PublicTrade is one print of the public trade channel.
+type PublicTrade struct {
+	ID     int64
+	Market string
+	Price  decimal.Decimal
+	Volume decimal.Decimal
+	Time   time.Time
+	Side   TakerSide
+}
+
+// TradeBackpressure decides what happens when a bounded trade buffer is full.
+type TradeBackpressure int
+
+const (
+	// stop reading the socket until GetTrades makes room, blocking past the read timeout reconnects.
+	// No trade is lost, but the stream stalls while nobody calls GetTrades.
+	BackpressureBlock TradeBackpressure = iota
+	// drop the connection, trades missed meanwhile are recovered from the snapshot when they carry ids.
+	BackpressureReconnect
+	// evict the oldest buffered trades, counted by Dropped. Opt-in, trades are lost.
+	BackpressureDropOldest
+)
+
+var errTradeBufferFull = errors.New("trade buffer is full")
+
+type TradeStreamConfig struct {
+	// max buffered trades between GetTrades calls, zero keeps every trade like before.
+	// negative disables the buffer, for streams only read through OnTrade or TradeChan.
+	BufferSize int
+	// only applies to a positive BufferSize.
+	Backpressure TradeBackpressure
+}
+
 // trade stream is build for gratching all trades of market.
 type TradeStreamBranch struct {
-	cancel     *context.CancelFunc
-	ConnBranch struct {
-		conn *websocket.Conn
+	cancel *context.CancelFunc
+	ctx    context.Context
+	engine *wsEngine
+	// first subscribed market.
+	Market  string
+	Markets []string
+	cfg     TradeStreamConfig
+
+	// Deprecated: use OnTrade or GetTrades. Every message is offered without blocking,
+	// so it is lossy when nobody reads it.
+	TradeChan chan TradeData
+
+	tradesBranch struct {
+		Trades []PublicTrade
 		sync.Mutex
 	}
-	onErrBranch struct {
-		onErr bool
-		mutex sync.RWMutex
-	}
-	Market string
+	dropped int64
 
-	TradeChan    chan TradeData
-	tradesBranch struct {
-		Trades []TradeData
+	// last trade id per market, to skip the trades of a snapshot already seen.
+	lastIdBranch struct {
+		ids map[string]int64
 		sync.Mutex
 	}
 
+	listenersBranch struct {
+		listeners []publicTradeListener
+		nextId    int64
+		sync.RWMutex
+	}
+
 	lastUpdatedTimestampBranch struct {
 		timestamp int64
 		mux       sync.RWMutex
 	}
 	logger *logrus.Logger
+	health *healthTracker
+}
+
+// message of the trade channel.
+type tradeMsg struct {
+	Channel   string       `json:"c"`
+	Event     string       `json:"e"`
+	Market    string       `json:"M"`
+	Trades    []tradeEntry `json:"t"`
+	Timestamp int64        `json:"T"`
+}
+
+// TradeData is the raw trade message.
+//
+// Deprecated: use PublicTrade.
+type TradeData struct {
+	Channel string `json:"c"`
+	Event   string `json:"e"`
+	Market  string `json:"M"`
+	Trades  []struct {
+		P  string `json:"p"`
+		V  string `json:"v"`
+		T  int    `json:"T"`
+		Tr string `json:"tr"`
+	} `json:"t"`
+	Timestamp int `json:"T"`
 }
 
-func maxSubscribeTradeMessage(symbol string) ([]byte, error) {
+type tradeEntry struct {
+	Id        int64           `json:"i"`
+	Price     decimal.Decimal `json:"p"`
+	Volume    decimal.Decimal `json:"v"`
+	Timestamp int64           `json:"T"`
+	Trend     string          `json:"tr"`
+}
+
+func maxSubscribeTradeMessage(symbols ...string) ([]byte, error) {
 	var args []map[string]interface{}
-	subscriptions := make(map[string]interface{})
-	subscriptions["channel"] = "trade"
-	subscriptions["market"] = strings.ToLower(symbol)
-	args = append(args, subscriptions)
+	for _, symbol := range symbols {
+		subscriptions := make(map[string]interface{})
+		subscriptions["channel"] = "trade"
+		subscriptions["market"] = strings.ToLower(symbol)
+		args = append(args, subscriptions)
+	}
 
 	param := make(map[string]interface{})
 	param["action"] = "sub"
@@ -55,114 +159,80 @@ func maxSubscribeTradeMessage(symbol string) ([]byte, error) {
 	return req, nil
 }
 
-func (o *TradeStreamBranch) ping(ctx context.Context) {
-	go func() {
-		for {
-			time.Sleep(30 * time.Second)
-			select {
-			case <-ctx.Done():
-				return
-			default:
-				message := []byte("ping")
-				o.ConnBranch.conn.WriteMessage(websocket.TextMessage, message)
-			}
-		}
-	}()
+func SpotTradeStream(symbol string, logger *logrus.Logger) *TradeStreamBranch {
+	return SpotTradeStreams(context.Background(), []string{symbol}, TradeStreamConfig{}, logger)
 }
 
-func SpotTradeStream(symbol string, logger *logrus.Logger) *TradeStreamBranch {
-	var o TradeStreamBranch
-	ctx, cancel := context.WithCancel(context.Background())
+// SpotTradeStreams subscribes the trades of several markets over one connection.
+func SpotTradeStreams(ctx context.Context, symbols []string, cfg TradeStreamConfig, logger *logrus.Logger) *TradeStreamBranch {
+	o := newTradeStreamBranch(symbols, cfg, logger)
+	ctx, cancel := context.WithCancel(ctx)
 	o.cancel = &cancel
-	o.Market = strings.ToLower(symbol)
-	o.TradeChan = make(chan TradeData, 100)
-	o.logger = logger
-	go o.maintain(ctx, symbol)
-	go o.listen(ctx)
+	o.ctx = ctx
 
-	time.Sleep(1 * time.Second)
-	go o.ping(ctx)
-
-	return &o
-}
+	o.engine = newWsEngine("trade stream "+strings.Join(o.Markets, ","), wsConfig{}, logger, o.health)
+	o.engine.subscribe = func() ([][]byte, error) {
+		subMsg, err := maxSubscribeTradeMessage(o.Markets...)
+		if err != nil {
+			return nil, errors.New("fail to construct subscribtion message")
+		}
+		return [][]byte{subMsg}, nil
+	}
+	o.engine.handle = o.handleMaxTradeSocketMsg
 
-func (o *TradeStreamBranch) maintain(ctx context.Context, symbol string) {
-	var url string = "wss://max-stream.maicoin.com/ws"
+	go o.engine.run(ctx)
 
-	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
-	if err != nil {
-		o.logger.Error(err)
-	}
-	//LogInfoToDailyLogFile("Connected:", url)
-	o.ConnBranch.conn = conn
-	o.onErrBranch.mutex.Lock()
-	o.onErrBranch.onErr = false
-	o.onErrBranch.mutex.Unlock()
+	return o
+}
 
-	subMsg, err := maxSubscribeTradeMessage(symbol)
-	if err != nil {
-		o.logger.Error(errors.New("fail to construct subscribtion message"))
+func newTradeStreamBranch(symbols []string, cfg TradeStreamConfig, logger *logrus.Logger) *TradeStreamBranch {
+	var o TradeStreamBranch
+	for _, symbol := range symbols {
+		o.Markets = append(o.Markets, strings.ToLower(symbol))
 	}
-	o.ConnBranch.Lock()
-	err = o.ConnBranch.conn.WriteMessage(websocket.TextMessage, subMsg)
-	o.ConnBranch.Unlock()
-	if err != nil {
-		o.logger.Error(errors.New("fail to subscribe websocket"))
+	if len(o.Markets) > 0 {
+		o.Market = o.Markets[0]
 	}
+	o.cfg = cfg
+	o.ctx = context.Background()
+	o.logger = logger
+	o.health = newHealthTracker("trade stream " + strings.Join(o.Markets, ","))
+	o.lastIdBranch.ids = make(map[string]int64)
+	o.TradeChan = make(chan TradeData, 100)
+	return &o
+}
 
-	NoErr := true
-	for NoErr {
-		select {
-		case <-ctx.Done():
-			o.ConnBranch.Lock()
-			o.ConnBranch.conn.Close()
-			o.ConnBranch.Unlock()
-			return
-		default:
-			o.ConnBranch.Lock()
-			_, msg, err := o.ConnBranch.conn.ReadMessage()
-			o.ConnBranch.Unlock()
-			if err != nil {
-				o.logger.Error("read:", err)
-				o.onErrBranch.mutex.Lock()
-				o.onErrBranch.onErr = true
-				o.onErrBranch.mutex.Unlock()
-			}
-
-			var msgMap map[string]interface{}
-			err = json.Unmarshal(msg, &msgMap)
-			if err != nil {
-				o.logger.Error(err)
-				o.onErrBranch.mutex.Lock()
-				o.onErrBranch.onErr = true
-				o.onErrBranch.mutex.Unlock()
-			}
+// Close stops the trade stream.
+func (o *TradeStreamBranch) Close() {
+	(*o.cancel)()
+}
 
-			errh := o.handleMaxTradeSocketMsg(msg)
-			if errh != nil {
-				o.onErrBranch.mutex.Lock()
-				o.onErrBranch.onErr = true
-				o.onErrBranch.mutex.Unlock()
+// OnTrade registers a callback run for every new trade, in the order received.
+// It runs on the websocket goroutine, so it should return quickly.
+// The returned func unsubscribes it.
+func (o *TradeStreamBranch) OnTrade(fn func(PublicTrade)) (unsubscribe func()) {
+	o.listenersBranch.Lock()
+	defer o.listenersBranch.Unlock()
+	o.listenersBranch.nextId++
+	id := o.listenersBranch.nextId
+	o.listenersBranch.listeners = append(o.listenersBranch.listeners, publicTradeListener{id: id, fn: fn})
+	return func() {
+		o.listenersBranch.Lock()
+		defer o.listenersBranch.Unlock()
+		// copied, tradesArrived may still range over the old slice.
+		kept := make([]publicTradeListener, 0, len(o.listenersBranch.listeners))
+		for _, l := range o.listenersBranch.listeners {
+			if l.id != id {
+				kept = append(kept, l)
 			}
-
-		} // end select
-
-		// if there is something wrong that the WS should be reconnected.
-		if o.onErrBranch.onErr {
-			//message := "max websocket reconnecting"
-			//LogInfoToDailyLogFile(message)
-			NoErr = false
 		}
-		time.Sleep(time.Millisecond)
-	} // end for
-	o.ConnBranch.Lock()
-	o.ConnBranch.conn.Close()
-	o.ConnBranch.Unlock()
-
-	if !o.onErrBranch.onErr {
-		return
+		o.listenersBranch.listeners = kept
 	}
-	o.maintain(ctx, symbol)
+}
+
+type publicTradeListener struct {
+	id int64
+	fn func(PublicTrade)
 }
 
 func (o *TradeStreamBranch) handleMaxTradeSocketMsg(msg []byte) error {
@@ -184,76 +254,185 @@ func (o *TradeStreamBranch) handleMaxTradeSocketMsg(msg []byte) error {
 	switch event {
 	case "subscribed":
 		fmt.Println("websocket subscribed")
-	case "snapshot":
-		//err2 = o.parseOrderbookSnapshotMsg(msgMap)
-	case "update":
-		err2 = o.parseTradeUpdateMsg(msg)
+		o.health.connected()
+	case "snapshot", "update":
+		err2 = o.parseTradeMsg(msg)
 	}
 
 	if err2 != nil {
 		fmt.Println(err2, "err2")
-		return errors.New("fail to parse message")
+		return err2
 	}
 	return nil
 }
 
-type TradeData struct {
-	Channel string `json:"c"`
-	Event   string `json:"e"`
-	Market  string `json:"M"`
-	Trades  []struct {
-		P  string `json:"p"`
-		V  string `json:"v"`
-		T  int    `json:"T"`
-		Tr string `json:"tr"`
-	} `json:"t"`
-	Timestamp int `json:"T"`
-}
-
-func (o *TradeStreamBranch) parseTradeUpdateMsg(msg []byte) error {
-	var tradeData TradeData
-	json.Unmarshal(msg, &tradeData)
+func (o *TradeStreamBranch) parseTradeMsg(msg []byte) error {
+	var tradeData tradeMsg
+	if err := json.Unmarshal(msg, &tradeData); err != nil {
+		return err
+	}
 
 	// extract data
 	if tradeData.Channel != "trade" {
 		return errors.New("wrong channel")
 	}
-	if tradeData.Event != "update" {
-		return errors.New("wrong event")
-	}
-	if tradeData.Market != o.Market {
+	if !o.subscribed(tradeData.Market) {
 		return errors.New("wrong market")
 	}
 
-	o.TradeChan <- tradeData
+	trades := o.newTrades(tradeData)
+	// without trade ids the snapshot can't be told apart from trades already seen.
+	if tradeData.Event == "snapshot" && len(trades) > 0 && trades[0].ID == 0 {
+		trades = nil
+	}
+	if err := o.tradesArrived(trades); err != nil {
+		return err
+	}
+	o.markSeen(tradeData.Market, trades)
+	o.offerTradeData(msg)
 
 	o.lastUpdatedTimestampBranch.mux.Lock()
-	o.lastUpdatedTimestampBranch.timestamp = int64(tradeData.Timestamp)
+	o.lastUpdatedTimestampBranch.timestamp = tradeData.Timestamp
 	o.lastUpdatedTimestampBranch.mux.Unlock()
 
 	return nil
 }
 
-func (o *TradeStreamBranch) listen(ctx context.Context) {
-	for {
-		select {
-		case <-ctx.Done():
-			return
-		case <-o.TradeChan:
-			trade := <-o.TradeChan
+func (o *TradeStreamBranch) subscribed(market string) bool {
+	for _, m := range o.Markets {
+		if m == market {
+			return true
+		}
+	}
+	return false
+}
+
+// newTrades converts the entries, oldest first, skipping ids already seen.
+// The ids count as seen once markSeen is called, after the trades were taken.
+func (o *TradeStreamBranch) newTrades(tradeData tradeMsg) []PublicTrade {
+	o.lastIdBranch.Lock()
+	defer o.lastIdBranch.Unlock()
+
+	entries := tradeData.Trades
+	// MAX sends the newest trade first.
+	if len(entries) > 1 && entries[0].Timestamp > entries[len(entries)-1].Timestamp {
+		reversed := make([]tradeEntry, len(entries))
+		for i := range entries {
+			reversed[len(entries)-1-i] = entries[i]
+		}
+		entries = reversed
+	}
+
+	trades := make([]PublicTrade, 0, len(entries))
+	lastId := o.lastIdBranch.ids[tradeData.Market]
+	for _, e := range entries {
+		if e.Id != 0 {
+			if e.Id <= lastId {
+				continue
+			}
+			lastId = e.Id
+		}
+		trades = append(trades, PublicTrade{
+			ID:     e.Id,
+			Market: tradeData.Market,
+			Price:  e.Price,
+			Volume: e.Volume,
+			Time:   time.UnixMilli(e.Timestamp),
+			Side:   takerSideFromTrend(e.Trend),
+		})
+	}
+	return trades
+}
+
+func (o *TradeStreamBranch) markSeen(market string, trades []PublicTrade) {
+	if len(trades) == 0 || trades[len(trades)-1].ID == 0 {
+		return
+	}
+	o.lastIdBranch.Lock()
+	defer o.lastIdBranch.Unlock()
+	o.lastIdBranch.ids[market] = trades[len(trades)-1].ID
+}
+
+// tradesArrived buffers the trades following the back-pressure policy, then runs the callbacks.
+func (o *TradeStreamBranch) tradesArrived(trades []PublicTrade) error {
+	if len(trades) == 0 {
+		return nil
+	}
+
+	o.tradesBranch.Lock()
+	for o.cfg.BufferSize > 0 && len(o.tradesBranch.Trades)+len(trades) > o.cfg.BufferSize {
+		switch o.cfg.Backpressure {
+		case BackpressureBlock:
+			o.tradesBranch.Unlock()
+			select {
+			case <-o.ctx.Done():
+				return o.ctx.Err()
+			case <-time.After(10 * time.Millisecond):
+			}
 			o.tradesBranch.Lock()
-			o.tradesBranch.Trades = append(o.tradesBranch.Trades, trade)
+		case BackpressureReconnect:
 			o.tradesBranch.Unlock()
+			return errTradeBufferFull
 		default:
-			time.Sleep(time.Second)
+			n := len(o.tradesBranch.Trades) + len(trades) - o.cfg.BufferSize
+			if n > len(o.tradesBranch.Trades) {
+				n = len(o.tradesBranch.Trades)
+			}
+			o.tradesBranch.Trades = o.tradesBranch.Trades[n:]
+			atomic.AddInt64(&o.dropped, int64(n))
+			if len(o.tradesBranch.Trades)+len(trades) > o.cfg.BufferSize {
+				dropped := len(trades) - o.cfg.BufferSize
+				trades = trades[dropped:]
+				atomic.AddInt64(&o.dropped, int64(dropped))
+			}
 		}
 	}
+	if o.cfg.BufferSize >= 0 {
+		o.tradesBranch.Trades = append(o.tradesBranch.Trades, trades...)
+	}
+	o.tradesBranch.Unlock()
+
+	o.listenersBranch.RLock()
+	listeners := o.listenersBranch.listeners
+	o.listenersBranch.RUnlock()
+	for _, trade := range trades {
+		for _, l := range listeners {
+			l.fn(trade)
+		}
+	}
+	return nil
+}
+
+// offerTradeData feeds the deprecated TradeChan, skipping the message when it is full.
+func (o *TradeStreamBranch) offerTradeData(msg []byte) {
+	if o.TradeChan == nil {
+		return
+	}
+	var tradeData TradeData
+	if err := json.Unmarshal(msg, &tradeData); err != nil {
+		return
+	}
+	select {
+	case o.TradeChan <- tradeData:
+	default:
+	}
 }
 
-func (o *TradeStreamBranch) GetTrades() []TradeData {
+// GetTrades takes every buffered trade, oldest first.
+func (o *TradeStreamBranch) GetTrades() []PublicTrade {
 	o.tradesBranch.Lock()
 	trades := o.tradesBranch.Trades
-	o.tradesBranch.Trades = []TradeData{}
+	o.tradesBranch.Trades = []PublicTrade{}
 	o.tradesBranch.Unlock()
 	return trades
 }
+
+// Dropped is the number of trades evicted by BackpressureDropOldest.
+func (o *TradeStreamBranch) Dropped() int64 {
+	return atomic.LoadInt64(&o.dropped)
+}
+
+// Health of the trade stream websocket.
+func (o *TradeStreamBranch) Health() Health {
+	return o.health.snapshot()
+}
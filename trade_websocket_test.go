@@ -0,0 +1,147 @@
+package max_RESTfulAPI
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func tradeFrame(event string, ids ...int64) []byte {
+	// newest first, as MAX sends them.
+	entries := ""
+	for i := len(ids) - 1; i >= 0; i-- {
+		if entries != "" {
+			entries += ","
+		}
+		entries += fmt.Sprintf(`{"i":%d,"p":"100","v":"1","T":%d,"tr":"up"}`, ids[i], 1000+ids[i])
+	}
+	return []byte(fmt.Sprintf(`{"c":"trade","e":"%s","M":"btcusdt","t":[%s],"T":2000}`, event, entries))
+}
+
+func tradeIds(trades []PublicTrade) []int64 {
+	ids := make([]int64, 0, len(trades))
+	for _, trade := range trades {
+		ids = append(ids, trade.ID)
+	}
+	return ids
+}
+
+// test the trade stream orders, de-duplicates and buffers trades following the back-pressure policy
+func TestTradeStream(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	t.Run("ordering and de-duplication", func(t *testing.T) {
+		o := newTradeStreamBranch([]string{"BTCUSDT"}, TradeStreamConfig{}, logger)
+		var seen []int64
+		o.OnTrade(func(trade PublicTrade) { seen = append(seen, trade.ID) })
+		for _, frame := range [][]byte{
+			tradeFrame("snapshot", 1, 2, 3),
+			tradeFrame("update", 4),
+			// snapshot after a reconnect overlaps the trades already seen.
+			tradeFrame("snapshot", 2, 3, 4, 5),
+			tradeFrame("update", 5, 6),
+		} {
+			if err := o.handleMaxTradeSocketMsg(frame); err != nil {
+				t.Fatal(err)
+			}
+		}
+		want := fmt.Sprint([]int64{1, 2, 3, 4, 5, 6})
+		if got := fmt.Sprint(tradeIds(o.GetTrades())); got != want {
+			t.Errorf("buffered %s, want %s", got, want)
+		}
+		if got := fmt.Sprint(seen); got != want {
+			t.Errorf("listener %s, want %s", got, want)
+		}
+		if len(o.TradeChan) != 4 {
+			t.Errorf("trade chan %d messages", len(o.TradeChan))
+		}
+	})
+
+	t.Run("unbounded by default", func(t *testing.T) {
+		o := newTradeStreamBranch([]string{"btcusdt"}, TradeStreamConfig{}, logger)
+		var seen int
+		o.OnTrade(func(PublicTrade) { seen++ })
+		// nobody calls GetTrades, the stream must not stall.
+		for id := int64(1); id <= 20000; id++ {
+			if err := o.tradesArrived([]PublicTrade{{ID: id, Market: "btcusdt"}}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if seen != 20000 || len(o.GetTrades()) != 20000 || o.Dropped() != 0 {
+			t.Errorf("seen %d, dropped %d", seen, o.Dropped())
+		}
+	})
+
+	t.Run("block", func(t *testing.T) {
+		o := newTradeStreamBranch([]string{"btcusdt"}, TradeStreamConfig{BufferSize: 2}, logger)
+		if err := o.handleMaxTradeSocketMsg(tradeFrame("update", 1, 2)); err != nil {
+			t.Fatal(err)
+		}
+		done := make(chan error)
+		go func() { done <- o.handleMaxTradeSocketMsg(tradeFrame("update", 3)) }()
+		select {
+		case err := <-done:
+			t.Fatalf("not blocked, %v", err)
+		case <-time.After(50 * time.Millisecond):
+		}
+		if got := tradeIds(o.GetTrades()); len(got) != 2 {
+			t.Fatalf("trades %v", got)
+		}
+		if err := <-done; err != nil {
+			t.Fatal(err)
+		}
+		if got := tradeIds(o.GetTrades()); len(got) != 1 || got[0] != 3 || o.Dropped() != 0 {
+			t.Errorf("trades %v, dropped %d", got, o.Dropped())
+		}
+	})
+
+	t.Run("reconnect", func(t *testing.T) {
+		o := newTradeStreamBranch([]string{"btcusdt"}, TradeStreamConfig{BufferSize: 2, Backpressure: BackpressureReconnect}, logger)
+		if err := o.handleMaxTradeSocketMsg(tradeFrame("update", 1, 2)); err != nil {
+			t.Fatal(err)
+		}
+		if err := o.handleMaxTradeSocketMsg(tradeFrame("update", 3)); !errors.Is(err, errTradeBufferFull) {
+			t.Fatalf("err %v", err)
+		}
+		o.GetTrades()
+		// trade 3 is recovered from the snapshot of the new connection.
+		if err := o.handleMaxTradeSocketMsg(tradeFrame("snapshot", 2, 3)); err != nil {
+			t.Fatal(err)
+		}
+		if got := tradeIds(o.GetTrades()); len(got) != 1 || got[0] != 3 {
+			t.Errorf("trades %v", got)
+		}
+	})
+
+	t.Run("drop oldest", func(t *testing.T) {
+		o := newTradeStreamBranch([]string{"btcusdt"}, TradeStreamConfig{BufferSize: 2, Backpressure: BackpressureDropOldest}, logger)
+		if err := o.handleMaxTradeSocketMsg(tradeFrame("update", 1, 2, 3)); err != nil {
+			t.Fatal(err)
+		}
+		if err := o.handleMaxTradeSocketMsg(tradeFrame("update", 4)); err != nil {
+			t.Fatal(err)
+		}
+		if got := fmt.Sprint(tradeIds(o.GetTrades())); got != "[3 4]" || o.Dropped() != 2 {
+			t.Errorf("trades %s, dropped %d", got, o.Dropped())
+		}
+	})
+
+	t.Run("listener only", func(t *testing.T) {
+		o := newTradeStreamBranch([]string{"btcusdt"}, TradeStreamConfig{BufferSize: -1}, logger)
+		var seen int
+		o.OnTrade(func(PublicTrade) { seen++ })
+		for id := int64(1); id <= 5; id++ {
+			if err := o.handleMaxTradeSocketMsg(tradeFrame("update", id)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if seen != 5 || len(o.GetTrades()) != 0 {
+			t.Errorf("seen %d", seen)
+		}
+	})
+}
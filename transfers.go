@@ -0,0 +1,282 @@
+package max_RESTfulAPI
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	ErrUnknownCurrency = errors.New("unknown currency")
+	// the withdrawal address is not in the address book of the currency.
+	ErrAddressNotWhitelisted = errors.New("withdrawal address not whitelisted")
+)
+
+// lookupCurrency finds a currency, refreshing the currencies once if it is not known yet.
+func (Mc *MaxClient) lookupCurrency(id string) (Currency, error) {
+	if c, ok := Mc.Currency(id); ok {
+		return c, nil
+	}
+	if _, err := Mc.GetCurrencies(); err != nil {
+		return Currency{}, err
+	}
+	if c, ok := Mc.Currency(id); ok {
+		return c, nil
+	}
+	return Currency{}, fmt.Errorf("%w: %s", ErrUnknownCurrency, NormalizeCurrency(id))
+}
+
+func (Mc *MaxClient) GetDepositAddresses(currency string) ([]DepositAddress, error) {
+	c, err := Mc.lookupCurrency(currency)
+	if err != nil {
+		return []DepositAddress{}, err
+	}
+	addresses, _, err := Mc.ApiClient.PrivateApi.GetApiV2DepositAddresses(context.Background(), Mc.signer, c.Id)
+	if err != nil {
+		return []DepositAddress{}, err
+	}
+	return addresses, nil
+}
+
+// GetDeposits gets the deposits of a currency since a time, zero for the latest ones.
+func (Mc *MaxClient) GetDeposits(currency string, since time.Time) ([]Deposit, error) {
+	c, err := Mc.lookupCurrency(currency)
+	if err != nil {
+		return []Deposit{}, err
+	}
+	deposits, _, err := Mc.ApiClient.PrivateApi.GetApiV2Deposits(context.Background(), Mc.signer, c.Id, sinceParams(since))
+	if err != nil {
+		return []Deposit{}, err
+	}
+	return deposits, nil
+}
+
+// GetWithdrawAddresses gets the address book of a currency.
+func (Mc *MaxClient) GetWithdrawAddresses(currency string) ([]WithdrawAddress, error) {
+	c, err := Mc.lookupCurrency(currency)
+	if err != nil {
+		return []WithdrawAddress{}, err
+	}
+	addresses, _, err := Mc.ApiClient.PrivateApi.GetApiV2WithdrawAddresses(context.Background(), Mc.signer, c.Id)
+	if err != nil {
+		return []WithdrawAddress{}, err
+	}
+	return addresses, nil
+}
+
+// Withdraw sends amount of currency to an address of the address book, identified by its uuid.
+// The amount is rounded down to the precision of the currency and checked against the minimum of the network of the address.
+func (Mc *MaxClient) Withdraw(currency, addressUuid string, amount decimal.Decimal) (Withdrawal, error) {
+	c, err := Mc.lookupCurrency(currency)
+	if err != nil {
+		return Withdrawal{}, err
+	}
+	if !c.WithdrawEnabled() {
+		return Withdrawal{}, fmt.Errorf("withdrawal of %s is disabled", c.Id)
+	}
+	amount = c.Round(amount)
+	if !amount.IsPositive() {
+		return Withdrawal{}, fmt.Errorf("withdrawal amount %s is below the precision of %s", amount, c.Id)
+	}
+
+	addresses, err := Mc.GetWithdrawAddresses(c.Id)
+	if err != nil {
+		return Withdrawal{}, err
+	}
+	var address WithdrawAddress
+	whitelisted := false
+	for _, a := range addresses {
+		if a.Uuid == addressUuid {
+			address = a
+			whitelisted = true
+			break
+		}
+	}
+	if !whitelisted {
+		return Withdrawal{}, fmt.Errorf("%w: %s of %s", ErrAddressNotWhitelisted, addressUuid, c.Id)
+	}
+
+	// the minimum of the network of the address, the lowest one if the network is unknown.
+	min := c.MinWithdrawal()
+	if network, ok := c.Network(address.CurrencyVersion); ok {
+		if !network.WithdrawalEnabled {
+			return Withdrawal{}, fmt.Errorf("withdrawal of %s through %s is disabled", c.Id, address.CurrencyVersion)
+		}
+		min = network.MinWithdrawalAmount
+	}
+	if amount.LessThan(min) {
+		return Withdrawal{}, fmt.Errorf("withdrawal amount %s is below the minimum %s of %s", amount, min, c.Id)
+	}
+
+	withdrawal, _, err := Mc.ApiClient.PrivateApi.PostApiV2Withdrawal(context.Background(), Mc.signer, c.Id, addressUuid, amount.String())
+	if err != nil {
+		return Withdrawal{}, fmt.Errorf("fail to withdraw %s %s: %w", amount, c.Id, err)
+	}
+	Mc.logger.Infof("Withdraw %s %s to %s, uuid %s.", amount, c.Id, addressUuid, withdrawal.Uuid)
+	return withdrawal, nil
+}
+
+// GetWithdrawals gets the withdrawals of a currency since a time, zero for the latest ones.
+func (Mc *MaxClient) GetWithdrawals(currency string, since time.Time) ([]Withdrawal, error) {
+	c, err := Mc.lookupCurrency(currency)
+	if err != nil {
+		return []Withdrawal{}, err
+	}
+	withdrawals, _, err := Mc.ApiClient.PrivateApi.GetApiV2Withdrawals(context.Background(), Mc.signer, c.Id, sinceParams(since))
+	if err != nil {
+		return []Withdrawal{}, err
+	}
+	return withdrawals, nil
+}
+
+func (Mc *MaxClient) CancelWithdrawal(uuid string) (Withdrawal, error) {
+	withdrawal, _, err := Mc.ApiClient.PrivateApi.PostApiV2WithdrawalCancel(context.Background(), Mc.signer, uuid)
+	if err != nil {
+		return Withdrawal{}, fmt.Errorf("fail to cancel withdrawal %s: %w", uuid, err)
+	}
+	Mc.logger.Info("Cancel withdrawal ", uuid)
+	return withdrawal, nil
+}
+
+func sinceParams(since time.Time) map[string]interface{} {
+	params := make(map[string]interface{})
+	if !since.IsZero() {
+		params["from"] = since.Unix()
+	}
+	return params
+}
+
+type TransferPollerConfig struct {
+	Currencies []string
+	// default to 30 seconds.
+	Interval time.Duration
+	// how far back every poll looks, default to 7 days.
+	Lookback time.Duration
+
+	// called when a deposit shows up or changes state, prevState is empty for a new one.
+	OnDeposit func(deposit Deposit, prevState string)
+	// called when a withdrawal shows up or changes state, prevState is empty for a new one.
+	OnWithdrawal func(withdrawal Withdrawal, prevState string)
+}
+
+// TransferPoller polls the deposits and withdrawals and reports their state transitions.
+// The transfers found by the first poll are recorded without being reported.
+type TransferPoller struct {
+	Config TransferPollerConfig
+
+	client *MaxClient
+	logger *logrus.Logger
+
+	statesBranch struct {
+		deposits    map[string]string
+		withdrawals map[string]string
+		seeded      bool
+		sync.Mutex
+	}
+}
+
+// PollTransfers starts polling the transfers of the configured currencies until ctx is done.
+func (Mc *MaxClient) PollTransfers(ctx context.Context, cfg TransferPollerConfig) *TransferPoller {
+	if cfg.Interval <= 0 {
+		cfg.Interval = 30 * time.Second
+	}
+	if cfg.Lookback <= 0 {
+		cfg.Lookback = 7 * 24 * time.Hour
+	}
+	p := &TransferPoller{Config: cfg, client: Mc, logger: Mc.logger}
+	p.statesBranch.deposits = make(map[string]string)
+	p.statesBranch.withdrawals = make(map[string]string)
+
+	go func() {
+		p.Poll()
+		ticker := time.NewTicker(cfg.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				p.Poll()
+			}
+		}
+	}()
+	return p
+}
+
+// Poll checks every currency once.
+func (p *TransferPoller) Poll() {
+	since := time.Now().Add(-p.Config.Lookback)
+	var deposits []Deposit
+	var withdrawals []Withdrawal
+	complete := true
+	for _, currency := range p.Config.Currencies {
+		d, err := p.client.GetDeposits(currency, since)
+		if err != nil {
+			p.logger.Warnf("fail to poll %s deposits: %v", currency, err)
+			complete = false
+		}
+		deposits = append(deposits, d...)
+
+		w, err := p.client.GetWithdrawals(currency, since)
+		if err != nil {
+			p.logger.Warnf("fail to poll %s withdrawals: %v", currency, err)
+			complete = false
+		}
+		withdrawals = append(withdrawals, w...)
+	}
+	p.track(deposits, withdrawals, complete)
+}
+
+// track reports the changes once a complete poll has seeded the states.
+func (p *TransferPoller) track(deposits []Deposit, withdrawals []Withdrawal, complete bool) {
+	p.statesBranch.Lock()
+	seeded := p.statesBranch.seeded
+	if complete {
+		p.statesBranch.seeded = true
+	}
+
+	type depositChange struct {
+		deposit Deposit
+		prev    string
+	}
+	type withdrawalChange struct {
+		withdrawal Withdrawal
+		prev       string
+	}
+	var depositChanges []depositChange
+	var withdrawalChanges []withdrawalChange
+	for _, d := range deposits {
+		prev, ok := p.statesBranch.deposits[d.Uuid]
+		p.statesBranch.deposits[d.Uuid] = d.State
+		if seeded && (!ok || prev != d.State) {
+			depositChanges = append(depositChanges, depositChange{d, prev})
+		}
+	}
+	for _, w := range withdrawals {
+		prev, ok := p.statesBranch.withdrawals[w.Uuid]
+		p.statesBranch.withdrawals[w.Uuid] = w.State
+		if seeded && (!ok || prev != w.State) {
+			withdrawalChanges = append(withdrawalChanges, withdrawalChange{w, prev})
+		}
+	}
+	p.statesBranch.Unlock()
+
+	// callbacks run outside of the lock, so they may call the client.
+	for _, c := range depositChanges {
+		p.logger.Infof("%s deposit %s %s: %q -> %q", c.deposit.Currency, c.deposit.Uuid, c.deposit.Amount, c.prev, c.deposit.State)
+		if p.Config.OnDeposit != nil {
+			p.Config.OnDeposit(c.deposit, c.prev)
+		}
+	}
+	for _, c := range withdrawalChanges {
+		p.logger.Infof("%s withdrawal %s %s: %q -> %q", c.withdrawal.Currency, c.withdrawal.Uuid, c.withdrawal.Amount, c.prev, c.withdrawal.State)
+		if p.Config.OnWithdrawal != nil {
+			p.Config.OnWithdrawal(c.withdrawal, c.prev)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package max_RESTfulAPI
+
+import (
+	"encoding/base64"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/sirupsen/logrus"
+)
+
+// newTestTransferClient points a client at a local server answering the transfer endpoints by path.
+func newTestTransferClient(t *testing.T, routes map[string]func(body map[string]interface{}) string) *MaxClient {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		raw, _ := base64.StdEncoding.DecodeString(r.Header.Get("X-MAX-PAYLOAD"))
+		var body map[string]interface{}
+		json.Unmarshal(raw, &body)
+		route, ok := routes[r.URL.Path]
+		if !ok {
+			t.Errorf("unexpected %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		resp := route(body)
+		if strings.HasPrefix(resp, `{"error"`) {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+		w.Write([]byte(resp))
+	}))
+	t.Cleanup(server.Close)
+
+	cfg := NewConfiguration()
+	cfg.BasePath = server.URL
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	Mc := &MaxClient{signer: NewHMACSigner("access", "secret"), logger: logger, ApiClient: NewAPIClient(cfg)}
+	Mc.setCurrencies([]Currency{{Id: "usdt", Precision: 2, Networks: []CurrencyNetwork{
+		{Id: "usdt-erc20", NetworkProtocol: "erc20", WithdrawalEnabled: true, MinWithdrawalAmount: decimal.NewFromInt(10)},
+		{Id: "usdt-trc20", NetworkProtocol: "trc20", WithdrawalEnabled: true, MinWithdrawalAmount: decimal.NewFromInt(3)},
+		{Id: "usdt-bep20", NetworkProtocol: "bep20", WithdrawalEnabled: false, MinWithdrawalAmount: decimal.NewFromInt(1)},
+	}}})
+	return Mc
+}
+
+// test withdrawals go to whitelisted addresses only, above the minimum of their network
+func TestWithdraw(t *testing.T) {
+	var withdrawn []map[string]interface{}
+	Mc := newTestTransferClient(t, map[string]func(map[string]interface{}) string{
+		"/api/v2/withdraw_addresses": func(map[string]interface{}) string {
+			return `[{"uuid":"erc","currency":"usdt","currency_version":"erc20"},{"uuid":"trc","currency":"usdt","currency_version":"TRC20"},
+				{"uuid":"bep","currency":"usdt","currency_version":"bep20"},{"uuid":"old","currency":"usdt","currency_version":"omni"}]`
+		},
+		"/api/v2/withdrawal": func(body map[string]interface{}) string {
+			withdrawn = append(withdrawn, body)
+			return `{"uuid":"w1","currency":"usdt","amount":"` + body["amount"].(string) + `","state":"submitting"}`
+		},
+	})
+
+	if _, err := Mc.Withdraw("usdt", "unknown", decimal.NewFromInt(100)); !errors.Is(err, ErrAddressNotWhitelisted) {
+		t.Fatalf("err %v", err)
+	}
+	// 5 clears the trc20 minimum but not the erc20 one.
+	if _, err := Mc.Withdraw("usdt", "erc", decimal.NewFromInt(5)); err == nil || !strings.Contains(err.Error(), "minimum 10") {
+		t.Fatalf("err %v", err)
+	}
+	if _, err := Mc.Withdraw("usdt", "bep", decimal.NewFromInt(5)); err == nil || !strings.Contains(err.Error(), "disabled") {
+		t.Fatalf("err %v", err)
+	}
+	// an unknown network falls back to the lowest minimum.
+	if _, err := Mc.Withdraw("usdt", "old", decimal.RequireFromString("2.999")); err == nil || !strings.Contains(err.Error(), "minimum 3") {
+		t.Fatalf("err %v", err)
+	}
+	if len(withdrawn) != 0 {
+		t.Fatalf("withdrawn %v", withdrawn)
+	}
+
+	withdrawal, err := Mc.Withdraw("USDT", "trc", decimal.RequireFromString("5.129"))
+	if err != nil || withdrawal.Uuid != "w1" {
+		t.Fatalf("withdrawal %+v, err %v", withdrawal, err)
+	}
+	if len(withdrawn) != 1 || withdrawn[0]["currency"] != "usdt" || withdrawn[0]["withdraw_address_uuid"] != "trc" || withdrawn[0]["amount"] != "5.12" {
+		t.Fatalf("withdrawn %v", withdrawn)
+	}
+}
+
+// test the poller seeds the states with a complete poll then reports the new transfers and state changes
+func TestTransferPoller(t *testing.T) {
+	var mux sync.Mutex
+	deposits := `[{"uuid":"d1","currency":"usdt","amount":"10","state":"accepted"}]`
+	withdrawals := `[{"uuid":"w1","currency":"usdt","amount":"5","state":"submitting"}]`
+	failing := true
+	Mc := newTestTransferClient(t, map[string]func(map[string]interface{}) string{
+		"/api/v2/deposits": func(body map[string]interface{}) string {
+			mux.Lock()
+			defer mux.Unlock()
+			if body["from"] == nil {
+				t.Errorf("no lookback in %v", body)
+			}
+			return deposits
+		},
+		"/api/v2/withdrawals": func(map[string]interface{}) string {
+			mux.Lock()
+			defer mux.Unlock()
+			if failing {
+				return `{"error":{"code":2006,"message":"The nonce has already been used."}}`
+			}
+			return withdrawals
+		},
+	})
+
+	type change struct{ uuid, prev, state string }
+	var changes []change
+	p := &TransferPoller{
+		Config: TransferPollerConfig{
+			Currencies: []string{"usdt"},
+			Lookback:   24 * time.Hour,
+			OnDeposit: func(d Deposit, prev string) {
+				changes = append(changes, change{d.Uuid, prev, d.State})
+			},
+			OnWithdrawal: func(w Withdrawal, prev string) {
+				changes = append(changes, change{w.Uuid, prev, w.State})
+			},
+		},
+		client: Mc,
+		logger: Mc.logger,
+	}
+	p.statesBranch.deposits = make(map[string]string)
+	p.statesBranch.withdrawals = make(map[string]string)
+
+	// an incomplete poll doesn't seed, a complete one does without reporting.
+	p.Poll()
+	mux.Lock()
+	failing = false
+	mux.Unlock()
+	p.Poll()
+	if len(changes) != 0 {
+		t.Fatalf("changes while seeding %v", changes)
+	}
+
+	mux.Lock()
+	deposits = `[{"uuid":"d1","currency":"usdt","amount":"10","state":"accepted"},{"uuid":"d2","currency":"usdt","amount":"3","state":"submitted"}]`
+	withdrawals = `[{"uuid":"w1","currency":"usdt","amount":"5","state":"sent"}]`
+	mux.Unlock()
+	p.Poll()
+	p.Poll()
+	want := []change{{"d2", "", "submitted"}, {"w1", "submitting", "sent"}}
+	if len(changes) != len(want) {
+		t.Fatalf("changes %v, want %v", changes, want)
+	}
+	for i := range want {
+		if changes[i] != want[i] {
+			t.Errorf("change %d %v, want %v", i, changes[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package max_RESTfulAPI
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/sirupsen/logrus"
+)
+
+// AssetValue is one balance priced in the valuation currency.
+type AssetValue struct {
+	Currency  string
+	Available decimal.Decimal
+	Locked    decimal.Decimal
+	// Available + Locked
+	Total decimal.Decimal
+	// Total in the valuation currency, zero if not Priced.
+	Value  decimal.Decimal
+	Priced bool
+}
+
+// Valuation is the account equity at one moment.
+type Valuation struct {
+	Currency string
+	At       time.Time
+	// sorted by currency.
+	Assets []AssetValue
+	// sum of the priced assets.
+	Equity decimal.Decimal
+	// currencies holding a balance but without a price route, not counted in Equity.
+	Unpriced []string
+}
+
+// EquityPoint is one sample of the equity curve.
+type EquityPoint struct {
+	At     time.Time
+	Equity decimal.Decimal
+}
+
+// Valuator prices every balance of the account, locked funds included, in one currency.
+type Valuator struct {
+	Currency string
+	// e.g. PriceSources{BookPrices{...}, ticker stream, NewTickerPrices(...)}, routed through the bridge currencies.
+	Prices PriceSource
+	// samples kept in the equity curve, default to 7 days of minutes.
+	MaxPoints int
+
+	balances func() (map[string]Balance, error)
+	logger   *logrus.Logger
+
+	curveBranch struct {
+		points []EquityPoint
+		sync.RWMutex
+	}
+}
+
+// NewValuator values the balances of the client in currency, e.g. "twd" or "usdt".
+func (Mc *MaxClient) NewValuator(currency string, prices PriceSource) *Valuator {
+	return &Valuator{
+		Currency:  NormalizeCurrency(currency),
+		Prices:    prices,
+		MaxPoints: 7 * 24 * 60,
+		balances:  Mc.GetBalance,
+		logger:    Mc.logger,
+	}
+}
+
+// Value fetches the balances and prices them.
+func (v *Valuator) Value() (Valuation, error) {
+	balances, err := v.balances()
+	if err != nil {
+		return Valuation{}, err
+	}
+	return ValueBalances(balances, v.Currency, v.Prices), nil
+}
+
+// ValueBalances prices balances in currency with the best route prices offers.
+func ValueBalances(balances map[string]Balance, currency string, prices PriceSource) Valuation {
+	currency = NormalizeCurrency(currency)
+	valuation := Valuation{Currency: currency, At: time.Now(), Equity: decimal.Zero}
+	for name, balance := range balances {
+		asset := AssetValue{
+			Currency:  NormalizeCurrency(name),
+			Available: decimal.NewFromFloat(balance.Avaliable),
+			Locked:    decimal.NewFromFloat(balance.Locked),
+		}
+		asset.Total = asset.Available.Add(asset.Locked)
+		if asset.Total.IsZero() {
+			continue
+		}
+		asset.Value, asset.Priced = convertAmount(prices, asset.Total, asset.Currency, currency)
+		if asset.Priced {
+			valuation.Equity = valuation.Equity.Add(asset.Value)
+		} else {
+			valuation.Unpriced = append(valuation.Unpriced, asset.Currency)
+		}
+		valuation.Assets = append(valuation.Assets, asset)
+	}
+	sort.Slice(valuation.Assets, func(i, j int) bool { return valuation.Assets[i].Currency < valuation.Assets[j].Currency })
+	sort.Strings(valuation.Unpriced)
+	return valuation
+}
+
+// Record values the account and appends the equity to the curve.
+func (v *Valuator) Record() (Valuation, error) {
+	valuation, err := v.Value()
+	if err != nil {
+		return Valuation{}, err
+	}
+	if len(valuation.Unpriced) > 0 {
+		v.logger.Warnf("no price route to %s for %v, left out of equity", v.Currency, valuation.Unpriced)
+	}
+	v.record(EquityPoint{At: valuation.At, Equity: valuation.Equity})
+	return valuation, nil
+}
+
+func (v *Valuator) record(point EquityPoint) {
+	v.curveBranch.Lock()
+	defer v.curveBranch.Unlock()
+	v.curveBranch.points = append(v.curveBranch.points, point)
+	if v.MaxPoints > 0 && len(v.curveBranch.points) > v.MaxPoints {
+		v.curveBranch.points = append([]EquityPoint(nil), v.curveBranch.points[len(v.curveBranch.points)-v.MaxPoints:]...)
+	}
+}
+
+// Run records the equity every interval until ctx is done.
+func (v *Valuator) Run(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if _, err := v.Record(); err != nil {
+					v.logger.Warn("fail to record equity: ", err)
+				}
+			}
+		}
+	}()
+}
+
+// Curve returns a copy of the recorded equity curve, oldest first.
+func (v *Valuator) Curve() []EquityPoint {
+	v.curveBranch.RLock()
+	defer v.curveBranch.RUnlock()
+	return append([]EquityPoint(nil), v.curveBranch.points...)
+}
@@ -0,0 +1,42 @@
+package max_RESTfulAPI
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestValueBalances(t *testing.T) {
+	prices := staticPrices{
+		"usdttwd": decimal.NewFromInt(32),
+		"ethusdt": decimal.NewFromInt(2000),
+	}
+	balances := map[string]Balance{
+		"TWD":  {Name: "TWD", Avaliable: 1000},
+		"usdt": {Name: "usdt", Avaliable: 10, Locked: 5},
+		"eth":  {Name: "eth", Locked: 0.5},
+		"doge": {Name: "doge", Avaliable: 100},
+		"btc":  {Name: "btc"},
+	}
+
+	v := ValueBalances(balances, "twd", prices)
+	// 1000 + 15*32 + 0.5*2000*32
+	if !v.Equity.Equal(decimal.NewFromInt(33480)) {
+		t.Errorf("equity %s, want 33480", v.Equity)
+	}
+	if len(v.Assets) != 4 || v.Assets[0].Currency != "doge" || v.Assets[0].Priced {
+		t.Errorf("assets %+v", v.Assets)
+	}
+	if len(v.Unpriced) != 1 || v.Unpriced[0] != "doge" {
+		t.Errorf("unpriced %v", v.Unpriced)
+	}
+
+	var val Valuator
+	val.MaxPoints = 2
+	for i := int64(1); i <= 3; i++ {
+		val.record(EquityPoint{Equity: decimal.NewFromInt(i)})
+	}
+	if curve := val.Curve(); len(curve) != 2 || !curve[0].Equity.Equal(decimal.NewFromInt(2)) {
+		t.Errorf("curve %v", curve)
+	}
+}
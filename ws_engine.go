@@ -0,0 +1,249 @@
+package max_RESTfulAPI
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/sirupsen/logrus"
+)
+
+const maxStreamURL = "wss://max-stream.maicoin.com/ws"
+
+var (
+	errWsNotConnected = errors.New("websocket is not connected")
+	errWsReconnect    = errors.New("websocket reconnect requested")
+)
+
+// wsConfig tunes the connection lifecycle of a wsEngine, zero values take the defaults.
+type wsConfig struct {
+	DialTimeout  time.Duration
+	MinBackoff   time.Duration
+	MaxBackoff   time.Duration
+	PingInterval time.Duration
+	// the connection is dropped if nothing, pong included, is read for this long.
+	ReadTimeout time.Duration
+}
+
+func (c wsConfig) withDefaults() wsConfig {
+	if c.DialTimeout <= 0 {
+		c.DialTimeout = 5 * time.Second
+	}
+	if c.MinBackoff <= 0 {
+		c.MinBackoff = 500 * time.Millisecond
+	}
+	if c.MaxBackoff <= 0 {
+		c.MaxBackoff = 30 * time.Second
+	}
+	if c.PingInterval <= 0 {
+		c.PingInterval = 30 * time.Second
+	}
+	if c.ReadTimeout <= 0 {
+		c.ReadTimeout = 2 * c.PingInterval
+	}
+	return c
+}
+
+// wsEngine keeps one websocket connection alive: dial with timeout, backoff with jitter,
+// heartbeats with read deadlines, and resubscription on every reconnect.
+type wsEngine struct {
+	name   string
+	url    string
+	cfg    wsConfig
+	logger *logrus.Logger
+	health *healthTracker
+
+	// messages sent right after every connect.
+	subscribe func() ([][]byte, error)
+	// handle returns an error to drop the connection and reconnect.
+	handle       func(msg []byte) error
+	onConnect    func()
+	onDisconnect func(err error)
+
+	connBranch struct {
+		conn *websocket.Conn
+		sync.Mutex
+	}
+	reconnectCh chan struct{}
+}
+
+func newWsEngine(name string, cfg wsConfig, logger *logrus.Logger, health *healthTracker) *wsEngine {
+	return &wsEngine{
+		name:        name,
+		url:         maxStreamURL,
+		cfg:         cfg.withDefaults(),
+		logger:      logger,
+		health:      health,
+		reconnectCh: make(chan struct{}, 1),
+	}
+}
+
+// run blocks until ctx is done, reconnecting whenever the connection drops.
+func (e *wsEngine) run(ctx context.Context) {
+	attempt := 0
+	for {
+		if ctx.Err() != nil {
+			e.health.closed()
+			return
+		}
+
+		e.health.connecting()
+		gotMessage, err := e.session(ctx)
+		if ctx.Err() != nil {
+			e.health.closed()
+			return
+		}
+
+		e.health.fail(err)
+		if e.onDisconnect != nil {
+			e.onDisconnect(err)
+		}
+		if gotMessage {
+			attempt = 0
+		}
+		wait := e.backoff(attempt)
+		attempt++
+		e.logf("❌ %s disconnected: %v, reconnect in %s", e.name, err, wait.Round(time.Millisecond))
+
+		select {
+		case <-ctx.Done():
+			e.health.closed()
+			return
+		case <-time.After(wait):
+		}
+	}
+}
+
+// reconnect drops the current connection, run will dial again.
+func (e *wsEngine) reconnect() {
+	select {
+	case e.reconnectCh <- struct{}{}:
+	default:
+	}
+}
+
+func (e *wsEngine) write(msgType int, data []byte) error {
+	e.connBranch.Lock()
+	defer e.connBranch.Unlock()
+	if e.connBranch.conn == nil {
+		return errWsNotConnected
+	}
+	e.connBranch.conn.SetWriteDeadline(time.Now().Add(e.cfg.DialTimeout))
+	return e.connBranch.conn.WriteMessage(msgType, data)
+}
+
+// conn is the current connection, nil while disconnected.
+func (e *wsEngine) conn() *websocket.Conn {
+	e.connBranch.Lock()
+	defer e.connBranch.Unlock()
+	return e.connBranch.conn
+}
+
+func (e *wsEngine) session(ctx context.Context) (gotMessage bool, err error) {
+	dialCtx, cancelDial := context.WithTimeout(ctx, e.cfg.DialTimeout)
+	conn, _, err := websocket.DefaultDialer.DialContext(dialCtx, e.url, nil)
+	cancelDial()
+	if err != nil {
+		return false, err
+	}
+
+	e.connBranch.Lock()
+	e.connBranch.conn = conn
+	e.connBranch.Unlock()
+
+	sessionCtx, cancel := context.WithCancel(ctx)
+	defer func() {
+		cancel()
+		e.connBranch.Lock()
+		e.connBranch.conn = nil
+		e.connBranch.Unlock()
+		conn.Close()
+	}()
+
+	// drain a stale reconnect request of the previous session.
+	select {
+	case <-e.reconnectCh:
+	default:
+	}
+
+	conn.SetReadDeadline(time.Now().Add(e.cfg.ReadTimeout))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(e.cfg.ReadTimeout))
+	})
+
+	if e.onConnect != nil {
+		e.onConnect()
+	}
+	if e.subscribe != nil {
+		msgs, err := e.subscribe()
+		if err != nil {
+			return false, err
+		}
+		for _, msg := range msgs {
+			if err := e.write(websocket.TextMessage, msg); err != nil {
+				return false, err
+			}
+		}
+	}
+
+	// unblock ReadMessage on shutdown or reconnect request, and keep the heartbeat.
+	go func() {
+		ticker := time.NewTicker(e.cfg.PingInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-sessionCtx.Done():
+				conn.Close()
+				return
+			case <-e.reconnectCh:
+				conn.Close()
+				return
+			case <-ticker.C:
+				e.connBranch.Lock()
+				err := conn.WriteControl(websocket.PingMessage, []byte("ping"), time.Now().Add(e.cfg.DialTimeout))
+				e.connBranch.Unlock()
+				if err != nil {
+					conn.Close()
+					return
+				}
+			}
+		}
+	}()
+
+	for {
+		_, msg, err := conn.ReadMessage()
+		if err != nil {
+			return gotMessage, err
+		}
+		gotMessage = true
+		conn.SetReadDeadline(time.Now().Add(e.cfg.ReadTimeout))
+		e.health.message()
+		tapFrame(e.name, msg)
+
+		if err := e.handle(msg); err != nil {
+			return gotMessage, err
+		}
+	}
+}
+
+// backoff is exponential from MinBackoff to MaxBackoff, with jitter over the upper half.
+func (e *wsEngine) backoff(attempt int) time.Duration {
+	d := e.cfg.MinBackoff
+	for i := 0; i < attempt && d < e.cfg.MaxBackoff; i++ {
+		d *= 2
+	}
+	if d > e.cfg.MaxBackoff {
+		d = e.cfg.MaxBackoff
+	}
+	half := d / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+func (e *wsEngine) logf(format string, args ...interface{}) {
+	if e.logger != nil {
+		e.logger.Infof(format, args...)
+	}
+}
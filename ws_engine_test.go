@@ -0,0 +1,172 @@
+package max_RESTfulAPI
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// newTestWsEngine points an engine at a local websocket server running serve for every connection.
+func newTestWsEngine(t *testing.T, cfg wsConfig, serve func(n int, conn *websocket.Conn)) *wsEngine {
+	var upgrader websocket.Upgrader
+	var mux sync.Mutex
+	connections := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+		mux.Lock()
+		connections++
+		n := connections
+		mux.Unlock()
+		serve(n, conn)
+	}))
+	t.Cleanup(server.Close)
+
+	e := newWsEngine("test", cfg, nil, newHealthTracker("test"))
+	e.url = "ws" + strings.TrimPrefix(server.URL, "http")
+	e.handle = func([]byte) error { return nil }
+	return e
+}
+
+func runTestWsEngine(t *testing.T, e *wsEngine) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		e.run(ctx)
+		close(done)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+}
+
+// test a server dropping every connection right away is redialed with a growing backoff
+func TestWsEngineBackoff(t *testing.T) {
+	dials := make(chan time.Time, 10)
+	e := newTestWsEngine(t, wsConfig{MinBackoff: 20 * time.Millisecond, MaxBackoff: 80 * time.Millisecond}, func(n int, conn *websocket.Conn) {
+		dials <- time.Now()
+	})
+	runTestWsEngine(t, e)
+
+	var at []time.Time
+	for len(at) < 5 {
+		select {
+		case ts := <-dials:
+			at = append(at, ts)
+		case <-time.After(2 * time.Second):
+			t.Fatalf("%d dials", len(at))
+		}
+	}
+	// half of the backoff at least: 10, 20, 40 then 40 capped by MaxBackoff.
+	for i, min := range []time.Duration{10, 20, 40, 40} {
+		if gap := at[i+1].Sub(at[i]); gap < min*time.Millisecond {
+			t.Errorf("dial %d after %s, want at least %dms", i+1, gap, min)
+		}
+	}
+	if h := e.health.snapshot(); h.State == ConnStateConnected || h.LastError == "" {
+		t.Errorf("health %+v", h)
+	}
+}
+
+// test every connection is subscribed again after the server drops it
+func TestWsEngineResubscribe(t *testing.T) {
+	subscribed := make(chan int, 10)
+	e := newTestWsEngine(t, wsConfig{MinBackoff: 10 * time.Millisecond}, func(n int, conn *websocket.Conn) {
+		_, msg, err := conn.ReadMessage()
+		if err != nil || string(msg) != "sub" {
+			t.Errorf("connection %d got %q, %v", n, msg, err)
+			return
+		}
+		conn.WriteMessage(websocket.TextMessage, []byte("hello"))
+		subscribed <- n
+		if n > 1 {
+			// keep the second connection.
+			conn.ReadMessage()
+		}
+	})
+	e.subscribe = func() ([][]byte, error) { return [][]byte{[]byte("sub")}, nil }
+	var mux sync.Mutex
+	var received []string
+	e.handle = func(msg []byte) error {
+		mux.Lock()
+		defer mux.Unlock()
+		received = append(received, string(msg))
+		return nil
+	}
+	disconnects := make(chan error, 10)
+	e.onDisconnect = func(err error) { disconnects <- err }
+	runTestWsEngine(t, e)
+
+	for want := 1; want <= 2; want++ {
+		select {
+		case n := <-subscribed:
+			if n != want {
+				t.Fatalf("connection %d, want %d", n, want)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("connection %d not subscribed", want)
+		}
+	}
+	if len(disconnects) != 1 {
+		t.Errorf("%d disconnects", len(disconnects))
+	}
+	// the message of the second connection is handled too.
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		mux.Lock()
+		n := len(received)
+		mux.Unlock()
+		if n == 2 && e.conn() != nil {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	mux.Lock()
+	defer mux.Unlock()
+	t.Errorf("received %v, connected %v", received, e.conn() != nil)
+}
+
+// test a connection which stops answering pings is dropped after the read timeout
+func TestWsEngineReadTimeout(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	e := newTestWsEngine(t, wsConfig{PingInterval: 20 * time.Millisecond, ReadTimeout: 60 * time.Millisecond, MinBackoff: time.Second}, func(n int, conn *websocket.Conn) {
+		// never reads, so the pings are never answered.
+		<-release
+	})
+	disconnects := make(chan error, 1)
+	e.onDisconnect = func(err error) {
+		select {
+		case disconnects <- err:
+		default:
+		}
+	}
+	start := time.Now()
+	runTestWsEngine(t, e)
+
+	select {
+	case err := <-disconnects:
+		var netErr net.Error
+		if !errors.As(err, &netErr) || !netErr.Timeout() {
+			t.Errorf("disconnected by %v, want a timeout", err)
+		}
+		if elapsed := time.Since(start); elapsed < 60*time.Millisecond {
+			t.Errorf("dropped after %s", elapsed)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("silent connection not dropped")
+	}
+}